@@ -0,0 +1,414 @@
+// controllers/attachment.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxAttachmentBytes = 10 << 20
+
+// attachmentTypes maps the file types we accept, detected from the file's
+// contents rather than its name, to the extension they are stored with
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+// attachmentKinds says which kinds of attachment may be documents as well as photos
+var attachmentKinds = map[string]bool{
+	"before_photo": false,
+	"after_photo":  false,
+	"photo":        false,
+	"document":     true,
+}
+
+// AttachmentController stores customer photos and documents
+type AttachmentController struct {
+	Storage services.Storage
+}
+
+// UpdateAttachmentInput defines the expected JSON structure for updating an attachment
+type UpdateAttachmentInput struct {
+	Kind    *string `json:"kind" binding:"omitempty,oneof=before_photo after_photo photo document"`
+	Caption *string `json:"caption"`
+}
+
+// UploadAttachment stores a photo or document against the customer.
+//
+// Multipart fields: file (JPEG, PNG, WebP or PDF, up to 10 MB); kind
+// ("before_photo", "after_photo", "photo" or "document"); caption; invoiceId
+// (optional, the visit the file belongs to). JPEG and PNG photos get a thumbnail.
+func (ac *AttachmentController) UploadAttachment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	kind := c.PostForm("kind")
+	allowsDocuments, ok := attachmentKinds[kind]
+	if !ok {
+		utils.RespondWithError(c, http.StatusBadRequest, "kind must be before_photo, after_photo, photo or document")
+		return
+	}
+
+	var invoiceID *uuid.UUID
+	if raw := c.PostForm("invoiceId"); raw != "" {
+		invoiceUUID, err := uuid.Parse(raw)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+			return
+		}
+		var count int64
+		if err := config.DB.Model(&models.Invoice{}).
+			Where("salon_id = ? AND customer_id = ? AND id = ?", salonUUID, customer.ID, invoiceUUID).
+			Count(&count).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if count == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invoice not found for this customer")
+			return
+		}
+		invoiceID = &invoiceUUID
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "A file is required")
+		return
+	}
+	if fileHeader.Size > maxAttachmentBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "File is larger than 10 MB")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxAttachmentBytes+1))
+	file.Close()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	if len(data) > maxAttachmentBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "File is larger than 10 MB")
+		return
+	}
+
+	// Trust the bytes, not the name or the browser's Content-Type
+	contentType := http.DetectContentType(data)
+	ext, ok := attachmentTypes[contentType]
+	if !ok {
+		utils.RespondWithError(c, http.StatusBadRequest, "Only JPEG, PNG, WebP and PDF files can be uploaded")
+		return
+	}
+	if contentType == "application/pdf" && !allowsDocuments {
+		utils.RespondWithError(c, http.StatusBadRequest, "Photos must be JPEG, PNG or WebP images")
+		return
+	}
+
+	attachment := models.Attachment{
+		ID:               uuid.New(),
+		SalonID:          salonUUID,
+		CustomerID:       customer.ID,
+		InvoiceID:        invoiceID,
+		UploadedByUserID: userUUID,
+		Kind:             kind,
+		Filename:         attachmentFilename(fileHeader.Filename, ext),
+		ContentType:      contentType,
+		SizeBytes:        int64(len(data)),
+		Caption:          c.PostForm("caption"),
+	}
+	// Keys are scoped by salon and carry no customer details, so they survive merges and erasure
+	attachment.StorageKey = fmt.Sprintf("salons/%s/attachments/%s%s", salonUUID, attachment.ID, ext)
+
+	var thumbnail []byte
+	if contentType == "image/jpeg" || contentType == "image/png" {
+		thumbnail, attachment.Width, attachment.Height, err = services.MakeThumbnail(data)
+		if errors.Is(err, services.ErrImageTooLarge) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Image dimensions are too large")
+			return
+		}
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Image could not be read")
+			return
+		}
+		attachment.ThumbnailKey = fmt.Sprintf("salons/%s/attachments/%s-thumb.jpg", salonUUID, attachment.ID)
+	}
+
+	if err := ac.Storage.Put(attachment.StorageKey, data, contentType); err != nil {
+		log.Printf("Failed to store attachment %s: %v", attachment.ID, err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to store file")
+		return
+	}
+	if thumbnail != nil {
+		if err := ac.Storage.Put(attachment.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+			log.Printf("Failed to store thumbnail for attachment %s: %v", attachment.ID, err)
+			removeStoredFiles(ac.Storage, attachment.StorageKey)
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to store file")
+			return
+		}
+	}
+
+	if err := config.DB.Create(&attachment).Error; err != nil {
+		removeStoredFiles(ac.Storage, attachment.StorageKey, attachment.ThumbnailKey)
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save attachment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachments lists the customer's attachments, newest first, optionally
+// filtered by ?kind= and ?invoiceId=
+func (ac *AttachmentController) GetAttachments(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if invoiceID := c.Query("invoiceId"); invoiceID != "" {
+		invoiceUUID, err := uuid.Parse(invoiceID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+			return
+		}
+		query = query.Where("invoice_id = ?", invoiceUUID)
+	}
+
+	var attachments []models.Attachment
+	if err := query.Order("created_at DESC").Find(&attachments).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch attachments")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// DownloadAttachment streams an attachment's file, or its thumbnail with
+// ?thumbnail=true. Files are only ever served through here, after the salon
+// check, never from a public URL.
+func (ac *AttachmentController) DownloadAttachment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var attachment models.Attachment
+	if !loadCustomerRecord(c, salonUUID, "attachmentId", "Attachment", &attachment) {
+		return
+	}
+
+	key, contentType, size := attachment.StorageKey, attachment.ContentType, attachment.SizeBytes
+	disposition := "inline"
+	if c.Query("thumbnail") == "true" {
+		if attachment.ThumbnailKey == "" {
+			utils.RespondWithError(c, http.StatusNotFound, "Attachment has no thumbnail")
+			return
+		}
+		key, contentType, size = attachment.ThumbnailKey, "image/jpeg", -1
+	} else if c.Query("download") == "true" {
+		disposition = "attachment"
+	}
+
+	body, err := ac.Storage.Get(key)
+	if err != nil {
+		if errors.Is(err, services.ErrStoredFileNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "File not found in storage")
+		} else {
+			log.Printf("Failed to read attachment %s: %v", attachment.ID, err)
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to read file")
+		}
+		return
+	}
+	defer body.Close()
+
+	c.DataFromReader(http.StatusOK, size, contentType, body, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("%s; filename=%q", disposition, attachment.Filename),
+		"Cache-Control":          "private, max-age=3600",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// UpdateAttachment changes an attachment's kind or caption
+func (ac *AttachmentController) UpdateAttachment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var attachment models.Attachment
+	if !loadCustomerRecord(c, salonUUID, "attachmentId", "Attachment", &attachment) {
+		return
+	}
+
+	var input UpdateAttachmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.Kind != nil {
+		if attachment.ContentType == "application/pdf" && !attachmentKinds[*input.Kind] {
+			utils.RespondWithError(c, http.StatusBadRequest, "A PDF can only be a document")
+			return
+		}
+		attachment.Kind = *input.Kind
+	}
+	if input.Caption != nil {
+		attachment.Caption = *input.Caption
+	}
+
+	if err := config.DB.Save(&attachment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update attachment")
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// DeleteAttachment removes an attachment and its stored files. Owners and managers only.
+func (ac *AttachmentController) DeleteAttachment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can delete attachments")
+		return
+	}
+
+	var attachment models.Attachment
+	if !loadCustomerRecord(c, salonUUID, "attachmentId", "Attachment", &attachment) {
+		return
+	}
+
+	if err := config.DB.Delete(&attachment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete attachment")
+		return
+	}
+	removeStoredFiles(ac.Storage, attachment.StorageKey, attachment.ThumbnailKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Attachment deleted successfully"})
+}
+
+// attachmentFilename keeps the base of an uploaded file's name, with the
+// extension matching what the file actually is
+func attachmentFilename(name, ext string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment-" + time.Now().Format("20060102-150405")
+	}
+	if runes := []rune(name); len(runes) > 200 {
+		name = string(runes[:200])
+	}
+	return name + ext
+}
+
+// removeStoredFiles deletes files whose database rows are gone. Failures are
+// only logged; the files are unreachable without their rows.
+func removeStoredFiles(storage services.Storage, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := storage.Delete(key); err != nil {
+			log.Printf("Failed to delete stored file %s: %v", key, err)
+		}
+	}
+}
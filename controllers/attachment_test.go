@@ -0,0 +1,36 @@
+// controllers/attachment_test.go
+package controllers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAttachmentFilename(t *testing.T) {
+	tests := []struct {
+		name, in, ext, want string
+	}{
+		{"extension replaced", "before.JPG", ".jpg", "before.jpg"},
+		{"wrong extension", "consent-form.png", ".pdf", "consent-form.pdf"},
+		{"no extension", "after", ".png", "after.png"},
+		{"windows path", `C:\Users\asha\Pictures\colour.jpeg`, ".jpg", "colour.jpg"},
+		{"path traversal", "../../etc/passwd", ".pdf", "passwd.pdf"},
+		{"spaces trimmed", "  patch test.jpg ", ".jpg", "patch test.jpg"},
+		{"long name", strings.Repeat("आ", 250) + ".jpg", ".jpg", strings.Repeat("आ", 200) + ".jpg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := attachmentFilename(tt.in, tt.ext); got != tt.want {
+				t.Errorf("attachmentFilename(%q, %q) = %q, want %q", tt.in, tt.ext, got, tt.want)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "   ", ".jpg", "/"} {
+		got := attachmentFilename(in, ".jpg")
+		if !strings.HasPrefix(got, "attachment-") || !strings.HasSuffix(got, ".jpg") || !utf8.ValidString(got) {
+			t.Errorf("attachmentFilename(%q) = %q, want a generated attachment-*.jpg name", in, got)
+		}
+	}
+}
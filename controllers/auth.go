@@ -31,6 +31,8 @@ type RegisterInput struct {
 	SalonName    string       `json:"salonName" binding:"required"`
 	SalonAddress string       `json:"salonAddress"`
 	WorkingHours models.JSONB `json:"workingHours"`
+	CurrencyCode string       `json:"currencyCode" binding:"omitempty,len=3"`
+	Locale       string       `json:"locale"`
 }
 
 type LoginInput struct {
@@ -78,6 +80,25 @@ func Register(c *gin.Context) {
 		Address: input.SalonAddress,
 	}
 
+	// Apply regional settings if provided, otherwise the column defaults are used
+	if input.CurrencyCode != "" {
+		salon.CurrencyCode = strings.ToUpper(input.CurrencyCode)
+		if !utils.ValidateCurrencyCode(salon.CurrencyCode) {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, "Unknown currency code")
+			return
+		}
+		salon.DecimalPlaces = utils.CurrencyDecimalPlaces(salon.CurrencyCode)
+	}
+	if input.Locale != "" {
+		if !utils.ValidateLocale(input.Locale) {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid locale")
+			return
+		}
+		salon.Locale = input.Locale
+	}
+
 	// Set default working hours if not provided
 	if input.WorkingHours == nil {
 		salon.WorkingHours = models.JSONB{
@@ -99,6 +120,14 @@ func Register(c *gin.Context) {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create salon")
 		return
 	}
+	// GORM leaves a zero value to the column default of 2, which is wrong for currencies such as JPY
+	if salon.CurrencyCode != "" && salon.DecimalPlaces == 0 {
+		if err := tx.Model(&salon).Update("decimal_places", 0).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create salon")
+			return
+		}
+	}
 
 	// Create owner user
 	newUser := models.User{
@@ -125,6 +154,13 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Create the coupons the default templates promise
+	if err := createDefaultCoupons(tx, salon.ID, newUser.ID); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create default coupons: "+err.Error())
+		return
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
@@ -162,9 +198,10 @@ func Register(c *gin.Context) {
 			"role":  newUser.Role,
 		},
 		"salon": gin.H{
-			"id":      salon.ID,
-			"name":    salon.Name,
-			"address": salon.Address,
+			"id":               salon.ID,
+			"name":             salon.Name,
+			"address":          salon.Address,
+			"regionalSettings": salonRegionalSettings(salon),
 		},
 	})
 }
@@ -249,9 +286,10 @@ func Login(c *gin.Context) {
 			"role":  user.Role,
 		},
 		"salon": gin.H{
-			"id":      salon.ID,
-			"name":    salon.Name,
-			"address": salon.Address,
+			"id":               salon.ID,
+			"name":             salon.Name,
+			"address":          salon.Address,
+			"regionalSettings": salonRegionalSettings(salon),
 		},
 	})
 }
@@ -540,9 +578,10 @@ func Me(c *gin.Context) {
 			"role":  user.Role,
 		},
 		"salon": gin.H{
-			"id":      salon.ID,
-			"name":    salon.Name,
-			"address": salon.Address,
+			"id":               salon.ID,
+			"name":             salon.Name,
+			"address":          salon.Address,
+			"regionalSettings": salonRegionalSettings(salon),
 		},
 	})
 }
@@ -554,14 +593,28 @@ func createDefaultReminderTemplates(tx *gorm.DB, salonID uuid.UUID) error {
 			ID:       uuid.New(),
 			SalonID:  salonID,
 			Type:     "birthday",
-			Message:  "Hi [CustomerName], SalonPro wishes you a very happy birthday! 🎉 Enjoy 20% off on your next visit this month!",
+			Message:  "Hi [CustomerName], SalonPro wishes you a very happy birthday! 🎉 Enjoy 20% off on your next visit this month with code BIRTHDAY20!",
 			IsActive: true,
 		},
 		{
 			ID:       uuid.New(),
 			SalonID:  salonID,
 			Type:     "anniversary",
-			Message:  "Hi [CustomerName], happy salon anniversary! 🎊 Thank you for being our valued customer. Here's 15% off your next service!",
+			Message:  "Hi [CustomerName], happy salon anniversary! 🎊 Thank you for being our valued customer. Here's 15% off your next service this month with code ANNIVERSARY15!",
+			IsActive: true,
+		},
+		{
+			ID:       uuid.New(),
+			SalonID:  salonID,
+			Type:     "membership_expiry",
+			Message:  "Hi [CustomerName], your [PlanName] membership at [SalonName] expires on [ExpiryDate]. Renew on your next visit to keep your member benefits!",
+			IsActive: true,
+		},
+		{
+			ID:       uuid.New(),
+			SalonID:  salonID,
+			Type:     "dues",
+			Message:  "Hi [CustomerName], a balance of [BalanceDue] is still due on invoice [InvoiceNumber] dated [InvoiceDate] at [SalonName]. Please settle it at your convenience. Thank you!",
 			IsActive: true,
 		},
 	}
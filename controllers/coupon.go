@@ -0,0 +1,577 @@
+// controllers/coupon.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateCouponInput defines the expected JSON structure for creating a coupon
+type CreateCouponInput struct {
+	Code               string     `json:"code" binding:"required"`
+	Description        string     `json:"description"`
+	DiscountType       string     `json:"discountType" binding:"required,oneof=percentage flat"`
+	Value              float64    `json:"value" binding:"required,gt=0"`
+	MaxDiscount        float64    `json:"maxDiscount" binding:"min=0"`
+	MinSpend           float64    `json:"minSpend" binding:"min=0"`
+	EligibleServiceIDs []string   `json:"eligibleServiceIds"`
+	EligibleCategories []string   `json:"eligibleCategories"`
+	ValidFrom          *time.Time `json:"validFrom"`
+	ValidUntil         *time.Time `json:"validUntil"`
+	UsageLimit         int        `json:"usageLimit" binding:"min=0"`
+	PerCustomerLimit   int        `json:"perCustomerLimit" binding:"min=0"`
+	FirstVisitOnly     bool       `json:"firstVisitOnly"`
+	Occasion           string     `json:"occasion" binding:"omitempty,oneof=birthday anniversary"`
+}
+
+// UpdateCouponInput defines the expected JSON structure for updating a coupon
+type UpdateCouponInput struct {
+	Description        *string    `json:"description"`
+	Value              *float64   `json:"value" binding:"omitempty,gt=0"`
+	MaxDiscount        *float64   `json:"maxDiscount" binding:"omitempty,min=0"`
+	MinSpend           *float64   `json:"minSpend" binding:"omitempty,min=0"`
+	EligibleServiceIDs *[]string  `json:"eligibleServiceIds"`
+	EligibleCategories *[]string  `json:"eligibleCategories"`
+	ValidFrom          *time.Time `json:"validFrom"`
+	ValidUntil         *time.Time `json:"validUntil"`
+	UsageLimit         *int       `json:"usageLimit" binding:"omitempty,min=0"`
+	PerCustomerLimit   *int       `json:"perCustomerLimit" binding:"omitempty,min=0"`
+	FirstVisitOnly     *bool      `json:"firstVisitOnly"`
+	IsActive           *bool      `json:"isActive"`
+}
+
+// ValidateCouponInput previews a coupon against a prospective invoice
+type ValidateCouponInput struct {
+	Code       string             `json:"code" binding:"required"`
+	CustomerID uuid.UUID          `json:"customerId" binding:"required"`
+	Items      []InvoiceItemInput `json:"items" binding:"required,min=1"`
+}
+
+// CreateCoupon creates a new coupon for the salon
+func CreateCoupon(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input CreateCouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.DiscountType == "percentage" && input.Value > 100 {
+		utils.RespondWithError(c, http.StatusBadRequest, "Percentage discount cannot exceed 100")
+		return
+	}
+	if input.ValidFrom != nil && input.ValidUntil != nil && input.ValidUntil.Before(*input.ValidFrom) {
+		utils.RespondWithError(c, http.StatusBadRequest, "validUntil must be after validFrom")
+		return
+	}
+
+	code := normaliseCouponCode(input.Code)
+
+	// Check if code already exists for this salon
+	var existingCoupon models.Coupon
+	if err := config.DB.Where("salon_id = ? AND code = ?", salonUUID, code).
+		First(&existingCoupon).Error; err == nil {
+		utils.RespondWithError(c, http.StatusConflict, "Coupon with this code already exists")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	coupon := models.Coupon{
+		ID:                 uuid.New(),
+		SalonID:            salonUUID,
+		CreatedByUserID:    uuid.Must(uuid.Parse(userID.(string))),
+		Code:               code,
+		Description:        input.Description,
+		DiscountType:       input.DiscountType,
+		Value:              input.Value,
+		MaxDiscount:        input.MaxDiscount,
+		MinSpend:           input.MinSpend,
+		EligibleServiceIDs: models.StringList(input.EligibleServiceIDs),
+		EligibleCategories: models.StringList(input.EligibleCategories),
+		ValidFrom:          input.ValidFrom,
+		ValidUntil:         input.ValidUntil,
+		UsageLimit:         input.UsageLimit,
+		PerCustomerLimit:   input.PerCustomerLimit,
+		FirstVisitOnly:     input.FirstVisitOnly,
+		Occasion:           input.Occasion,
+		IsActive:           true,
+	}
+
+	if err := config.DB.Create(&coupon).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create coupon")
+		return
+	}
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// GetCoupons retrieves all coupons for the salon
+func GetCoupons(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var coupons []models.Coupon
+	if err := config.DB.Where("salon_id = ?", salonUUID).Order("created_at DESC").Find(&coupons).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve coupons")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupons)
+}
+
+// GetCoupon retrieves a coupon with its redemptions
+func GetCoupon(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	couponUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid coupon ID format")
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, couponUUID).
+		First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Coupon not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var redemptions []models.CouponRedemption
+	if err := config.DB.Where("coupon_id = ?", coupon.ID).Order("redeemed_at DESC").
+		Find(&redemptions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve redemptions")
+		return
+	}
+
+	var totalDiscount float64
+	for _, r := range redemptions {
+		totalDiscount += r.DiscountAmount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"coupon":        coupon,
+		"redemptions":   redemptions,
+		"totalDiscount": totalDiscount,
+	})
+}
+
+// UpdateCoupon updates an existing coupon. Code and discount type are fixed once created.
+func UpdateCoupon(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	couponUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid coupon ID format")
+		return
+	}
+
+	var input UpdateCouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, couponUUID).
+		First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Coupon not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Update fields if provided
+	if input.Description != nil {
+		coupon.Description = *input.Description
+	}
+	if input.Value != nil {
+		if coupon.DiscountType == "percentage" && *input.Value > 100 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Percentage discount cannot exceed 100")
+			return
+		}
+		coupon.Value = *input.Value
+	}
+	if input.MaxDiscount != nil {
+		coupon.MaxDiscount = *input.MaxDiscount
+	}
+	if input.MinSpend != nil {
+		coupon.MinSpend = *input.MinSpend
+	}
+	if input.EligibleServiceIDs != nil {
+		coupon.EligibleServiceIDs = models.StringList(*input.EligibleServiceIDs)
+	}
+	if input.EligibleCategories != nil {
+		coupon.EligibleCategories = models.StringList(*input.EligibleCategories)
+	}
+	if input.ValidFrom != nil {
+		coupon.ValidFrom = input.ValidFrom
+	}
+	if input.ValidUntil != nil {
+		coupon.ValidUntil = input.ValidUntil
+	}
+	if input.UsageLimit != nil {
+		coupon.UsageLimit = *input.UsageLimit
+	}
+	if input.PerCustomerLimit != nil {
+		coupon.PerCustomerLimit = *input.PerCustomerLimit
+	}
+	if input.FirstVisitOnly != nil {
+		coupon.FirstVisitOnly = *input.FirstVisitOnly
+	}
+	if input.IsActive != nil {
+		coupon.IsActive = *input.IsActive
+	}
+
+	if err := config.DB.Save(&coupon).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update coupon")
+		return
+	}
+
+	c.JSON(http.StatusOK, coupon)
+}
+
+// DeleteCoupon deactivates a coupon, keeping it for the redemption audit trail
+func DeleteCoupon(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	couponUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid coupon ID format")
+		return
+	}
+
+	result := config.DB.Model(&models.Coupon{}).
+		Where("salon_id = ? AND id = ?", salonUUID, couponUUID).
+		Update("is_active", false)
+
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to deactivate coupon")
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Coupon not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deactivated successfully"})
+}
+
+// ValidateCoupon checks whether a code applies to a prospective invoice and
+// returns the discount it would give, without redeeming it
+func ValidateCoupon(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input ValidateCouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var items []models.InvoiceItem
+	for _, item := range input.Items {
+		lineItem, err := resolveInvoiceItem(config.DB, salonUUID, item)
+		if err != nil {
+			respondInvoiceInputError(c, err)
+			return
+		}
+		items = append(items, lineItem)
+	}
+
+	coupon, discount, err := evaluateCoupon(config.DB, salonUUID, input.Code, customer, items, time.Now())
+	if err != nil {
+		var inputErr *invoiceInputError
+		if errors.As(err, &inputErr) {
+			c.JSON(http.StatusOK, gin.H{"valid": false, "reason": inputErr.Error()})
+			return
+		}
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":    true,
+		"code":     coupon.Code,
+		"discount": discount,
+	})
+}
+
+// evaluateCoupon checks every rule on a coupon for this customer and these
+// invoice lines, and returns the discount it gives
+func evaluateCoupon(db *gorm.DB, salonID uuid.UUID, code string, customer models.Customer, items []models.InvoiceItem, now time.Time) (models.Coupon, float64, error) {
+	var coupon models.Coupon
+	if err := db.Where("salon_id = ? AND code = ?", salonID, normaliseCouponCode(code)).
+		First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return coupon, 0, &invoiceInputError{"Coupon not found"}
+		}
+		return coupon, 0, err
+	}
+
+	if !coupon.IsActive {
+		return coupon, 0, &invoiceInputError{"Coupon is not active"}
+	}
+	if coupon.ValidFrom != nil && now.Before(*coupon.ValidFrom) {
+		return coupon, 0, &invoiceInputError{"Coupon is not valid yet"}
+	}
+	if coupon.ValidUntil != nil && now.After(*coupon.ValidUntil) {
+		return coupon, 0, &invoiceInputError{"Coupon has expired"}
+	}
+	if coupon.UsageLimit > 0 && coupon.UsageCount >= coupon.UsageLimit {
+		return coupon, 0, &invoiceInputError{"Coupon usage limit reached"}
+	}
+	if coupon.FirstVisitOnly && customer.TotalVisits > 0 {
+		return coupon, 0, &invoiceInputError{"Coupon is only valid on a first visit"}
+	}
+
+	switch coupon.Occasion {
+	case "birthday":
+		if customer.Birthday == nil || customer.Birthday.Month() != now.Month() {
+			return coupon, 0, &invoiceInputError{"Coupon is only valid in the customer's birthday month"}
+		}
+	case "anniversary":
+		if customer.Anniversary == nil || customer.Anniversary.Month() != now.Month() {
+			return coupon, 0, &invoiceInputError{"Coupon is only valid in the customer's anniversary month"}
+		}
+	}
+
+	if coupon.PerCustomerLimit > 0 {
+		query := db.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND customer_id = ?", coupon.ID, customer.ID)
+		// Occasion offers come round every year, so the limit applies per calendar year
+		if coupon.Occasion != "" {
+			query = query.Where("redeemed_at >= ?", time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()))
+		}
+		var used int64
+		if err := query.Count(&used).Error; err != nil {
+			return coupon, 0, err
+		}
+		if int(used) >= coupon.PerCustomerLimit {
+			return coupon, 0, &invoiceInputError{"Customer has already used this coupon"}
+		}
+	}
+
+	discount, err := couponDiscount(coupon, items)
+	return coupon, discount, err
+}
+
+// couponDiscount works out the discount a coupon gives on these invoice lines,
+// checking its minimum spend and eligible services. Gift card sales never count.
+func couponDiscount(coupon models.Coupon, items []models.InvoiceItem) (float64, error) {
+	var spend, eligible float64
+	restricted := len(coupon.EligibleServiceIDs) > 0 || len(coupon.EligibleCategories) > 0
+	for _, item := range items {
+		if item.ItemType == "gift_card" {
+			continue
+		}
+		spend += item.TotalPrice
+
+		if !restricted {
+			eligible += item.TotalPrice
+			continue
+		}
+		if item.ServiceID != nil && coupon.EligibleServiceIDs.Contains(item.ServiceID.String()) {
+			eligible += item.TotalPrice
+		} else if item.Category != "" && coupon.EligibleCategories.Contains(item.Category) {
+			eligible += item.TotalPrice
+		}
+	}
+
+	if spend < coupon.MinSpend {
+		return 0, &invoiceInputError{"Minimum spend for this coupon has not been met"}
+	}
+	if eligible <= 0 {
+		return 0, &invoiceInputError{"Coupon does not apply to any items on this invoice"}
+	}
+
+	var discount float64
+	if coupon.DiscountType == "percentage" {
+		discount = eligible * coupon.Value / 100
+		if coupon.MaxDiscount > 0 {
+			discount = math.Min(discount, coupon.MaxDiscount)
+		}
+	} else {
+		discount = math.Min(coupon.Value, eligible)
+	}
+
+	return math.Round(discount*100) / 100, nil
+}
+
+// redeemCoupon records the redemption and counts it against the usage limit.
+// The guarded increment stops concurrent invoices going over the limit.
+func redeemCoupon(tx *gorm.DB, coupon models.Coupon, invoice models.Invoice, discount float64) error {
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR usage_count < usage_limit)", coupon.ID).
+		Update("usage_count", gorm.Expr("usage_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &invoiceInputError{"Coupon usage limit reached"}
+	}
+
+	return tx.Create(&models.CouponRedemption{
+		ID:             uuid.New(),
+		SalonID:        invoice.SalonID,
+		CouponID:       coupon.ID,
+		InvoiceID:      invoice.ID,
+		CustomerID:     invoice.CustomerID,
+		DiscountAmount: discount,
+	}).Error
+}
+
+// reverseCouponRedemption releases the coupon use held by an invoice being deleted
+func reverseCouponRedemption(tx *gorm.DB, invoice models.Invoice) error {
+	if invoice.CouponID == nil {
+		return nil
+	}
+
+	result := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.CouponRedemption{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	return tx.Model(&models.Coupon{}).
+		Where("id = ? AND usage_count > 0", *invoice.CouponID).
+		Update("usage_count", gorm.Expr("usage_count - 1")).Error
+}
+
+// createDefaultCoupons sets up the offers promised by the default reminder templates
+func createDefaultCoupons(tx *gorm.DB, salonID, ownerID uuid.UUID) error {
+	defaultCoupons := []models.Coupon{
+		{
+			ID:               uuid.New(),
+			SalonID:          salonID,
+			CreatedByUserID:  ownerID,
+			Code:             "BIRTHDAY20",
+			Description:      "20% off in the customer's birthday month",
+			DiscountType:     "percentage",
+			Value:            20,
+			PerCustomerLimit: 1,
+			Occasion:         "birthday",
+			IsActive:         true,
+		},
+		{
+			ID:               uuid.New(),
+			SalonID:          salonID,
+			CreatedByUserID:  ownerID,
+			Code:             "ANNIVERSARY15",
+			Description:      "15% off in the customer's anniversary month",
+			DiscountType:     "percentage",
+			Value:            15,
+			PerCustomerLimit: 1,
+			Occasion:         "anniversary",
+			IsActive:         true,
+		},
+	}
+
+	for _, coupon := range defaultCoupons {
+		if err := tx.Create(&coupon).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normaliseCouponCode makes codes case-insensitive
+func normaliseCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,146 @@
+// controllers/coupon_test.go
+package controllers
+
+import (
+	"errors"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCouponDiscount(t *testing.T) {
+	haircut := uuid.New()
+	colour := uuid.New()
+	items := []models.InvoiceItem{
+		{ItemType: "service", ServiceID: &haircut, Category: "Hair", TotalPrice: 800},
+		{ItemType: "service", ServiceID: &colour, Category: "Colour", TotalPrice: 2400},
+		{ItemType: "product", Category: "Retail", TotalPrice: 600},
+		{ItemType: "gift_card", TotalPrice: 1000},
+	}
+
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		items   []models.InvoiceItem
+		want    float64
+		wantErr string
+	}{
+		{"percentage of everything but gift cards", models.Coupon{DiscountType: "percentage", Value: 10}, items, 380, ""},
+		{"percentage capped", models.Coupon{DiscountType: "percentage", Value: 50, MaxDiscount: 500}, items, 500, ""},
+		{"flat", models.Coupon{DiscountType: "flat", Value: 250}, items, 250, ""},
+		{"flat no more than the eligible lines",
+			models.Coupon{DiscountType: "flat", Value: 1000, EligibleServiceIDs: models.StringList{haircut.String()}}, items, 800, ""},
+		{"eligible service",
+			models.Coupon{DiscountType: "percentage", Value: 20, EligibleServiceIDs: models.StringList{colour.String()}}, items, 480, ""},
+		{"eligible category",
+			models.Coupon{DiscountType: "percentage", Value: 10, EligibleCategories: models.StringList{"Hair", "Retail"}}, items, 140, ""},
+		{"rounded to paise", models.Coupon{DiscountType: "percentage", Value: 12.5}, []models.InvoiceItem{
+			{ItemType: "service", TotalPrice: 99.99},
+		}, 12.5, ""},
+		{"minimum spend met", models.Coupon{DiscountType: "flat", Value: 100, MinSpend: 3800}, items, 100, ""},
+		{"minimum spend ignores gift cards", models.Coupon{DiscountType: "flat", Value: 100, MinSpend: 4000}, items, 0,
+			"Minimum spend for this coupon has not been met"},
+		{"no eligible lines",
+			models.Coupon{DiscountType: "flat", Value: 100, EligibleCategories: models.StringList{"Nails"}}, items, 0,
+			"Coupon does not apply to any items on this invoice"},
+		{"gift card sale only", models.Coupon{DiscountType: "flat", Value: 100}, items[3:], 0,
+			"Coupon does not apply to any items on this invoice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := couponDiscount(tt.coupon, tt.items)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("couponDiscount() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("couponDiscount() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("couponDiscount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateCoupon(t *testing.T) {
+	now := time.Date(2026, 5, 14, 12, 0, 0, 0, time.UTC)
+	past := now.AddDate(0, 0, -1)
+	future := now.AddDate(0, 0, 1)
+	may := time.Date(1990, 5, 2, 0, 0, 0, 0, time.UTC)
+	june := time.Date(1990, 6, 2, 0, 0, 0, 0, time.UTC)
+	items := []models.InvoiceItem{{ItemType: "service", TotalPrice: 1000}}
+
+	tests := []struct {
+		name     string
+		coupon   models.Coupon
+		inactive bool
+		customer models.Customer
+		redeemed int
+		wantErr  string
+	}{
+		{"valid", models.Coupon{}, false, models.Customer{}, 0, ""},
+		{"inactive", models.Coupon{}, true, models.Customer{}, 0, "Coupon is not active"},
+		{"not started", models.Coupon{ValidFrom: &future}, false, models.Customer{}, 0, "Coupon is not valid yet"},
+		{"expired", models.Coupon{ValidUntil: &past}, false, models.Customer{}, 0, "Coupon has expired"},
+		{"usage limit", models.Coupon{UsageLimit: 5, UsageCount: 5}, false, models.Customer{}, 0, "Coupon usage limit reached"},
+		{"first visit", models.Coupon{FirstVisitOnly: true}, false, models.Customer{TotalVisits: 1}, 0, "Coupon is only valid on a first visit"},
+		{"birthday month", models.Coupon{Occasion: "birthday"}, false, models.Customer{Birthday: &may}, 0, ""},
+		{"not birthday month", models.Coupon{Occasion: "birthday"}, false, models.Customer{Birthday: &june}, 0,
+			"Coupon is only valid in the customer's birthday month"},
+		{"no anniversary", models.Coupon{Occasion: "anniversary"}, false, models.Customer{}, 0,
+			"Coupon is only valid in the customer's anniversary month"},
+		{"per customer limit", models.Coupon{PerCustomerLimit: 1}, false, models.Customer{}, 1, "Customer has already used this coupon"},
+		{"per customer limit not reached", models.Coupon{PerCustomerLimit: 2}, false, models.Customer{}, 1, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, &models.Coupon{}, &models.CouponRedemption{})
+
+			coupon := tt.coupon
+			coupon.ID = uuid.New()
+			coupon.SalonID = uuid.New()
+			coupon.CreatedByUserID = uuid.New()
+			coupon.Code = "WELCOME10"
+			coupon.DiscountType = "percentage"
+			coupon.Value = 10
+			if err := db.Create(&coupon).Error; err != nil {
+				t.Fatalf("Failed to create coupon: %v", err)
+			}
+			if tt.inactive {
+				// Create leaves a false IsActive to the column default
+				db.Model(&coupon).Update("is_active", false)
+			}
+
+			customer := tt.customer
+			customer.ID = uuid.New()
+			for i := 0; i < tt.redeemed; i++ {
+				if err := db.Create(&models.CouponRedemption{
+					ID:         uuid.New(),
+					SalonID:    coupon.SalonID,
+					CouponID:   coupon.ID,
+					InvoiceID:  uuid.New(),
+					CustomerID: customer.ID,
+				}).Error; err != nil {
+					t.Fatalf("Failed to create redemption: %v", err)
+				}
+			}
+
+			_, discount, err := evaluateCoupon(db, coupon.SalonID, " welcome10 ", customer, items, now)
+			if tt.wantErr != "" {
+				var inputErr *invoiceInputError
+				if !errors.As(err, &inputErr) || inputErr.Error() != tt.wantErr {
+					t.Fatalf("evaluateCoupon() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || discount != 100 {
+				t.Errorf("evaluateCoupon() = %v, %v; want 100, nil", discount, err)
+			}
+		})
+	}
+}
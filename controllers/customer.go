@@ -6,11 +6,13 @@ import (
 	"salonpro-backend/config"
 	"salonpro-backend/models"
 	"salonpro-backend/utils"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateCustomerInput defines the expected JSON structure for creating a customer
@@ -21,6 +23,8 @@ type CreateCustomerInput struct {
 	Birthday    *time.Time `json:"birthday"`
 	Anniversary *time.Time `json:"anniversary"`
 	Notes       string     `json:"notes"`
+	Gender      string     `json:"gender" binding:"omitempty,oneof=female male other"`
+	Tags        []string   `json:"tags"`
 }
 
 // UpdateCustomerInput defines the expected JSON structure for updating a customer
@@ -31,7 +35,12 @@ type UpdateCustomerInput struct {
 	Birthday    *time.Time `json:"birthday"`
 	Anniversary *time.Time `json:"anniversary"`
 	Notes       *string    `json:"notes"`
+	Gender      *string    `json:"gender" binding:"omitempty,oneof=female male other"`
+	Tags        *[]string  `json:"tags"` // replaces the customer's tags
 	IsActive    *bool      `json:"isActive"`
+
+	// Household members only
+	Relationship *string `json:"relationship" binding:"omitempty,oneof=spouse partner child parent sibling other"`
 }
 
 // CreateCustomer creates a new customer for the salon
@@ -65,11 +74,12 @@ func CreateCustomer(c *gin.Context) {
 		return
 	}
 
-	// Check if phone already exists for this salon
+	// Check if phone already exists for this salon; a second person on the
+	// same number is added to that customer's household instead
 	var existingCustomer models.Customer
-	if err := config.DB.Where("salon_id = ? AND phone = ?", salonUUID, input.Phone).
+	if err := config.DB.Where("salon_id = ? AND phone = ? AND household_primary_id IS NULL", salonUUID, input.Phone).
 		First(&existingCustomer).Error; err == nil {
-		utils.RespondWithError(c, http.StatusConflict, "Customer with this phone number already exists")
+		utils.RespondWithError(c, http.StatusConflict, "Customer with this phone number already exists; add them to "+existingCustomer.Name+"'s household instead")
 		return
 	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
@@ -86,9 +96,19 @@ func CreateCustomer(c *gin.Context) {
 		Birthday:        input.Birthday,
 		Anniversary:     input.Anniversary,
 		Notes:           input.Notes,
+		Gender:          input.Gender,
 		IsActive:        true,
 	}
 
+	if input.Tags != nil {
+		tags, err := canonicalCustomerTags(config.DB, salonUUID, input.Tags)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		customer.Tags = tags
+	}
+
 	if input.Email != nil {
 		customer.Email = *input.Email
 	}
@@ -101,7 +121,31 @@ func CreateCustomer(c *gin.Context) {
 	c.JSON(http.StatusCreated, customer)
 }
 
-// GetCustomers retrieves all customers for the salon
+// CustomerListQuery holds the search, filter, sort and paging options for GetCustomers
+type CustomerListQuery struct {
+	Search        string `form:"q"`
+	Status        string `form:"status" binding:"omitempty,oneof=active inactive all"`
+	BirthdayMonth int    `form:"birthdayMonth" binding:"omitempty,min=1,max=12"`
+	LastVisitFrom string `form:"lastVisitFrom"` // YYYY-MM-DD
+	LastVisitTo   string `form:"lastVisitTo"`   // YYYY-MM-DD, inclusive
+	Tag           string `form:"tag"`
+	SegmentID     string `form:"segmentId"`
+	Sort          string `form:"sort" binding:"omitempty,oneof=name lastVisit totalSpent totalVisits"`
+	Order         string `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page          int    `form:"page" binding:"omitempty,min=1"`
+	PageSize      int    `form:"pageSize" binding:"omitempty,min=1,max=200"`
+}
+
+// customerSortColumns maps the sort options to columns
+var customerSortColumns = map[string]string{
+	"name":        "name",
+	"lastVisit":   "last_visit",
+	"totalSpent":  "total_spent",
+	"totalVisits": "total_visits",
+}
+
+// GetCustomers returns a page of the salon's customers. ?q= matches name or email
+// anywhere and phone numbers by their digits, so "98765" finds "+91 98765-43210".
 func GetCustomers(c *gin.Context) {
 	salonID, exists := c.Get("salonId")
 	if !exists {
@@ -115,13 +159,67 @@ func GetCustomers(c *gin.Context) {
 		return
 	}
 
+	var params CustomerListQuery
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+	if params.Page == 0 {
+		params.Page = 1
+	}
+	if params.PageSize == 0 {
+		params.PageSize = 50
+	}
+
+	query, err := filterCustomers(config.DB.Model(&models.Customer{}).Where("salon_id = ?", salonUUID), salonUUID, params)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	search := strings.TrimSpace(params.Search)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve customers")
+		return
+	}
+
+	// Name sorts A-Z by default; the activity columns show the biggest first
+	sort := params.Sort
+	if sort == "" {
+		sort = "name"
+	}
+	order := params.Order
+	if order == "" {
+		order = "desc"
+		if sort == "name" {
+			order = "asc"
+		}
+	}
+	if search != "" && params.Sort == "" {
+		// Names starting with the search term come first
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "name ILIKE ? DESC",
+			Vars: []interface{}{escapeLike(search) + "%"},
+		}})
+	}
+	query = query.Order(customerSortColumns[sort] + " " + order + " NULLS LAST").Order("id")
+
 	var customers []models.Customer
-	if err := config.DB.Where("salon_id = ?", salonUUID).Find(&customers).Error; err != nil {
+	if err := query.Offset((params.Page - 1) * params.PageSize).
+		Limit(params.PageSize).
+		Find(&customers).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve customers")
 		return
 	}
 
-	c.JSON(http.StatusOK, customers)
+	c.JSON(http.StatusOK, gin.H{
+		"customers":  customers,
+		"page":       params.Page,
+		"pageSize":   params.PageSize,
+		"total":      total,
+		"totalPages": (total + int64(params.PageSize) - 1) / int64(params.PageSize),
+	})
 }
 
 // GetCustomer retrieves a specific customer by ID
@@ -167,12 +265,24 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
 	salonUUID, err := uuid.Parse(salonID.(string))
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
 		return
 	}
 
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
 	customerID := c.Param("id")
 	customerUUID, err := uuid.Parse(customerID)
 	if err != nil {
@@ -197,6 +307,7 @@ func UpdateCustomer(c *gin.Context) {
 		}
 		return
 	}
+	before := customer
 
 	// Update fields if provided
 	if input.Name != nil {
@@ -209,11 +320,15 @@ func UpdateCustomer(c *gin.Context) {
 			return
 		}
 
-		// Check if phone is being changed to another existing customer
+		// Check if phone is being changed to another existing customer. Members
+		// may use their primary's number.
 		if customer.Phone != *input.Phone {
+			query := config.DB.Where("salon_id = ? AND phone = ? AND household_primary_id IS NULL", salonUUID, *input.Phone)
+			if customer.HouseholdPrimaryID != nil {
+				query = query.Where("id <> ?", *customer.HouseholdPrimaryID)
+			}
 			var existingCustomer models.Customer
-			if err := config.DB.Where("salon_id = ? AND phone = ?", salonUUID, *input.Phone).
-				First(&existingCustomer).Error; err == nil {
+			if err := query.First(&existingCustomer).Error; err == nil {
 				utils.RespondWithError(c, http.StatusConflict, "Another customer with this phone number already exists")
 				return
 			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -235,11 +350,43 @@ func UpdateCustomer(c *gin.Context) {
 	if input.Notes != nil {
 		customer.Notes = *input.Notes
 	}
+	if input.Gender != nil {
+		customer.Gender = *input.Gender
+	}
+	if input.Tags != nil {
+		tags, err := canonicalCustomerTags(config.DB, salonUUID, *input.Tags)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		customer.Tags = tags
+	}
 	if input.IsActive != nil {
 		customer.IsActive = *input.IsActive
 	}
+	if input.Relationship != nil {
+		if customer.HouseholdPrimaryID == nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Only household members have a relationship")
+			return
+		}
+		customer.Relationship = *input.Relationship
+	}
 
-	if err := config.DB.Save(&customer).Error; err != nil {
+	// Points and wallet balances only change through their ledgers
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("LoyaltyPoints", "WalletBalance").Save(&customer).Error; err != nil {
+			return err
+		}
+		// Members sharing a primary's number follow it when it changes
+		if customer.HouseholdPrimaryID == nil && customer.Phone != before.Phone {
+			if err := tx.Model(&models.Customer{}).
+				Where("household_primary_id = ? AND phone = ?", customer.ID, before.Phone).
+				Update("phone", customer.Phone).Error; err != nil {
+				return err
+			}
+		}
+		return recordCustomerChange(tx, before, customer, userUUID)
+	}); err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer")
 		return
 	}
@@ -268,6 +415,16 @@ func DeleteCustomer(c *gin.Context) {
 		return
 	}
 
+	var members int64
+	if err := config.DB.Model(&models.Customer{}).Where("household_primary_id = ?", customerUUID).Count(&members).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if members > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Customer is a household's primary contact; unlink or reassign its members first")
+		return
+	}
+
 	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
 		Delete(&models.Customer{})
 
@@ -283,3 +440,81 @@ func DeleteCustomer(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Customer deleted successfully"})
 }
+
+// filterCustomers applies the search and filter options shared by the customer
+// list and export
+func filterCustomers(query *gorm.DB, salonID uuid.UUID, params CustomerListQuery) (*gorm.DB, error) {
+	search := strings.TrimSpace(params.Search)
+	if search != "" {
+		pattern := "%" + escapeLike(search) + "%"
+		conditions := config.DB.Where("name ILIKE ?", pattern).Or("email ILIKE ?", pattern)
+		if digits := phoneDigits(search); digits != "" {
+			conditions = conditions.Or("regexp_replace(phone, '[^0-9]', '', 'g') LIKE ?", "%"+digits+"%")
+		}
+		query = query.Where(conditions)
+	}
+
+	switch params.Status {
+	case "active":
+		query = query.Where("is_active = true")
+	case "inactive":
+		query = query.Where("is_active = false")
+	}
+
+	if params.BirthdayMonth != 0 {
+		query = query.Where("EXTRACT(MONTH FROM birthday) = ?", params.BirthdayMonth)
+	}
+
+	if params.LastVisitFrom != "" {
+		from, err := time.ParseInLocation("2006-01-02", params.LastVisitFrom, time.Local)
+		if err != nil {
+			return nil, errors.New("Invalid lastVisitFrom date, expected YYYY-MM-DD")
+		}
+		query = query.Where("last_visit >= ?", from)
+	}
+	if params.LastVisitTo != "" {
+		to, err := time.ParseInLocation("2006-01-02", params.LastVisitTo, time.Local)
+		if err != nil {
+			return nil, errors.New("Invalid lastVisitTo date, expected YYYY-MM-DD")
+		}
+		query = query.Where("last_visit < ?", to.AddDate(0, 0, 1))
+	}
+
+	if tag := strings.TrimSpace(params.Tag); tag != "" {
+		query = query.Where(customerHasAnyTag, []string{strings.ToLower(tag)})
+	}
+
+	if params.SegmentID != "" {
+		segmentUUID, err := uuid.Parse(params.SegmentID)
+		if err != nil {
+			return nil, errors.New("Invalid segment ID format")
+		}
+		var segment models.CustomerSegment
+		if err := config.DB.Where("salon_id = ? AND id = ?", salonID, segmentUUID).First(&segment).Error; err != nil {
+			return nil, errors.New("Segment not found")
+		}
+		query = applySegmentRules(query, segment.Rules)
+	}
+	return query, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// phoneDigits returns the digits of a search term that looks like a phone number,
+// so formatted numbers match, or "" when the term contains anything else
+func phoneDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune("+-() ", r):
+		default:
+			return ""
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,355 @@
+// controllers/customer_consent.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordConsentInput is a customer's choice for one channel and purpose
+type RecordConsentInput struct {
+	Channel string `json:"channel" binding:"required,oneof=sms whatsapp email"`
+	Purpose string `json:"purpose" binding:"required,oneof=marketing transactional"`
+	Granted *bool  `json:"granted" binding:"required"`
+	Source  string `json:"source" binding:"required,oneof=in_store online_form phone_call sms_reply whatsapp_reply email_link import other"`
+	Note    string `json:"note"`
+}
+
+// CreateSuppressionInput adds an address to the suppression list by hand
+type CreateSuppressionInput struct {
+	Channel string `json:"channel" binding:"required,oneof=sms whatsapp email"`
+	Purpose string `json:"purpose" binding:"required,oneof=marketing transactional"`
+	Address string `json:"address" binding:"required"`
+	Reason  string `json:"reason" binding:"required,oneof=opt_out complaint bounce manual"`
+	Note    string `json:"note"`
+}
+
+// GetCustomerConsents returns the customer's current consent per channel and
+// purpose, the full history of changes and any suppressions on their addresses
+func GetCustomerConsents(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	current, err := services.LatestConsents(config.DB, customer)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch consents")
+		return
+	}
+
+	var history []models.CustomerConsent
+	if err := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customerUUID).
+		Order("created_at DESC").
+		Find(&history).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch consents")
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	addresses := config.DB.Where("channel IN ? AND address = ?", []string{"sms", "whatsapp"}, services.CustomerAddress(customer, "sms"))
+	if email := services.CustomerAddress(customer, "email"); email != "" {
+		addresses = addresses.Or("channel = 'email' AND address = ?", email)
+	}
+	var suppressions []models.MessageSuppression
+	if err := query.Where(addresses).Find(&suppressions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch suppressions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current":      current,
+		"history":      history,
+		"suppressions": suppressions,
+	})
+}
+
+// RecordCustomerConsent records a customer granting or withdrawing consent,
+// noting who captured it and how
+func RecordCustomerConsent(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var input RecordConsentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if input.Channel == "email" && customer.Email == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer has no email address")
+		return
+	}
+
+	consent := models.CustomerConsent{
+		ID:               uuid.New(),
+		SalonID:          salonUUID,
+		CustomerID:       customer.ID,
+		Channel:          input.Channel,
+		Purpose:          input.Purpose,
+		Granted:          *input.Granted,
+		Source:           input.Source,
+		CapturedByUserID: &userUUID,
+		Note:             input.Note,
+	}
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		return services.RecordConsent(tx, customer, &consent)
+	}); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record consent")
+		return
+	}
+
+	c.JSON(http.StatusCreated, consent)
+}
+
+// GetMessageSuppressions lists the salon's suppressed addresses, optionally
+// filtered by ?channel= and ?address=. Owners and managers only.
+func GetMessageSuppressions(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can view the suppression list")
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	channel := c.Query("channel")
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	if address := c.Query("address"); address != "" {
+		if channel == "" {
+			channel = "sms"
+			if strings.Contains(address, "@") {
+				channel = "email"
+			}
+		}
+		query = query.Where("address = ?", services.SuppressionAddress(channel, address))
+	}
+
+	var suppressions []models.MessageSuppression
+	if err := query.Order("created_at DESC").Limit(500).Find(&suppressions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch suppressions")
+		return
+	}
+
+	c.JSON(http.StatusOK, suppressions)
+}
+
+// CreateMessageSuppression stops messages to an address, whether or not it
+// belongs to a customer. Owners and managers only.
+func CreateMessageSuppression(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can change the suppression list")
+		return
+	}
+
+	var input CreateSuppressionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.Channel == "email" {
+		if _, err := mail.ParseAddress(input.Address); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid email address")
+			return
+		}
+	} else if !utils.ValidatePhone(input.Address) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid phone number format")
+		return
+	}
+
+	suppression := models.MessageSuppression{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		Channel:         input.Channel,
+		Purpose:         input.Purpose,
+		Address:         services.SuppressionAddress(input.Channel, input.Address),
+		Reason:          input.Reason,
+		Source:          "manual",
+		CreatedByUserID: &currentUser.ID,
+		Note:            input.Note,
+	}
+	result := config.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&suppression)
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to add suppression")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Address is already suppressed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, suppression)
+}
+
+// DeleteMessageSuppression lets messages reach an address again. Owners only,
+// since sending to someone who opted out is a compliance risk.
+func DeleteMessageSuppression(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	suppressionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid suppression ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can remove suppressions")
+		return
+	}
+
+	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, suppressionUUID).Delete(&models.MessageSuppression{})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to remove suppression")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Suppression not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Suppression removed successfully"})
+}
@@ -0,0 +1,323 @@
+// controllers/customer_export.go
+package controllers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// How many customers are written between flushes to the client
+const customerExportFlushEvery = 500
+
+// ExportCustomers streams the salon's customers as CSV (?format=csv, with visit
+// and spend stats) or as vCards for phone contacts (?format=vcard). It accepts
+// the same search and filter parameters as the customer list. Owners only, and
+// every export is logged.
+func ExportCustomers(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can export customers")
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "vcard" {
+		utils.RespondWithError(c, http.StatusBadRequest, "format must be csv or vcard")
+		return
+	}
+
+	var params CustomerListQuery
+	if err := c.ShouldBindQuery(&params); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid query: "+err.Error())
+		return
+	}
+	query, err := filterCustomers(config.DB.Model(&models.Customer{}).Where("salon_id = ?", salonUUID), salonUUID, params)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	// Log before streaming so failed downloads are recorded too
+	export := models.CustomerExport{
+		ID:        uuid.New(),
+		SalonID:   salonUUID,
+		UserID:    currentUser.ID,
+		Format:    format,
+		Filters:   models.StringMap{},
+		IPAddress: c.ClientIP(),
+	}
+	for key, values := range c.Request.URL.Query() {
+		if key != "format" && len(values) > 0 {
+			export.Filters[key] = values[0]
+		}
+	}
+	if err := config.DB.Create(&export).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to log export")
+		return
+	}
+
+	rows, err := query.Order("name ASC").Order("id").Rows()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to export customers")
+		return
+	}
+	defer rows.Close()
+
+	filename := "customers-" + time.Now().Format("2006-01-02")
+	var writeCustomer func(models.Customer) error
+	var finish func() error
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.csv\"", filename))
+		writeCustomer, finish = customerCSVWriter(c.Writer, salon.MoneyFormat())
+	} else {
+		c.Header("Content-Type", "text/vcard; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.vcf\"", filename))
+		writeCustomer = func(customer models.Customer) error {
+			_, err := io.WriteString(c.Writer, customerVCard(customer))
+			return err
+		}
+		finish = func() error { return nil }
+	}
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure part way can only end the stream
+	completed := true
+	for rows.Next() {
+		var customer models.Customer
+		if err := config.DB.ScanRows(rows, &customer); err != nil {
+			log.Printf("Customer export %s failed to read row: %v", export.ID, err)
+			completed = false
+			break
+		}
+		if err := writeCustomer(customer); err != nil {
+			log.Printf("Customer export %s stopped: %v", export.ID, err)
+			completed = false
+			break
+		}
+		export.RowCount++
+		if export.RowCount%customerExportFlushEvery == 0 {
+			if err := finish(); err != nil {
+				completed = false
+				break
+			}
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Customer export %s failed: %v", export.ID, err)
+		completed = false
+	}
+	if err := finish(); err != nil {
+		completed = false
+	}
+
+	if err := config.DB.Model(&models.CustomerExport{}).Where("id = ?", export.ID).Updates(map[string]interface{}{
+		"row_count": export.RowCount,
+		"completed": completed,
+	}).Error; err != nil {
+		log.Printf("Failed to update customer export log %s: %v", export.ID, err)
+	}
+}
+
+// GetCustomerExports lists the salon's export log, newest first. Owners only.
+func GetCustomerExports(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can view customer exports")
+		return
+	}
+
+	var exports []models.CustomerExport
+	if err := config.DB.Where("salon_id = ?", salonUUID).
+		Order("created_at DESC").
+		Limit(100).
+		Find(&exports).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch customer exports")
+		return
+	}
+
+	c.JSON(http.StatusOK, exports)
+}
+
+// customerCSVWriter writes customers as CSV rows after a heading row. The
+// returned finish function flushes buffered rows and reports write errors.
+func customerCSVWriter(out io.Writer, format utils.MoneyFormat) (func(models.Customer) error, func() error) {
+	amount := func(v float64) string {
+		return strconv.FormatFloat(format.Round(v), 'f', format.DecimalPlaces, 64)
+	}
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return format.FormatDate(*t)
+	}
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"Name", "Phone", "Email", "Birthday", "Anniversary", "Gender", "Tags", "Status", "Total Visits",
+		"Total Spent", "Average Spend", "Last Visit", "Loyalty Points", "Wallet Balance", "Currency", "Notes"})
+
+	write := func(customer models.Customer) error {
+		status := "Active"
+		if !customer.IsActive {
+			status = "Inactive"
+		}
+		average := 0.0
+		if customer.TotalVisits > 0 {
+			average = customer.TotalSpent / float64(customer.TotalVisits)
+		}
+		return w.Write([]string{
+			csvSafe(customer.Name),
+			customer.Phone,
+			csvSafe(customer.Email),
+			date(customer.Birthday),
+			date(customer.Anniversary),
+			customer.Gender,
+			csvSafe(strings.Join(customer.Tags, ", ")),
+			status,
+			strconv.Itoa(customer.TotalVisits),
+			amount(customer.TotalSpent),
+			amount(average),
+			date(customer.LastVisit),
+			strconv.Itoa(customer.LoyaltyPoints),
+			amount(customer.WalletBalance),
+			format.CurrencyCode,
+			csvSafe(customer.Notes),
+		})
+	}
+	finish := func() error {
+		w.Flush()
+		return w.Error()
+	}
+	return write, finish
+}
+
+// csvSafe stops spreadsheet apps from running free text as a formula.
+// Phone numbers are validated, so their leading "+" is left alone.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// customerVCard renders a vCard 3.0 contact for a customer
+func customerVCard(customer models.Customer) string {
+	var b strings.Builder
+	line := func(content string) {
+		b.WriteString(foldVCardLine(content))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCARD")
+	line("VERSION:3.0")
+	line("FN:" + vCardEscape(customer.Name))
+	line("N:" + vCardEscape(customer.Name) + ";;;;")
+	line("TEL;TYPE=CELL:" + vCardEscape(customer.Phone))
+	if customer.Email != "" {
+		line("EMAIL;TYPE=INTERNET:" + vCardEscape(customer.Email))
+	}
+	if customer.Birthday != nil {
+		line("BDAY:" + customer.Birthday.Format("2006-01-02"))
+	}
+	if customer.Anniversary != nil {
+		line("X-ANNIVERSARY:" + customer.Anniversary.Format("2006-01-02"))
+	}
+	if customer.Notes != "" {
+		line("NOTE:" + vCardEscape(customer.Notes))
+	}
+	line("UID:urn:uuid:" + customer.ID.String())
+	line("END:VCARD")
+	return b.String()
+}
+
+func vCardEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(s)
+}
+
+// foldVCardLine splits lines longer than 75 bytes as RFC 2425 requires,
+// without breaking a UTF-8 character
+func foldVCardLine(s string) string {
+	const limit = 75
+	if len(s) <= limit {
+		return s
+	}
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
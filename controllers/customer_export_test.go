@@ -0,0 +1,136 @@
+// controllers/customer_export_test.go
+package controllers
+
+import (
+	"salonpro-backend/models"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Asha Rao", "Asha Rao"},
+		{"", ""},
+		{"=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"+919876543210", "'+919876543210"},
+		{"-5", "'-5"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tcmd", "'\tcmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := csvSafe(tt.in); got != tt.want {
+				t.Errorf("csvSafe(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVCardEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Asha Rao", "Asha Rao"},
+		{"Rao, Asha", `Rao\, Asha`},
+		{"a;b", `a\;b`},
+		{`C:\notes`, `C:\\notes`},
+		{"line one\r\nline two\nthree", `line one\nline two\nthree`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := vCardEscape(tt.in); got != tt.want {
+				t.Errorf("vCardEscape(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFoldVCardLine(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"short", "FN:Asha Rao"},
+		{"exactly the limit", "NOTE:" + strings.Repeat("a", 70)},
+		{"long ascii", "NOTE:" + strings.Repeat("abcdefghij", 20)},
+		{"multi-byte characters", "NOTE:" + strings.Repeat("आशा राव ", 30)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := foldVCardLine(tt.in)
+			lines := strings.Split(got, "\r\n")
+			if len(tt.in) <= 75 && len(lines) != 1 {
+				t.Errorf("folded a %d byte line", len(tt.in))
+			}
+			for i, line := range lines {
+				if len(line) > 75 {
+					t.Errorf("line %d is %d bytes", i, len(line))
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d does not start with a space", i)
+				}
+			}
+			if unfolded := strings.ReplaceAll(got, "\r\n ", ""); unfolded != tt.in {
+				t.Errorf("unfolded = %q, want %q", unfolded, tt.in)
+			}
+		})
+	}
+}
+
+func TestCustomerVCard(t *testing.T) {
+	birthday := time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)
+	id := uuid.New()
+
+	tests := []struct {
+		name     string
+		customer models.Customer
+		want     []string
+		missing  []string
+	}{
+		{
+			name:     "name and phone only",
+			customer: models.Customer{ID: id, Name: "Asha Rao", Phone: "+919876543210"},
+			want:     []string{"BEGIN:VCARD", "VERSION:3.0", "FN:Asha Rao", "N:Asha Rao;;;;", "TEL;TYPE=CELL:+919876543210", "UID:urn:uuid:" + id.String(), "END:VCARD"},
+			missing:  []string{"EMAIL", "BDAY", "X-ANNIVERSARY", "NOTE"},
+		},
+		{
+			name:     "all fields",
+			customer: models.Customer{ID: id, Name: "Rao, Asha", Phone: "9876543210", Email: "asha@example.com", Birthday: &birthday, Notes: "Allergic; use mild dye"},
+			want:     []string{`FN:Rao\, Asha`, "EMAIL;TYPE=INTERNET:asha@example.com", "BDAY:1990-04-03", `NOTE:Allergic\; use mild dye`},
+			missing:  []string{"X-ANNIVERSARY"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			card := customerVCard(tt.customer)
+			if !strings.HasSuffix(card, "END:VCARD\r\n") {
+				t.Errorf("card does not end with END:VCARD and CRLF: %q", card)
+			}
+			lines := strings.Split(strings.TrimSuffix(card, "\r\n"), "\r\n")
+			has := map[string]bool{}
+			for _, line := range lines {
+				has[line] = true
+			}
+			for _, want := range tt.want {
+				if !has[want] {
+					t.Errorf("card has no line %q:\n%s", want, card)
+				}
+			}
+			for _, property := range tt.missing {
+				if strings.Contains(card, "\r\n"+property) {
+					t.Errorf("card has an empty %s line", property)
+				}
+			}
+		})
+	}
+}
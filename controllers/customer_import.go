@@ -0,0 +1,546 @@
+// controllers/customer_import.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCustomerImportBytes = 10 << 20
+	// Files with more rows than this are imported in the background
+	customerImportSyncRows = 500
+	// How often a background import saves its progress
+	customerImportProgressEvery = 100
+)
+
+// customerImportFields are the customer fields a column can be mapped to
+var customerImportFields = map[string]bool{
+	"name": true, "phone": true, "email": true, "birthday": true, "anniversary": true, "notes": true,
+}
+
+// customerImportHeaders recognises common spreadsheet headings, normalised by
+// normalizeImportHeader
+var customerImportHeaders = map[string]string{
+	"name": "name", "fullname": "name", "customer": "name", "customername": "name", "clientname": "name", "client": "name",
+	"phone": "phone", "phonenumber": "phone", "mobile": "phone", "mobilenumber": "phone", "mobileno": "phone",
+	"contact": "phone", "contactnumber": "phone", "cell": "phone", "whatsapp": "phone",
+	"email": "email", "emailaddress": "email", "mail": "email",
+	"birthday": "birthday", "dob": "birthday", "dateofbirth": "birthday", "birthdate": "birthday",
+	"anniversary": "anniversary", "anniversarydate": "anniversary", "weddinganniversary": "anniversary",
+	"notes": "notes", "note": "notes", "remarks": "notes", "comments": "notes",
+}
+
+// ImportCustomers imports customers from an uploaded CSV or XLSX file.
+//
+// Multipart fields: file; dryRun ("true" validates without saving); onDuplicate
+// ("skip" or "update"); dateOrder ("dmy" or "mdy", for dates like 03/04/2020);
+// mapping (optional JSON object of column heading -> customer field, on top of
+// the headings recognised automatically).
+func ImportCustomers(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can import customers")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "A CSV or XLSX file is required")
+		return
+	}
+	if fileHeader.Size > maxCustomerImportBytes {
+		utils.RespondWithError(c, http.StatusBadRequest, "File is larger than 10 MB")
+		return
+	}
+
+	onDuplicate := c.DefaultPostForm("onDuplicate", "skip")
+	if onDuplicate != "skip" && onDuplicate != "update" {
+		utils.RespondWithError(c, http.StatusBadRequest, "onDuplicate must be skip or update")
+		return
+	}
+	dateOrder := c.DefaultPostForm("dateOrder", "dmy")
+	if dateOrder != "dmy" && dateOrder != "mdy" {
+		utils.RespondWithError(c, http.StatusBadRequest, "dateOrder must be dmy or mdy")
+		return
+	}
+
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid mapping: "+err.Error())
+			return
+		}
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxCustomerImportBytes+1))
+	file.Close()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Failed to read file")
+		return
+	}
+
+	rows, err := utils.ReadSpreadsheet(fileHeader.Filename, data)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(rows) < 2 {
+		utils.RespondWithError(c, http.StatusBadRequest, "File needs a heading row and at least one customer")
+		return
+	}
+
+	columns, err := mapImportColumns(rows[0], mapping)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	job := models.CustomerImport{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: currentUser.ID,
+		Filename:        fileHeader.Filename,
+		DryRun:          c.PostForm("dryRun") == "true",
+		OnDuplicate:     onDuplicate,
+		Columns:         models.StringMap{},
+		Status:          "queued",
+	}
+	for col, field := range columns {
+		job.Columns[rows[0][col]] = field
+	}
+	for _, row := range rows[1:] {
+		if !isBlankRow(row) {
+			job.TotalRows++
+		}
+	}
+
+	if err := config.DB.Create(&job).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to start import")
+		return
+	}
+
+	importer := customerImporter{
+		db:       config.DB,
+		job:      &job,
+		rows:     rows[1:],
+		columns:  columns,
+		dayFirst: dateOrder == "dmy",
+	}
+
+	if job.TotalRows > customerImportSyncRows {
+		// Respond with a copy taken before the importer starts writing to job
+		queued := job
+		go importer.run()
+		c.JSON(http.StatusAccepted, queued)
+		return
+	}
+
+	importer.run()
+	c.JSON(http.StatusCreated, job)
+}
+
+// GetCustomerImports lists the salon's recent imports, newest first
+func GetCustomerImports(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var imports []models.CustomerImport
+	if err := config.DB.Omit("Issues").
+		Where("salon_id = ?", salonUUID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&imports).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch imports")
+		return
+	}
+
+	c.JSON(http.StatusOK, imports)
+}
+
+// GetCustomerImport returns an import's progress, counts and row issues
+func GetCustomerImport(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	importUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid import ID format")
+		return
+	}
+
+	var job models.CustomerImport
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, importUUID).
+		First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Import not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	progress := 100.0
+	if job.TotalRows > 0 {
+		progress = float64(job.ProcessedRows) * 100 / float64(job.TotalRows)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"import":   job,
+		"progress": progress,
+	})
+}
+
+// mapImportColumns works out which customer field each column holds. Explicit
+// mapping entries win over recognised headings; name and phone are required.
+func mapImportColumns(headings []string, mapping map[string]string) (map[int]string, error) {
+	explicit := map[string]string{}
+	for heading, field := range mapping {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field != "" && field != "ignore" && !customerImportFields[field] {
+			return nil, fmt.Errorf("unknown customer field %q in mapping", field)
+		}
+		explicit[normalizeImportHeader(heading)] = field
+	}
+
+	columns := map[int]string{}
+	used := map[string]int{}
+	for col, heading := range headings {
+		key := normalizeImportHeader(heading)
+		field, ok := explicit[key]
+		if !ok {
+			field = customerImportHeaders[key]
+		}
+		if field == "" || field == "ignore" {
+			continue
+		}
+		if prev, dup := used[field]; dup {
+			return nil, fmt.Errorf("columns %q and %q both map to %s", headings[prev], heading, field)
+		}
+		used[field] = col
+		columns[col] = field
+	}
+
+	for _, required := range []string{"name", "phone"} {
+		if _, ok := used[required]; !ok {
+			return nil, fmt.Errorf("no column found for %s; found headings %s", required, strings.Join(headings, ", "))
+		}
+	}
+	return columns, nil
+}
+
+// normalizeImportHeader lowercases a heading and drops spaces and punctuation,
+// so "Mobile No." and "mobile_no" match
+func normalizeImportHeader(heading string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(heading) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func isBlankRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// customerImporter validates and saves the rows of one import
+type customerImporter struct {
+	db       *gorm.DB
+	job      *models.CustomerImport
+	rows     [][]string
+	columns  map[int]string
+	dayFirst bool
+}
+
+// importedCustomer is one row's values once parsed
+type importedCustomer struct {
+	Name, Phone, Email, Notes string
+	Birthday, Anniversary     *time.Time
+}
+
+func (imp *customerImporter) run() {
+	job := imp.job
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Customer import %s panicked: %v", job.ID, r)
+			imp.finish("failed", fmt.Sprint(r))
+		}
+	}()
+
+	now := time.Now()
+	job.Status = "running"
+	job.StartedAt = &now
+	imp.saveProgress()
+
+	// Existing customers by phone digits, so "+91 98765 43210" matches "+919876543210".
+	// A shared household number matches the household's primary contact.
+	var existing []models.Customer
+	if err := imp.db.Select("id", "phone").Where("salon_id = ? AND household_primary_id IS NULL", job.SalonID).Find(&existing).Error; err != nil {
+		imp.finish("failed", "Failed to load existing customers")
+		return
+	}
+	existingByPhone := map[string]uuid.UUID{}
+	for _, customer := range existing {
+		existingByPhone[phoneDigits(customer.Phone)] = customer.ID
+	}
+	seenInFile := map[string]int{}
+
+	for i, row := range imp.rows {
+		if isBlankRow(row) {
+			continue
+		}
+		rowNumber := i + 2 // the heading is row 1
+
+		customer, ok := imp.parseRow(rowNumber, row)
+		if ok {
+			digits := phoneDigits(customer.Phone)
+			if first, dup := seenInFile[digits]; dup {
+				imp.addIssue(models.ImportIssue{Row: rowNumber, Field: "phone", Value: customer.Phone,
+					Message: fmt.Sprintf("Same phone as row %d", first)})
+				job.ErrorCount++
+			} else {
+				seenInFile[digits] = rowNumber
+				if existingID, found := existingByPhone[digits]; found {
+					imp.applyDuplicate(rowNumber, existingID, customer)
+				} else {
+					imp.create(rowNumber, customer)
+				}
+			}
+		} else {
+			job.ErrorCount++
+		}
+
+		job.ProcessedRows++
+		if job.ProcessedRows%customerImportProgressEvery == 0 {
+			imp.saveProgress()
+		}
+	}
+
+	imp.finish("completed", "")
+}
+
+// parseRow validates one row, recording an issue for each bad value
+func (imp *customerImporter) parseRow(rowNumber int, row []string) (importedCustomer, bool) {
+	var customer importedCustomer
+	ok := true
+	fail := func(field, value, message string) {
+		imp.addIssue(models.ImportIssue{Row: rowNumber, Field: field, Value: value, Message: message})
+		ok = false
+	}
+
+	for col, field := range imp.columns {
+		value := ""
+		if col < len(row) {
+			value = strings.TrimSpace(row[col])
+		}
+
+		switch field {
+		case "name":
+			customer.Name = value
+		case "phone":
+			customer.Phone = value
+		case "email":
+			if value != "" {
+				if addr, err := mail.ParseAddress(value); err != nil || addr.Address != value {
+					fail(field, value, "Invalid email address")
+				}
+			}
+			customer.Email = value
+		case "birthday", "anniversary":
+			if value == "" {
+				continue
+			}
+			date, err := utils.ParseFlexibleDate(value, imp.dayFirst)
+			if err != nil {
+				fail(field, value, "Unrecognised date")
+				continue
+			}
+			if field == "birthday" {
+				customer.Birthday = &date
+			} else {
+				customer.Anniversary = &date
+			}
+		case "notes":
+			customer.Notes = value
+		}
+	}
+
+	if customer.Name == "" {
+		fail("name", "", "Name is required")
+	}
+	if customer.Phone == "" {
+		fail("phone", "", "Phone is required")
+	} else if !utils.ValidatePhone(customer.Phone) {
+		fail("phone", customer.Phone, "Invalid phone number format")
+	}
+	return customer, ok
+}
+
+// create adds a new customer, or only counts it on a dry run
+func (imp *customerImporter) create(rowNumber int, customer importedCustomer) {
+	job := imp.job
+	if !job.DryRun {
+		if err := imp.db.Create(&models.Customer{
+			ID:              uuid.New(),
+			SalonID:         job.SalonID,
+			CreatedByUserID: job.CreatedByUserID,
+			Name:            customer.Name,
+			Phone:           customer.Phone,
+			Email:           customer.Email,
+			Birthday:        customer.Birthday,
+			Anniversary:     customer.Anniversary,
+			Notes:           customer.Notes,
+			IsActive:        true,
+		}).Error; err != nil {
+			imp.addIssue(models.ImportIssue{Row: rowNumber, Message: "Failed to save customer"})
+			job.ErrorCount++
+			return
+		}
+	}
+	job.CreatedCount++
+}
+
+// applyDuplicate skips a customer that already exists, or fills in the values
+// the spreadsheet provides when the import updates duplicates
+func (imp *customerImporter) applyDuplicate(rowNumber int, customerID uuid.UUID, customer importedCustomer) {
+	job := imp.job
+	if job.OnDuplicate != "update" {
+		imp.addIssue(models.ImportIssue{Row: rowNumber, Field: "phone", Value: customer.Phone,
+			Message: "Customer with this phone number already exists", Skipped: true})
+		job.SkippedCount++
+		return
+	}
+
+	updates := map[string]interface{}{"name": customer.Name}
+	if customer.Email != "" {
+		updates["email"] = customer.Email
+	}
+	if customer.Birthday != nil {
+		updates["birthday"] = customer.Birthday
+	}
+	if customer.Anniversary != nil {
+		updates["anniversary"] = customer.Anniversary
+	}
+	if customer.Notes != "" {
+		updates["notes"] = customer.Notes
+	}
+
+	if !job.DryRun {
+		if err := imp.db.Model(&models.Customer{}).Where("id = ?", customerID).Updates(updates).Error; err != nil {
+			imp.addIssue(models.ImportIssue{Row: rowNumber, Message: "Failed to update customer"})
+			job.ErrorCount++
+			return
+		}
+	}
+	job.UpdatedCount++
+}
+
+func (imp *customerImporter) addIssue(issue models.ImportIssue) {
+	if len(imp.job.Issues) < models.MaxImportIssues {
+		imp.job.Issues = append(imp.job.Issues, issue)
+	}
+}
+
+func (imp *customerImporter) saveProgress() {
+	job := imp.job
+	if err := imp.db.Model(&models.CustomerImport{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":         job.Status,
+		"started_at":     job.StartedAt,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"skipped_count":  job.SkippedCount,
+		"error_count":    job.ErrorCount,
+	}).Error; err != nil {
+		log.Printf("Failed to save progress of customer import %s: %v", job.ID, err)
+	}
+}
+
+func (imp *customerImporter) finish(status, reason string) {
+	job := imp.job
+	now := time.Now()
+	job.Status = status
+	job.FailureReason = reason
+	job.FinishedAt = &now
+	if err := imp.db.Model(&models.CustomerImport{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":         job.Status,
+		"failure_reason": job.FailureReason,
+		"finished_at":    job.FinishedAt,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"skipped_count":  job.SkippedCount,
+		"error_count":    job.ErrorCount,
+		"issues":         job.Issues,
+	}).Error; err != nil {
+		log.Printf("Failed to finish customer import %s: %v", job.ID, err)
+	}
+}
@@ -0,0 +1,191 @@
+// controllers/customer_import_test.go
+package controllers
+
+import (
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+)
+
+func TestNormalizeImportHeader(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Name", "name"},
+		{"Mobile No.", "mobileno"},
+		{"mobile_no", "mobileno"},
+		{" E-mail Address ", "emailaddress"},
+		{"D.O.B", "dob"},
+		{"Phone 2", "phone2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := normalizeImportHeader(tt.in); got != tt.want {
+				t.Errorf("normalizeImportHeader(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapImportColumns(t *testing.T) {
+	tests := []struct {
+		name     string
+		headings []string
+		mapping  map[string]string
+		want     map[int]string
+		wantErr  bool
+	}{
+		{
+			name:     "recognised headings",
+			headings: []string{"Customer Name", "Mobile No.", "Email", "DOB", "Remarks"},
+			want:     map[int]string{0: "name", 1: "phone", 2: "email", 3: "birthday", 4: "notes"},
+		},
+		{
+			name:     "unknown headings are skipped",
+			headings: []string{"Name", "Phone", "Last Visit"},
+			want:     map[int]string{0: "name", 1: "phone"},
+		},
+		{
+			name:     "explicit mapping",
+			headings: []string{"Client", "Tel", "Wedding"},
+			mapping:  map[string]string{"Tel": "phone", "wedding": "Anniversary"},
+			want:     map[int]string{0: "name", 1: "phone", 2: "anniversary"},
+		},
+		{
+			name:     "mapping a column to ignore",
+			headings: []string{"Name", "Phone", "WhatsApp"},
+			mapping:  map[string]string{"WhatsApp": "ignore"},
+			want:     map[int]string{0: "name", 1: "phone"},
+		},
+		{
+			name:     "two columns for one field",
+			headings: []string{"Name", "Mobile", "WhatsApp"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field in mapping",
+			headings: []string{"Name", "Phone", "Gender"},
+			mapping:  map[string]string{"Gender": "gender"},
+			wantErr:  true,
+		},
+		{
+			name:     "no phone column",
+			headings: []string{"Name", "Email"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapImportColumns(tt.headings, tt.mapping)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mapImportColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mapImportColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBlankRow(t *testing.T) {
+	tests := []struct {
+		row  []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"", " ", "\t"}, true},
+		{[]string{"", "9876543210"}, false},
+	}
+	for _, tt := range tests {
+		if got := isBlankRow(tt.row); got != tt.want {
+			t.Errorf("isBlankRow(%q) = %v, want %v", tt.row, got, tt.want)
+		}
+	}
+}
+
+func TestImportParseRow(t *testing.T) {
+	columns := map[int]string{0: "name", 1: "phone", 2: "email", 3: "birthday", 4: "notes"}
+
+	tests := []struct {
+		name         string
+		row          []string
+		dayFirst     bool
+		wantOK       bool
+		wantBirthday time.Time
+		wantIssues   []string // field of each issue
+	}{
+		{
+			name:         "valid row, day first",
+			row:          []string{" Asha Rao ", "+91 98765 43210", "asha@example.com", "03/04/1990", "Prefers mornings"},
+			dayFirst:     true,
+			wantOK:       true,
+			wantBirthday: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:         "valid row, month first",
+			row:          []string{"Asha Rao", "9876543210", "", "03/04/1990"},
+			wantOK:       true,
+			wantBirthday: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "short row",
+			row:    []string{"Asha Rao", "9876543210"},
+			wantOK: true,
+		},
+		{
+			name:       "missing name and phone",
+			row:        []string{"", "", "asha@example.com"},
+			wantIssues: []string{"name", "phone"},
+		},
+		{
+			name:       "bad phone",
+			row:        []string{"Asha Rao", "98765-abc"},
+			wantIssues: []string{"phone"},
+		},
+		{
+			name:       "display-name email",
+			row:        []string{"Asha Rao", "9876543210", "Asha <asha@example.com>"},
+			wantIssues: []string{"email"},
+		},
+		{
+			name:       "bad date",
+			row:        []string{"Asha Rao", "9876543210", "", "31/31/1990"},
+			dayFirst:   true,
+			wantIssues: []string{"birthday"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp := &customerImporter{job: &models.CustomerImport{}, columns: columns, dayFirst: tt.dayFirst}
+			customer, ok := imp.parseRow(2, tt.row)
+			if ok != tt.wantOK {
+				t.Fatalf("parseRow() ok = %v, want %v (issues %+v)", ok, tt.wantOK, imp.job.Issues)
+			}
+
+			var issues []string
+			for _, issue := range imp.job.Issues {
+				if issue.Row != 2 {
+					t.Errorf("issue row = %d, want 2", issue.Row)
+				}
+				issues = append(issues, issue.Field)
+			}
+			if !reflect.DeepEqual(issues, tt.wantIssues) {
+				t.Errorf("issues = %v, want %v", issues, tt.wantIssues)
+			}
+
+			if !tt.wantOK {
+				return
+			}
+			if customer.Name != "Asha Rao" {
+				t.Errorf("name = %q, want trimmed", customer.Name)
+			}
+			switch {
+			case tt.wantBirthday.IsZero() && customer.Birthday != nil:
+				t.Errorf("birthday = %v, want none", customer.Birthday)
+			case !tt.wantBirthday.IsZero() && (customer.Birthday == nil || !customer.Birthday.Equal(tt.wantBirthday)):
+				t.Errorf("birthday = %v, want %v", customer.Birthday, tt.wantBirthday)
+			}
+		})
+	}
+}
@@ -0,0 +1,610 @@
+// controllers/customer_merge.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Weights of each signal in a duplicate score, capped at 1
+const (
+	duplicateNameWeight      = 0.6 // times the name similarity
+	duplicateEmailWeight     = 0.5
+	duplicateSamePhoneWeight = 0.6 // same number written differently, e.g. with and without +91
+	duplicatePhoneTypoWeight = 0.35
+	duplicateNameSimilarity  = 0.5 // pg_trgm similarity for a name-only candidate
+	defaultDuplicateMinScore = 0.5
+	defaultDuplicateLimit    = 50
+	maxDuplicateSuggestions  = 200
+	phoneSuffixDigits        = 10
+)
+
+// DuplicateCustomerView is one suggested pair of customers that may be the same person
+type DuplicateCustomerView struct {
+	Customers [2]DuplicateCustomerSummary `json:"customers"`
+	Score     float64                     `json:"score"`
+	Reasons   []string                    `json:"reasons"`
+}
+
+// DuplicateCustomerSummary is what a reviewer needs to choose the survivor
+type DuplicateCustomerSummary struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Phone       string     `json:"phone"`
+	Email       string     `json:"email"`
+	TotalVisits int        `json:"totalVisits"`
+	TotalSpent  float64    `json:"totalSpent"`
+	LastVisit   *time.Time `json:"lastVisit"`
+}
+
+// MergeCustomerInput names the duplicate to fold into the customer in the URL
+type MergeCustomerInput struct {
+	DuplicateID uuid.UUID `json:"duplicateId" binding:"required"`
+	Reason      string    `json:"reason"`
+}
+
+// GetDuplicateCustomers suggests pairs of customers that are likely the same
+// person, from similar names, matching emails and phone numbers that are equal
+// once formatted alike or differ by one digit. Members of the same household
+// share a number and are never suggested. ?minScore= (0-1) and ?limit= tune the
+// list. Owners and managers only.
+func GetDuplicateCustomers(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can review duplicate customers")
+		return
+	}
+
+	minScore := defaultDuplicateMinScore
+	if raw := c.Query("minScore"); raw != "" {
+		if minScore, err = strconv.ParseFloat(raw, 64); err != nil || minScore < 0 || minScore > 1 {
+			utils.RespondWithError(c, http.StatusBadRequest, "minScore must be between 0 and 1")
+			return
+		}
+	}
+	limit := defaultDuplicateLimit
+	if raw := c.Query("limit"); raw != "" {
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxDuplicateSuggestions {
+			utils.RespondWithError(c, http.StatusBadRequest, "limit must be between 1 and 200")
+			return
+		}
+	}
+
+	var customers []models.Customer
+	if err := config.DB.Select("id", "name", "phone", "email", "total_visits", "total_spent", "last_visit", "household_primary_id").
+		Where("salon_id = ?", salonUUID).
+		Find(&customers).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch customers")
+		return
+	}
+	byID := make(map[uuid.UUID]models.Customer, len(customers))
+	for _, customer := range customers {
+		byID[customer.ID] = customer
+	}
+
+	candidates := map[[2]uuid.UUID]*DuplicateCustomerView{}
+	candidate := func(a, b uuid.UUID) *DuplicateCustomerView {
+		if b.String() < a.String() {
+			a, b = b, a
+		}
+		key := [2]uuid.UUID{a, b}
+		if view, ok := candidates[key]; ok {
+			return view
+		}
+		view := &DuplicateCustomerView{Reasons: []string{}}
+		candidates[key] = view
+		return view
+	}
+
+	// Similar names, found through the trigram index on customers.name
+	type namePair struct {
+		AID uuid.UUID
+		BID uuid.UUID
+	}
+	var namePairs []namePair
+	if err := config.DB.Raw(`
+		SELECT a.id AS a_id, b.id AS b_id
+		FROM customers a
+		JOIN customers b ON b.salon_id = a.salon_id AND a.id < b.id
+		WHERE a.salon_id = ?
+		  AND similarity(a.name, b.name) >= ?
+		  AND a.name % b.name
+	`, salonUUID, duplicateNameSimilarity).Scan(&namePairs).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to find duplicate customers")
+		return
+	}
+	for _, pair := range namePairs {
+		candidate(pair.AID, pair.BID)
+	}
+
+	// Same email, ignoring case
+	byEmail := map[string][]uuid.UUID{}
+	for _, customer := range customers {
+		if email := strings.ToLower(strings.TrimSpace(customer.Email)); email != "" {
+			byEmail[email] = append(byEmail[email], customer.ID)
+		}
+	}
+	for _, ids := range byEmail {
+		forEachPair(ids, func(a, b uuid.UUID) { candidate(a, b) })
+	}
+
+	// Phones compared on their last ten digits, so country codes and formatting
+	// don't matter. Masking each digit in turn buckets numbers one typo apart.
+	byPhone := map[string][]uuid.UUID{}
+	byPhoneMask := map[string][]uuid.UUID{}
+	for _, customer := range customers {
+		suffix := phoneSuffix(customer.Phone)
+		if suffix == "" {
+			continue
+		}
+		byPhone[suffix] = append(byPhone[suffix], customer.ID)
+		for i := range suffix {
+			masked := suffix[:i] + "?" + suffix[i+1:]
+			byPhoneMask[masked] = append(byPhoneMask[masked], customer.ID)
+		}
+	}
+	for _, ids := range byPhone {
+		forEachPair(ids, func(a, b uuid.UUID) { candidate(a, b) })
+	}
+	for _, ids := range byPhoneMask {
+		forEachPair(ids, func(a, b uuid.UUID) { candidate(a, b) })
+	}
+
+	suggestions := []DuplicateCustomerView{}
+	for key, view := range candidates {
+		a, b := byID[key[0]], byID[key[1]]
+		if householdRoot(a) == householdRoot(b) {
+			continue
+		}
+		scoreDuplicatePair(view, a, b)
+		if view.Score < minScore {
+			continue
+		}
+		view.Customers = [2]DuplicateCustomerSummary{duplicateSummary(a), duplicateSummary(b)}
+		suggestions = append(suggestions, *view)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].Customers[0].Name < suggestions[j].Customers[0].Name
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"duplicates": suggestions,
+		"minScore":   minScore,
+	})
+}
+
+// MergeCustomer folds a duplicate into the customer in the URL: invoices and
+// all other history move to the survivor, loyalty points and wallet balance are
+// added together, blank details are filled from the duplicate, its household
+// members join the survivor's household, visit and spend stats are recomputed
+// from the invoices, and the duplicate is deleted. The merge is recorded with a
+// snapshot of both customers. Owners and managers only.
+func MergeCustomer(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	survivorUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can merge customers")
+		return
+	}
+
+	var input MergeCustomerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.DuplicateID == survivorUUID {
+		utils.RespondWithError(c, http.StatusBadRequest, "A customer cannot be merged into itself")
+		return
+	}
+
+	var survivor models.Customer
+	var merge models.CustomerMerge
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var pair []models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("salon_id = ? AND id IN ?", salonUUID, []uuid.UUID{survivorUUID, input.DuplicateID}).
+			Order("id").
+			Find(&pair).Error; err != nil {
+			return err
+		}
+		var duplicate models.Customer
+		for _, customer := range pair {
+			if customer.ID == survivorUUID {
+				survivor = customer
+			} else {
+				duplicate = customer
+			}
+		}
+		if survivor.ID == uuid.Nil || duplicate.ID == uuid.Nil {
+			return errCustomerNotFound
+		}
+
+		merge = models.CustomerMerge{
+			ID:               uuid.New(),
+			SalonID:          salonUUID,
+			SurvivorID:       survivor.ID,
+			MergedCustomerID: duplicate.ID,
+			MergedByUserID:   currentUser.ID,
+			MergedCustomer:   models.SnapshotCustomer(duplicate),
+			SurvivorBefore:   models.SnapshotCustomer(survivor),
+			MovedRecords:     models.IntMap{},
+			Reason:           input.Reason,
+		}
+
+		moved, err := moveCustomerHistory(tx, salonUUID, duplicate.ID, survivor.ID)
+		if err != nil {
+			return err
+		}
+		merge.MovedRecords = moved
+
+		// The duplicate's household members join the survivor's household. A
+		// survivor that was one of them becomes the primary contact.
+		updates := map[string]interface{}{}
+		root := householdRoot(survivor)
+		if root == duplicate.ID {
+			root = survivor.ID
+			updates["household_primary_id"] = nil
+			updates["relationship"] = ""
+		}
+		result := tx.Model(&models.Customer{}).
+			Where("household_primary_id = ? AND id <> ?", duplicate.ID, survivor.ID).
+			Update("household_primary_id", root)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			moved["customers.household_primary_id"] = int(result.RowsAffected)
+		}
+
+		// Nothing points at the duplicate any more
+		if err := tx.Delete(&duplicate).Error; err != nil {
+			return err
+		}
+
+		updates["loyalty_points"] = gorm.Expr("loyalty_points + ?", duplicate.LoyaltyPoints)
+		updates["wallet_balance"] = gorm.Expr("wallet_balance + ?", duplicate.WalletBalance)
+		updates["is_active"] = survivor.IsActive || duplicate.IsActive
+		if survivor.Email == "" && duplicate.Email != "" {
+			updates["email"] = duplicate.Email
+		}
+		if survivor.Birthday == nil && duplicate.Birthday != nil {
+			updates["birthday"] = duplicate.Birthday
+		}
+		if survivor.Anniversary == nil && duplicate.Anniversary != nil {
+			updates["anniversary"] = duplicate.Anniversary
+		}
+		if survivor.Gender == "" && duplicate.Gender != "" {
+			updates["gender"] = duplicate.Gender
+		}
+		if len(duplicate.Tags) > 0 {
+			tags, err := canonicalCustomerTags(tx, salonUUID, append(append([]string{}, survivor.Tags...), duplicate.Tags...))
+			if err != nil {
+				return err
+			}
+			updates["tags"] = tags
+		}
+		// Keep the duplicate's number and notes where staff will see them
+		mergedNote := "Merged from " + duplicate.Name + " (" + duplicate.Phone + ")"
+		if duplicate.Notes != "" {
+			mergedNote += ": " + duplicate.Notes
+		}
+		if survivor.Notes != "" {
+			mergedNote = survivor.Notes + "\n" + mergedNote
+		}
+		updates["notes"] = mergedNote
+
+		if err := tx.Model(&models.Customer{}).Where("id = ?", survivor.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := recomputeCustomerStats(tx, survivor.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&merge).Error; err != nil {
+			return err
+		}
+		return tx.First(&survivor, "id = ?", survivor.ID).Error
+	})
+	if err != nil {
+		if errors.Is(err, errCustomerNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to merge customers")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customer": survivor,
+		"merge":    merge,
+	})
+}
+
+// GetCustomerMerges lists the merges into a customer, newest first
+func GetCustomerMerges(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var merges []models.CustomerMerge
+	if err := config.DB.Where("salon_id = ? AND survivor_id = ?", salonUUID, customerUUID).
+		Order("created_at DESC").
+		Find(&merges).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch customer merges")
+		return
+	}
+
+	c.JSON(http.StatusOK, merges)
+}
+
+var errCustomerNotFound = errors.New("customer not found")
+
+// customerHistoryTables lists every table that points at a customer, with the
+// column holding the customer ID
+var customerHistoryTables = []struct {
+	Table  string
+	Column string
+}{
+	{"invoices", "customer_id"},
+	{"invoice_voids", "customer_id"},
+	{"coupon_redemptions", "customer_id"},
+	{"customer_memberships", "customer_id"},
+	{"customer_packages", "customer_id"},
+	{"loyalty_transactions", "customer_id"},
+	{"wallet_transactions", "customer_id"},
+	{"gift_cards", "purchaser_customer_id"},
+	{"gift_cards", "recipient_customer_id"},
+	{"reminder_logs", "customer_id"},
+	{"customer_consents", "customer_id"},
+	{"customer_changes", "customer_id"},
+	{"service_records", "customer_id"},
+	{"customer_allergies", "customer_id"},
+	{"patch_tests", "customer_id"},
+	{"attachments", "customer_id"},
+}
+
+// moveCustomerHistory repoints the duplicate's records at the survivor and
+// returns how many rows moved per table. Ledger rows keep their original
+// BalanceAfter; the merged balances are added on the customer.
+func moveCustomerHistory(tx *gorm.DB, salonID, fromID, toID uuid.UUID) (models.IntMap, error) {
+	moved := models.IntMap{}
+	for _, ref := range customerHistoryTables {
+		result := tx.Table(ref.Table).
+			Where("salon_id = ? AND "+ref.Column+" = ?", salonID, fromID).
+			Update(ref.Column, toID)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			moved[ref.Table+"."+ref.Column] = int(result.RowsAffected)
+		}
+	}
+	return moved, nil
+}
+
+// recomputeCustomerStats sets visits, spend and last visit from the customer's
+// invoices, counting them the way invoiceCustomerStats does
+func recomputeCustomerStats(tx *gorm.DB, customerID uuid.UUID) error {
+	return tx.Exec(`
+		UPDATE customers SET
+			total_visits = s.visits,
+			total_spent = s.spent,
+			last_visit = s.last_visit
+		FROM (
+			SELECT COUNT(*) FILTER (WHERE v.is_visit) AS visits,
+				COALESCE(SUM(v.spent), 0) AS spent,
+				MAX(v.invoice_date) FILTER (WHERE v.is_visit) AS last_visit
+			FROM (
+				SELECT i.invoice_date,
+					i.total - COALESCE(SUM(ii.total_price) FILTER (WHERE ii.item_type = 'gift_card'), 0) AS spent,
+					COUNT(ii.id) = 0 OR COUNT(ii.id) FILTER (WHERE ii.item_type NOT IN ('gift_card', 'membership', 'package')) > 0 AS is_visit
+				FROM invoices i
+				LEFT JOIN invoice_items ii ON ii.invoice_id = i.id
+				WHERE i.customer_id = ? AND i.deleted_at IS NULL
+				GROUP BY i.id
+			) v
+		) s
+		WHERE customers.id = ?
+	`, customerID, customerID).Error
+}
+
+// scoreDuplicatePair scores how likely two customers are the same person and
+// explains why
+func scoreDuplicatePair(view *DuplicateCustomerView, a, b models.Customer) {
+	score := 0.0
+	if similarity := nameSimilarity(a.Name, b.Name); similarity >= duplicateNameSimilarity {
+		score += similarity * duplicateNameWeight
+		if similarity == 1 {
+			view.Reasons = append(view.Reasons, "same name")
+		} else {
+			view.Reasons = append(view.Reasons, "similar name")
+		}
+	}
+	if a.Email != "" && strings.EqualFold(strings.TrimSpace(a.Email), strings.TrimSpace(b.Email)) {
+		score += duplicateEmailWeight
+		view.Reasons = append(view.Reasons, "same email")
+	}
+	pa, pb := phoneSuffix(a.Phone), phoneSuffix(b.Phone)
+	if pa != "" && pa == pb {
+		score += duplicateSamePhoneWeight
+		view.Reasons = append(view.Reasons, "same phone number")
+	} else if pa != "" && len(pa) == len(pb) && digitsApart(pa, pb) == 1 {
+		score += duplicatePhoneTypoWeight
+		view.Reasons = append(view.Reasons, "phone differs by one digit")
+	}
+	if score > 1 {
+		score = 1
+	}
+	view.Score = float64(int(score*100+0.5)) / 100
+}
+
+func duplicateSummary(customer models.Customer) DuplicateCustomerSummary {
+	return DuplicateCustomerSummary{
+		ID:          customer.ID,
+		Name:        customer.Name,
+		Phone:       customer.Phone,
+		Email:       customer.Email,
+		TotalVisits: customer.TotalVisits,
+		TotalSpent:  customer.TotalSpent,
+		LastVisit:   customer.LastVisit,
+	}
+}
+
+// householdRoot is the ID of the customer's household primary, or the
+// customer's own ID if they are not a member of one
+func householdRoot(customer models.Customer) uuid.UUID {
+	if customer.HouseholdPrimaryID != nil {
+		return *customer.HouseholdPrimaryID
+	}
+	return customer.ID
+}
+
+func forEachPair(ids []uuid.UUID, fn func(a, b uuid.UUID)) {
+	for i := 0; i < len(ids); i++ {
+		for j := i + 1; j < len(ids); j++ {
+			fn(ids[i], ids[j])
+		}
+	}
+}
+
+// phoneSuffix returns the last ten digits of a phone number, or all of them
+// for shorter numbers
+func phoneSuffix(phone string) string {
+	var b strings.Builder
+	for _, r := range phone {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	digits := b.String()
+	if len(digits) > phoneSuffixDigits {
+		digits = digits[len(digits)-phoneSuffixDigits:]
+	}
+	return digits
+}
+
+// digitsApart counts the positions at which two equal-length strings differ
+func digitsApart(a, b string) int {
+	n := 0
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
+
+// nameSimilarity mirrors pg_trgm's similarity(): the share of word trigrams
+// the two names have in common
+func nameSimilarity(a, b string) float64 {
+	ta, tb := nameTrigrams(a), nameTrigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func nameTrigrams(s string) map[string]bool {
+	trigrams := map[string]bool{}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trigrams[string(padded[i:i+3])] = true
+		}
+	}
+	return trigrams
+}
@@ -0,0 +1,130 @@
+// controllers/customer_merge_test.go
+package controllers
+
+import (
+	"math"
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+)
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Asha Rao", "Asha Rao", 1},
+		{"ASHA RAO", "asha rao", 1},
+		{"Asha Rao", "Rao, Asha", 1},
+		{"Asha Rao", "Asha Rau", 7.0 / 11},
+		{"Asha Rao", "Ravi Kumar", 2.0 / 18},
+		{"Asha", "", 0},
+		{"...", "...", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := nameSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("nameSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhoneSuffix(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"9876543210", "9876543210"},
+		{"+91 98765 43210", "9876543210"},
+		{"0091-98765-43210", "9876543210"},
+		{"080-2345", "0802345"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := phoneSuffix(tt.in); got != tt.want {
+				t.Errorf("phoneSuffix(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigitsApart(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"9876543210", "9876543210", 0},
+		{"9876543210", "9876543211", 1},
+		{"9876543210", "8976543210", 2},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		if got := digitsApart(tt.a, tt.b); got != tt.want {
+			t.Errorf("digitsApart(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScoreDuplicatePair(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    models.Customer
+		score   float64
+		reasons []string
+	}{
+		{
+			name:    "same person, number written differently",
+			a:       models.Customer{Name: "Asha Rao", Phone: "+91 98765 43210"},
+			b:       models.Customer{Name: "asha rao", Phone: "9876543210"},
+			score:   1,
+			reasons: []string{"same name", "same phone number"},
+		},
+		{
+			name:    "shared phone, different names",
+			a:       models.Customer{Name: "Asha Rao", Phone: "9876543210"},
+			b:       models.Customer{Name: "Ravi Kumar", Phone: "9876543210"},
+			score:   0.6,
+			reasons: []string{"same phone number"},
+		},
+		{
+			name:    "same email in another case",
+			a:       models.Customer{Name: "Asha Rao", Phone: "9876543210", Email: "Asha@Example.com"},
+			b:       models.Customer{Name: "A. Kumar", Phone: "9123456780", Email: " asha@example.com"},
+			score:   0.5,
+			reasons: []string{"same email"},
+		},
+		{
+			name:    "misspelt name and mistyped phone",
+			a:       models.Customer{Name: "Asha Rao", Phone: "9876543210"},
+			b:       models.Customer{Name: "Asha Rau", Phone: "9876543211"},
+			score:   0.73,
+			reasons: []string{"similar name", "phone differs by one digit"},
+		},
+		{
+			name:  "blank emails are not a match",
+			a:     models.Customer{Name: "Asha Rao", Phone: "9876543210"},
+			b:     models.Customer{Name: "Ravi Kumar", Phone: "9123456780"},
+			score: 0,
+		},
+		{
+			name:    "numbers of different lengths",
+			a:       models.Customer{Name: "Ravi Kumar", Phone: "98765"},
+			b:       models.Customer{Name: "Asha Rao", Phone: "98766"},
+			score:   0.35,
+			reasons: []string{"phone differs by one digit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var view DuplicateCustomerView
+			scoreDuplicatePair(&view, tt.a, tt.b)
+			if view.Score != tt.score {
+				t.Errorf("score = %v, want %v", view.Score, tt.score)
+			}
+			if !reflect.DeepEqual(view.Reasons, tt.reasons) {
+				t.Errorf("reasons = %v, want %v", view.Reasons, tt.reasons)
+			}
+		})
+	}
+}
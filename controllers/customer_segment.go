@@ -0,0 +1,635 @@
+// controllers/customer_segment.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// customerHasAnyTag matches customers with any of the given lowercased tags
+const customerHasAnyTag = "EXISTS (SELECT 1 FROM jsonb_array_elements_text(customers.tags) t WHERE lower(t) IN ?)"
+
+const maxCustomerTagLength = 50
+
+// CustomerSegmentController sends messages to segments through the reminder channels
+type CustomerSegmentController struct {
+	Reminders *services.ReminderService
+}
+
+// CustomerSegmentInput defines the expected JSON structure for saving a segment
+type CustomerSegmentInput struct {
+	Name        string              `json:"name" binding:"required"`
+	Description string              `json:"description"`
+	Rules       models.SegmentRules `json:"rules"`
+}
+
+// SegmentPreviewInput holds unsaved rules to try out
+type SegmentPreviewInput struct {
+	Rules models.SegmentRules `json:"rules"`
+}
+
+// SegmentMessageInput is a message for every active customer in a segment.
+// The reminder placeholders such as [CustomerName] can be used.
+type SegmentMessageInput struct {
+	Message string `json:"message" binding:"required"`
+}
+
+// CustomerTagCount is a tag in use and how many customers have it
+type CustomerTagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// GetCustomerTags lists the tags used in the salon with how many customers have each
+func GetCustomerTags(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	tags := []CustomerTagCount{}
+	if err := config.DB.Raw(`
+		SELECT t AS tag, COUNT(*) AS count
+		FROM customers, jsonb_array_elements_text(customers.tags) t
+		WHERE customers.salon_id = ?
+		GROUP BY t
+		ORDER BY count DESC, t
+	`, salonUUID).Scan(&tags).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch customer tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// CreateCustomerSegment saves a new segment. Owners and managers only.
+func CreateCustomerSegment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can manage segments")
+		return
+	}
+
+	var input CustomerSegmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := validateSegmentRules(input.Rules); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	segment := models.CustomerSegment{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: currentUser.ID,
+		Name:            strings.TrimSpace(input.Name),
+		Description:     input.Description,
+		Rules:           input.Rules,
+	}
+	if err := config.DB.Create(&segment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create segment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, segment)
+}
+
+// GetCustomerSegments lists the salon's segments with their current size
+func GetCustomerSegments(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var segments []models.CustomerSegment
+	if err := config.DB.Where("salon_id = ?", salonUUID).Order("name").Find(&segments).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch segments")
+		return
+	}
+
+	result := make([]gin.H, 0, len(segments))
+	for _, segment := range segments {
+		count, err := countSegmentCustomers(salonUUID, segment.Rules)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to count segment customers")
+			return
+		}
+		result = append(result, gin.H{"segment": segment, "customerCount": count})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetCustomerSegment returns a segment with its current size. The customers
+// themselves come from the customer list with ?segmentId=.
+func GetCustomerSegment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	segment, ok := loadCustomerSegment(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	count, err := countSegmentCustomers(salonUUID, segment.Rules)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to count segment customers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"segment": segment, "customerCount": count})
+}
+
+// UpdateCustomerSegment replaces a segment's name, description and rules.
+// Owners and managers only.
+func UpdateCustomerSegment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can manage segments")
+		return
+	}
+
+	var input CustomerSegmentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := validateSegmentRules(input.Rules); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	segment, ok := loadCustomerSegment(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	segment.Name = strings.TrimSpace(input.Name)
+	segment.Description = input.Description
+	segment.Rules = input.Rules
+	if err := config.DB.Save(&segment).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update segment")
+		return
+	}
+
+	c.JSON(http.StatusOK, segment)
+}
+
+// DeleteCustomerSegment removes a segment. Owners and managers only.
+func DeleteCustomerSegment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	segmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid segment ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can manage segments")
+		return
+	}
+
+	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, segmentUUID).Delete(&models.CustomerSegment{})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete segment")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Segment not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Segment deleted successfully"})
+}
+
+// PreviewCustomerSegment counts the customers unsaved rules would match and
+// returns the first few
+func PreviewCustomerSegment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input SegmentPreviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+	if err := validateSegmentRules(input.Rules); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	count, err := countSegmentCustomers(salonUUID, input.Rules)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to count segment customers")
+		return
+	}
+
+	var sample []models.Customer
+	if err := applySegmentRules(config.DB.Model(&models.Customer{}).Where("salon_id = ?", salonUUID), input.Rules).
+		Order("name").
+		Limit(20).
+		Find(&sample).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch segment customers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerCount": count,
+		"customers":     sample,
+	})
+}
+
+// SendSegmentMessage messages every active customer in a segment over the
+// salon's WhatsApp or SMS channel. Sending runs in the background and is
+// tracked as a SegmentMessage; each message is logged with the reminders.
+// Owners and managers only.
+func (sc *CustomerSegmentController) SendSegmentMessage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can message segments")
+		return
+	}
+
+	var input SegmentMessageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+	if !salon.WhatsAppNotifications && !salon.SMSNotifications {
+		utils.RespondWithError(c, http.StatusBadRequest, "Turn on WhatsApp or SMS notifications to message customers")
+		return
+	}
+
+	segment, ok := loadCustomerSegment(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var customers []models.Customer
+	if err := applySegmentRules(config.DB.Model(&models.Customer{}).Where("salon_id = ? AND is_active = true", salonUUID), segment.Rules).
+		Find(&customers).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch segment customers")
+		return
+	}
+
+	job := models.SegmentMessage{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		SegmentID:       segment.ID,
+		CreatedByUserID: currentUser.ID,
+		Message:         input.Message,
+		Status:          "queued",
+		TotalRecipients: len(customers),
+	}
+	if err := config.DB.Create(&job).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to start sending")
+		return
+	}
+
+	// Respond with a copy taken before the sender starts writing to job
+	queued := job
+	go sc.Reminders.SendSegmentMessage(salon, customers, &job)
+
+	c.JSON(http.StatusAccepted, queued)
+}
+
+// GetSegmentMessages lists the messages sent to a segment, newest first, with
+// how far each has got
+func GetSegmentMessages(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	segment, ok := loadCustomerSegment(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var messages []models.SegmentMessage
+	if err := config.DB.Where("salon_id = ? AND segment_id = ?", salonUUID, segment.ID).
+		Order("created_at DESC").
+		Limit(50).
+		Find(&messages).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch segment messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+func loadCustomerSegment(c *gin.Context, salonID uuid.UUID) (models.CustomerSegment, bool) {
+	var segment models.CustomerSegment
+	segmentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid segment ID format")
+		return segment, false
+	}
+
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonID, segmentUUID).First(&segment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Segment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return segment, false
+	}
+	return segment, true
+}
+
+func countSegmentCustomers(salonID uuid.UUID, rules models.SegmentRules) (int64, error) {
+	var count int64
+	err := applySegmentRules(config.DB.Model(&models.Customer{}).Where("salon_id = ?", salonID), rules).
+		Count(&count).Error
+	return count, err
+}
+
+// applySegmentRules narrows a customers query to the customers matching the rules
+func applySegmentRules(query *gorm.DB, rules models.SegmentRules) *gorm.DB {
+	now := time.Now()
+	daysAgo := func(days int) time.Time {
+		return now.AddDate(0, 0, -days)
+	}
+
+	if len(rules.TagsAny) > 0 {
+		query = query.Where(customerHasAnyTag, lowerTags(rules.TagsAny))
+	}
+	if len(rules.TagsAll) > 0 {
+		tags := lowerTags(rules.TagsAll)
+		query = query.Where("(SELECT COUNT(DISTINCT lower(t)) FROM jsonb_array_elements_text(customers.tags) t WHERE lower(t) IN ?) = ?",
+			tags, len(tags))
+	}
+	if len(rules.ExcludeTags) > 0 {
+		query = query.Where("NOT "+customerHasAnyTag, lowerTags(rules.ExcludeTags))
+	}
+
+	if rules.MinTotalSpent != nil {
+		query = query.Where("total_spent >= ?", *rules.MinTotalSpent)
+	}
+	if rules.MaxTotalSpent != nil {
+		query = query.Where("total_spent <= ?", *rules.MaxTotalSpent)
+	}
+	if rules.MinVisits != nil {
+		query = query.Where("total_visits >= ?", *rules.MinVisits)
+	}
+	if rules.MaxVisits != nil {
+		query = query.Where("total_visits <= ?", *rules.MaxVisits)
+	}
+
+	if rules.LastVisitWithinDays != nil {
+		query = query.Where("last_visit >= ?", daysAgo(*rules.LastVisitWithinDays))
+	}
+	if rules.NoVisitForDays != nil {
+		query = query.Where("last_visit < ?", daysAgo(*rules.NoVisitForDays))
+	}
+
+	if len(rules.ServiceIDs) > 0 {
+		sub := config.DB.Table("invoice_items ii").
+			Select("1").
+			Joins("JOIN invoices i ON i.id = ii.invoice_id").
+			Where("i.customer_id = customers.id AND i.deleted_at IS NULL AND ii.service_id IN ?", rules.ServiceIDs)
+		if rules.ServiceWithinDays != nil {
+			sub = sub.Where("i.invoice_date >= ?", daysAgo(*rules.ServiceWithinDays))
+		}
+		query = query.Where("EXISTS (?)", sub)
+	}
+
+	if len(rules.BirthdayMonths) > 0 {
+		query = query.Where("EXTRACT(MONTH FROM birthday) IN ?", rules.BirthdayMonths)
+	}
+	if len(rules.Genders) > 0 {
+		query = query.Where("gender IN ?", rules.Genders)
+	}
+	return query
+}
+
+func validateSegmentRules(rules models.SegmentRules) error {
+	for _, month := range rules.BirthdayMonths {
+		if month < 1 || month > 12 {
+			return fmt.Errorf("birthday month %d must be between 1 and 12", month)
+		}
+	}
+	for _, gender := range rules.Genders {
+		if gender != "female" && gender != "male" && gender != "other" {
+			return fmt.Errorf("gender %q must be female, male or other", gender)
+		}
+	}
+	for _, days := range []*int{rules.LastVisitWithinDays, rules.NoVisitForDays, rules.ServiceWithinDays} {
+		if days != nil && *days < 1 {
+			return errors.New("day counts must be at least 1")
+		}
+	}
+	if rules.ServiceWithinDays != nil && len(rules.ServiceIDs) == 0 {
+		return errors.New("serviceWithinDays needs serviceIds")
+	}
+	if rules.MinTotalSpent != nil && rules.MaxTotalSpent != nil && *rules.MinTotalSpent > *rules.MaxTotalSpent {
+		return errors.New("minTotalSpent cannot be more than maxTotalSpent")
+	}
+	if rules.MinVisits != nil && rules.MaxVisits != nil && *rules.MinVisits > *rules.MaxVisits {
+		return errors.New("minVisits cannot be more than maxVisits")
+	}
+	return nil
+}
+
+// canonicalCustomerTags tidies tags for saving: trimmed, deduplicated ignoring
+// case, and spelt the way the salon already uses them so "vip" joins "VIP"
+func canonicalCustomerTags(db *gorm.DB, salonID uuid.UUID, tags []string) (models.StringList, error) {
+	var existing []string
+	if err := db.Raw(`
+		SELECT DISTINCT t
+		FROM customers, jsonb_array_elements_text(customers.tags) t
+		WHERE customers.salon_id = ?
+	`, salonID).Scan(&existing).Error; err != nil {
+		return nil, err
+	}
+	spelling := map[string]string{}
+	for _, tag := range existing {
+		if _, ok := spelling[strings.ToLower(tag)]; !ok {
+			spelling[strings.ToLower(tag)] = tag
+		}
+	}
+
+	result := models.StringList{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag == "" {
+			continue
+		}
+		if runes := []rune(tag); len(runes) > maxCustomerTagLength {
+			tag = string(runes[:maxCustomerTagLength])
+		}
+		key := strings.ToLower(tag)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if existing, ok := spelling[key]; ok {
+			tag = existing
+		}
+		result = append(result, tag)
+	}
+	return result, nil
+}
+
+func lowerTags(tags []string) []string {
+	lowered := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		lowered = append(lowered, strings.ToLower(strings.Join(strings.Fields(tag), " ")))
+	}
+	return lowered
+}
@@ -0,0 +1,66 @@
+// controllers/customer_segment_test.go
+package controllers
+
+import (
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateSegmentRules(t *testing.T) {
+	intPtr := func(n int) *int { return &n }
+	floatPtr := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name    string
+		rules   models.SegmentRules
+		wantErr bool
+	}{
+		{"empty", models.SegmentRules{}, false},
+		{"typical", models.SegmentRules{
+			TagsAny:        []string{"VIP"},
+			MinTotalSpent:  floatPtr(5000),
+			NoVisitForDays: intPtr(60),
+			BirthdayMonths: []int{1, 12},
+			Genders:        []string{"female", "other"},
+		}, false},
+		{"service in the last 30 days", models.SegmentRules{ServiceIDs: []uuid.UUID{uuid.New()}, ServiceWithinDays: intPtr(30)}, false},
+		{"equal bounds", models.SegmentRules{MinVisits: intPtr(3), MaxVisits: intPtr(3)}, false},
+		{"month 0", models.SegmentRules{BirthdayMonths: []int{0}}, true},
+		{"month 13", models.SegmentRules{BirthdayMonths: []int{6, 13}}, true},
+		{"capitalised gender", models.SegmentRules{Genders: []string{"Female"}}, true},
+		{"zero days", models.SegmentRules{LastVisitWithinDays: intPtr(0)}, true},
+		{"negative days", models.SegmentRules{NoVisitForDays: intPtr(-7)}, true},
+		{"service days without services", models.SegmentRules{ServiceWithinDays: intPtr(30)}, true},
+		{"spend bounds reversed", models.SegmentRules{MinTotalSpent: floatPtr(5000), MaxTotalSpent: floatPtr(1000)}, true},
+		{"visit bounds reversed", models.SegmentRules{MinVisits: intPtr(10), MaxVisits: intPtr(2)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSegmentRules(tt.rules); (err != nil) != tt.wantErr {
+				t.Errorf("validateSegmentRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLowerTags(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{"none", nil, []string{}},
+		{"case", []string{"VIP", "Bridal"}, []string{"vip", "bridal"}},
+		{"spacing", []string{"  Regular   Client "}, []string{"regular client"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lowerTags(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lowerTags(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+// controllers/customer_test.go
+package controllers
+
+import "testing"
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"asha", "asha"},
+		{"50%", `50\%`},
+		{"a_b", `a\_b`},
+		{`c:\temp`, `c:\\temp`},
+		{`100%_\`, `100\%\_\\`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := escapeLike(tt.in); got != tt.want {
+				t.Errorf("escapeLike(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPhoneDigits(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"9876543210", "9876543210"},
+		{"+91 98765 43210", "919876543210"},
+		{"(080) 2345-6789", "08023456789"},
+		{"43210", "43210"},
+		{"Asha", ""},
+		{"98765x", ""},
+		{"asha@example.com", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := phoneDigits(tt.in); got != tt.want {
+				t.Errorf("phoneDigits(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
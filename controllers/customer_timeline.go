@@ -0,0 +1,429 @@
+// controllers/customer_timeline.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTimelinePageSize = 30
+	maxTimelinePageSize     = 100
+)
+
+// timelineSources selects the id and time of each kind of timeline event for
+// one customer. Every query takes the salon ID then the customer ID.
+var timelineSources = map[string]string{
+	"invoice": `SELECT 'invoice' AS type, id, invoice_date AS occurred_at FROM invoices
+		WHERE salon_id = ? AND customer_id = ? AND deleted_at IS NULL`,
+	"payment": `SELECT 'payment' AS type, ip.id, ip.paid_at AS occurred_at FROM invoice_payments ip
+		JOIN invoices i ON i.id = ip.invoice_id
+		WHERE i.salon_id = ? AND i.customer_id = ? AND i.deleted_at IS NULL`,
+	"invoice_void": `SELECT 'invoice_void' AS type, id, voided_at AS occurred_at FROM invoice_voids
+		WHERE salon_id = ? AND customer_id = ?`,
+	"message": `SELECT 'message' AS type, id, sent_at AS occurred_at FROM reminder_logs
+		WHERE salon_id = ? AND customer_id = ?`,
+	"profile_change": `SELECT 'profile_change' AS type, id, created_at AS occurred_at FROM customer_changes
+		WHERE salon_id = ? AND customer_id = ?`,
+	"consent": `SELECT 'consent' AS type, id, created_at AS occurred_at FROM customer_consents
+		WHERE salon_id = ? AND customer_id = ?`,
+	"service_record": `SELECT 'service_record' AS type, id, performed_at AS occurred_at FROM service_records
+		WHERE salon_id = ? AND customer_id = ?`,
+	"patch_test": `SELECT 'patch_test' AS type, id, tested_at AS occurred_at FROM patch_tests
+		WHERE salon_id = ? AND customer_id = ?`,
+	"attachment": `SELECT 'attachment' AS type, id, created_at AS occurred_at FROM attachments
+		WHERE salon_id = ? AND customer_id = ?`,
+	"merge": `SELECT 'merge' AS type, id, created_at AS occurred_at FROM customer_merges
+		WHERE salon_id = ? AND survivor_id = ?`,
+}
+
+// timelineTypes fixes the order the sources are combined in
+var timelineTypes = []string{"invoice", "payment", "invoice_void", "message", "profile_change", "consent", "service_record", "patch_test", "attachment", "merge"}
+
+// TimelineEvent is one entry in a customer's activity feed. Summary is a line
+// of text for the feed; Details holds the underlying record.
+type TimelineEvent struct {
+	Type       string      `json:"type"`
+	ID         uuid.UUID   `json:"id"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Summary    string      `json:"summary"`
+	Details    interface{} `json:"details"`
+}
+
+// GetCustomerTimeline returns the customer's invoices (with their items),
+// payments, voids, messages sent, profile and notes changes, consent changes,
+// service records, patch tests, uploaded photos and documents and merges as one
+// feed, newest first. ?types= takes a comma-separated subset of those event
+// types; ?page= and ?pageSize= page through it. The salon has no appointment
+// book, so bookings are not part of the feed.
+func GetCustomerTimeline(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.RespondWithError(c, http.StatusBadRequest, "page must be a positive number")
+		return
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultTimelinePageSize)))
+	if err != nil || pageSize < 1 || pageSize > maxTimelinePageSize {
+		utils.RespondWithError(c, http.StatusBadRequest, "pageSize must be between 1 and 100")
+		return
+	}
+
+	types := timelineTypes
+	if raw := c.Query("types"); raw != "" {
+		types = nil
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			if _, ok := timelineSources[t]; !ok {
+				utils.RespondWithError(c, http.StatusBadRequest, "Unknown timeline type: "+t)
+				return
+			}
+			types = append(types, t)
+		}
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	parts := make([]string, 0, len(types))
+	var args []interface{}
+	for _, t := range types {
+		parts = append(parts, timelineSources[t])
+		args = append(args, salonUUID, customerUUID)
+	}
+	union := strings.Join(parts, "\nUNION ALL\n")
+
+	var total int64
+	if err := config.DB.Raw("SELECT COUNT(*) FROM ("+union+") events", args...).Scan(&total).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch timeline")
+		return
+	}
+
+	type eventRef struct {
+		Type       string
+		ID         uuid.UUID
+		OccurredAt time.Time
+	}
+	var refs []eventRef
+	if err := config.DB.Raw("SELECT type, id, occurred_at FROM ("+union+") events ORDER BY occurred_at DESC, id DESC LIMIT ? OFFSET ?",
+		append(args, pageSize, (page-1)*pageSize)...).Scan(&refs).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch timeline")
+		return
+	}
+
+	idsByType := map[string][]uuid.UUID{}
+	for _, ref := range refs {
+		idsByType[ref.Type] = append(idsByType[ref.Type], ref.ID)
+	}
+	details, err := loadTimelineDetails(salon, idsByType)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch timeline")
+		return
+	}
+
+	events := make([]TimelineEvent, 0, len(refs))
+	for _, ref := range refs {
+		event := details[ref.ID]
+		event.Type = ref.Type
+		event.ID = ref.ID
+		event.OccurredAt = ref.OccurredAt
+		events = append(events, event)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events":     events,
+		"page":       page,
+		"pageSize":   pageSize,
+		"total":      total,
+		"totalPages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// loadTimelineDetails loads the records behind a page of events, one query per
+// type, and writes each event's summary line
+func loadTimelineDetails(salon models.Salon, idsByType map[string][]uuid.UUID) (map[uuid.UUID]TimelineEvent, error) {
+	format := salon.MoneyFormat()
+	details := map[uuid.UUID]TimelineEvent{}
+
+	if ids := idsByType["invoice"]; len(ids) > 0 {
+		var invoices []models.Invoice
+		if err := config.DB.Preload("Items").Where("id IN ?", ids).Find(&invoices).Error; err != nil {
+			return nil, err
+		}
+		for _, invoice := range invoices {
+			var names []string
+			for _, item := range invoice.Items {
+				name := item.ServiceName
+				if item.Quantity > 1 {
+					name += " x" + strconv.Itoa(item.Quantity)
+				}
+				names = append(names, name)
+			}
+			summary := "Invoice " + invoice.InvoiceNumber + ": " + strings.Join(names, ", ") + " (" + format.Format(invoice.Total) + ")"
+			if invoice.Notes != "" {
+				summary += " - " + invoice.Notes
+			}
+			details[invoice.ID] = TimelineEvent{Summary: summary, Details: invoice}
+		}
+	}
+
+	if ids := idsByType["payment"]; len(ids) > 0 {
+		type paymentRow struct {
+			models.InvoicePayment
+			InvoiceNumber string `json:"invoiceNumber"`
+		}
+		var payments []paymentRow
+		if err := config.DB.Table("invoice_payments ip").
+			Select("ip.*, i.invoice_number").
+			Joins("JOIN invoices i ON i.id = ip.invoice_id").
+			Where("ip.id IN ?", ids).
+			Scan(&payments).Error; err != nil {
+			return nil, err
+		}
+		for _, payment := range payments {
+			verb := "Paid "
+			if payment.Amount < 0 {
+				verb = "Refunded "
+			}
+			amount := payment.Amount
+			if amount < 0 {
+				amount = -amount
+			}
+			details[payment.ID] = TimelineEvent{
+				Summary: verb + format.Format(amount) + " by " + payment.Method + " on invoice " + payment.InvoiceNumber,
+				Details: payment,
+			}
+		}
+	}
+
+	if ids := idsByType["invoice_void"]; len(ids) > 0 {
+		var voids []models.InvoiceVoid
+		if err := config.DB.Where("id IN ?", ids).Find(&voids).Error; err != nil {
+			return nil, err
+		}
+		for _, void := range voids {
+			summary := "Invoice " + void.InvoiceNumber + " voided (" + format.Format(void.Total) + ")"
+			if void.Reason != "" {
+				summary += ": " + void.Reason
+			}
+			details[void.ID] = TimelineEvent{Summary: summary, Details: void}
+		}
+	}
+
+	if ids := idsByType["message"]; len(ids) > 0 {
+		var messages []models.ReminderLog
+		if err := config.DB.Where("id IN ?", ids).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		for _, message := range messages {
+			summary := strings.ReplaceAll(message.Type, "_", " ") + " message by " + message.Channel + ": " + message.Message
+			if message.Status != "sent" {
+				summary = "Failed " + summary
+			}
+			details[message.ID] = TimelineEvent{Summary: summary, Details: message}
+		}
+	}
+
+	if ids := idsByType["profile_change"]; len(ids) > 0 {
+		var changes []models.CustomerChange
+		if err := config.DB.Where("id IN ?", ids).Find(&changes).Error; err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			var fields []string
+			for _, field := range change.Changes {
+				fields = append(fields, field.Field)
+			}
+			details[change.ID] = TimelineEvent{Summary: "Profile updated: " + strings.Join(fields, ", "), Details: change}
+		}
+	}
+
+	if ids := idsByType["consent"]; len(ids) > 0 {
+		var consents []models.CustomerConsent
+		if err := config.DB.Where("id IN ?", ids).Find(&consents).Error; err != nil {
+			return nil, err
+		}
+		for _, consent := range consents {
+			verb := "withdrew"
+			if consent.Granted {
+				verb = "gave"
+			}
+			details[consent.ID] = TimelineEvent{
+				Summary: "Customer " + verb + " " + consent.Purpose + " consent for " + consent.Channel,
+				Details: consent,
+			}
+		}
+	}
+
+	if ids := idsByType["service_record"]; len(ids) > 0 {
+		var records []models.ServiceRecord
+		if err := config.DB.Where("id IN ?", ids).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			summary := "Service notes"
+			if record.Formula != "" {
+				summary += ": " + record.Formula
+			}
+			if record.Result != "" {
+				summary += " - " + record.Result
+			}
+			details[record.ID] = TimelineEvent{Summary: summary, Details: record}
+		}
+	}
+
+	if ids := idsByType["patch_test"]; len(ids) > 0 {
+		var tests []models.PatchTest
+		if err := config.DB.Where("id IN ?", ids).Find(&tests).Error; err != nil {
+			return nil, err
+		}
+		for _, test := range tests {
+			summary := "Patch test"
+			if test.Product != "" {
+				summary += " with " + test.Product
+			}
+			summary += ": " + test.Result + ", valid until " + test.ExpiresAt.Format("2006-01-02")
+			details[test.ID] = TimelineEvent{Summary: summary, Details: test}
+		}
+	}
+
+	if ids := idsByType["attachment"]; len(ids) > 0 {
+		var attachments []models.Attachment
+		if err := config.DB.Where("id IN ?", ids).Find(&attachments).Error; err != nil {
+			return nil, err
+		}
+		for _, attachment := range attachments {
+			summary := strings.ReplaceAll(attachment.Kind, "_", " ") + " uploaded: " + attachment.Filename
+			if attachment.Caption != "" {
+				summary += " - " + attachment.Caption
+			}
+			details[attachment.ID] = TimelineEvent{Summary: strings.ToUpper(summary[:1]) + summary[1:], Details: attachment}
+		}
+	}
+
+	if ids := idsByType["merge"]; len(ids) > 0 {
+		var merges []models.CustomerMerge
+		if err := config.DB.Where("id IN ?", ids).Find(&merges).Error; err != nil {
+			return nil, err
+		}
+		for _, merge := range merges {
+			details[merge.ID] = TimelineEvent{
+				Summary: "Merged duplicate " + merge.MergedCustomer.Name + " (" + merge.MergedCustomer.Phone + ")",
+				Details: merge,
+			}
+		}
+	}
+
+	return details, nil
+}
+
+// recordCustomerChange stores which profile fields an update changed. Updates
+// that change nothing are not recorded.
+func recordCustomerChange(tx *gorm.DB, before, after models.Customer, userID uuid.UUID) error {
+	changes := customerFieldChanges(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&models.CustomerChange{
+		ID:              uuid.New(),
+		SalonID:         after.SalonID,
+		CustomerID:      after.ID,
+		ChangedByUserID: userID,
+		Changes:         changes,
+	}).Error
+}
+
+// customerFieldChanges lists the profile fields that differ between two versions
+func customerFieldChanges(before, after models.Customer) models.FieldChangeList {
+	changes := models.FieldChangeList{}
+	add := func(field string, old, new interface{}) {
+		changes = append(changes, models.FieldChange{Field: field, Old: old, New: new})
+	}
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	}
+
+	if before.Name != after.Name {
+		add("name", before.Name, after.Name)
+	}
+	if before.Phone != after.Phone {
+		add("phone", before.Phone, after.Phone)
+	}
+	if before.Email != after.Email {
+		add("email", before.Email, after.Email)
+	}
+	if date(before.Birthday) != date(after.Birthday) {
+		add("birthday", date(before.Birthday), date(after.Birthday))
+	}
+	if date(before.Anniversary) != date(after.Anniversary) {
+		add("anniversary", date(before.Anniversary), date(after.Anniversary))
+	}
+	if before.Notes != after.Notes {
+		add("notes", before.Notes, after.Notes)
+	}
+	if before.Gender != after.Gender {
+		add("gender", before.Gender, after.Gender)
+	}
+	if strings.Join(before.Tags, "\x00") != strings.Join(after.Tags, "\x00") {
+		add("tags", before.Tags, after.Tags)
+	}
+	if before.IsActive != after.IsActive {
+		add("isActive", before.IsActive, after.IsActive)
+	}
+	household := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+	if household(before.HouseholdPrimaryID) != household(after.HouseholdPrimaryID) {
+		add("householdPrimaryId", household(before.HouseholdPrimaryID), household(after.HouseholdPrimaryID))
+	}
+	if before.Relationship != after.Relationship {
+		add("relationship", before.Relationship, after.Relationship)
+	}
+	return changes
+}
@@ -0,0 +1,61 @@
+// controllers/customer_timeline_test.go
+package controllers
+
+import (
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+)
+
+func TestCustomerFieldChanges(t *testing.T) {
+	birthday := time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)
+	base := models.Customer{
+		Name:     "Asha Rao",
+		Phone:    "9876543210",
+		Birthday: &birthday,
+		Tags:     models.StringList{"VIP"},
+		IsActive: true,
+	}
+
+	tests := []struct {
+		name    string
+		edit    func(*models.Customer)
+		changes models.FieldChangeList
+	}{
+		{"no change", func(c *models.Customer) {}, models.FieldChangeList{}},
+		{"same birthday at another time of day", func(c *models.Customer) {
+			later := birthday.Add(5 * time.Hour)
+			c.Birthday = &later
+		}, models.FieldChangeList{}},
+		{"phone", func(c *models.Customer) { c.Phone = "9123456780" },
+			models.FieldChangeList{{Field: "phone", Old: "9876543210", New: "9123456780"}}},
+		{"birthday cleared", func(c *models.Customer) { c.Birthday = nil },
+			models.FieldChangeList{{Field: "birthday", Old: "1990-04-03", New: ""}}},
+		{"anniversary set", func(c *models.Customer) { c.Anniversary = &birthday },
+			models.FieldChangeList{{Field: "anniversary", Old: "", New: "1990-04-03"}}},
+		{"tags reordered", func(c *models.Customer) { c.Tags = models.StringList{"Bridal", "VIP"} },
+			models.FieldChangeList{{Field: "tags", Old: models.StringList{"VIP"}, New: models.StringList{"Bridal", "VIP"}}}},
+		{"deactivated with a note", func(c *models.Customer) { c.IsActive, c.Notes = false, "Moved away" },
+			models.FieldChangeList{
+				{Field: "notes", Old: "", New: "Moved away"},
+				{Field: "isActive", Old: true, New: false},
+			}},
+		{"name, email and gender", func(c *models.Customer) {
+			c.Name, c.Email, c.Gender = "Asha R", "asha@example.com", "female"
+		}, models.FieldChangeList{
+			{Field: "name", Old: "Asha Rao", New: "Asha R"},
+			{Field: "email", Old: "", New: "asha@example.com"},
+			{Field: "gender", Old: "", New: "female"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := base
+			tt.edit(&after)
+			if got := customerFieldChanges(base, after); !reflect.DeepEqual(got, tt.changes) {
+				t.Errorf("customerFieldChanges() = %+v, want %+v", got, tt.changes)
+			}
+		})
+	}
+}
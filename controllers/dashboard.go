@@ -61,7 +61,7 @@ func GetDashboardOverview(c *gin.Context) {
 	var monthlyRevenue float64
 	config.DB.Model(&models.Invoice{}).
 		Where("salon_id = ? AND invoice_date >= ? AND deleted_at IS NULL", salonUUID, firstOfMonth).
-		Select("COALESCE(SUM(total), 0)").Scan(&monthlyRevenue)
+		Select("COALESCE(SUM(" + invoiceRevenueSQL("invoices") + "), 0)").Scan(&monthlyRevenue)
 
 	// Total Invoices
 	var totalInvoices int64
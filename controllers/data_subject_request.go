@@ -0,0 +1,761 @@
+// controllers/data_subject_request.go
+package controllers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErasedCustomerName replaces the name of a customer whose data was erased
+const ErasedCustomerName = "Erased customer"
+
+var (
+	errErasureWalletBalance = errors.New("refund the customer's wallet balance before erasing their data")
+	errErasureUnpaid        = errors.New("the customer has unpaid invoices; settle or write them off before erasing their data")
+)
+
+// CreateDataSubjectRequestInput logs a customer's privacy request
+type CreateDataSubjectRequestInput struct {
+	Type         string `json:"type" binding:"required,oneof=export erasure"`
+	RequestedVia string `json:"requestedVia" binding:"required,oneof=in_store email phone written"`
+	Note         string `json:"note"`
+}
+
+// RejectDataSubjectRequestInput records why a request was refused
+type RejectDataSubjectRequestInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CreateDataSubjectRequest logs a customer's request for a copy of their data
+// or for its erasure. Owners only.
+func CreateDataSubjectRequest(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	var input CreateDataSubjectRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	request := models.DataSubjectRequest{
+		ID:                uuid.New(),
+		SalonID:           salonUUID,
+		CustomerID:        customer.ID,
+		Type:              input.Type,
+		Status:            "pending",
+		RequestedVia:      input.RequestedVia,
+		SubjectReference:  maskPhone(customer.Phone),
+		Note:              input.Note,
+		Summary:           models.IntMap{},
+		RequestedByUserID: currentUser.ID,
+		DueAt:             time.Now().AddDate(0, 0, models.DataSubjectResponseDays),
+	}
+	if err := config.DB.Create(&request).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to log data request")
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// GetDataSubjectRequests lists the salon's data requests, optionally by
+// ?status=, oldest due first. Owners only.
+func GetDataSubjectRequests(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	query := config.DB.Where("salon_id = ?", salonUUID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var requests []models.DataSubjectRequest
+	if err := query.Order("due_at").Find(&requests).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch data requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// GetDataSubjectRequest returns one data request. Owners only.
+func GetDataSubjectRequest(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	request, ok := loadDataSubjectRequest(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// DataSubjectRequestController fulfils data requests, which reach the
+// customer's uploaded files as well as their database records
+type DataSubjectRequestController struct {
+	Storage services.Storage
+}
+
+// DownloadCustomerDataArchive fulfils an export request with a ZIP of JSON
+// files holding everything stored about the customer, plus their uploaded
+// photos and documents. The archive is streamed, and can be downloaded again
+// after the request is completed. Owners only.
+func (dc *DataSubjectRequestController) DownloadCustomerDataArchive(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	request, ok := loadDataSubjectRequest(c, salonUUID)
+	if !ok {
+		return
+	}
+	if request.Type != "export" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Only export requests have an archive")
+		return
+	}
+	if request.Status == "rejected" {
+		utils.RespondWithError(c, http.StatusConflict, "Request was rejected")
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, request.CustomerID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"customer-data-%s.zip\"", customer.ID))
+	c.Status(http.StatusOK)
+
+	summary, err := writeCustomerDataArchive(c.Writer, config.DB, dc.Storage, salon, customer)
+	if err != nil {
+		// Headers are already sent, so the client sees a truncated archive
+		log.Printf("Data export %s failed: %v", request.ID, err)
+		return
+	}
+
+	if request.Status == "pending" {
+		now := time.Now()
+		if err := config.DB.Model(&request).Updates(map[string]interface{}{
+			"status":               "completed",
+			"summary":              summary,
+			"fulfilled_by_user_id": currentUser.ID,
+			"completed_at":         now,
+		}).Error; err != nil {
+			log.Printf("Failed to complete data request %s: %v", request.ID, err)
+		}
+	}
+}
+
+// EraseCustomerData fulfils an erasure request. Personal details on the
+// customer and in their message history are anonymised; invoices, payments and
+// ledgers are kept unchanged for tax retention, still linked to the anonymised
+// customer. Uploaded photos and documents are deleted. The phone number and
+// email stay on the marketing suppression list so the person is not messaged
+// again. Owners only.
+func (dc *DataSubjectRequestController) EraseCustomerData(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	request, ok := loadDataSubjectRequest(c, salonUUID)
+	if !ok {
+		return
+	}
+	if request.Type != "erasure" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Only erasure requests can erase data")
+		return
+	}
+	if request.Status != "pending" {
+		utils.RespondWithError(c, http.StatusConflict, "Request is already "+request.Status)
+		return
+	}
+
+	var storedFiles []string
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var customer models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("salon_id = ? AND id = ?", salonUUID, request.CustomerID).
+			First(&customer).Error; err != nil {
+			return err
+		}
+
+		if customer.WalletBalance > 0.005 {
+			return errErasureWalletBalance
+		}
+		var unpaid int64
+		if err := tx.Model(&models.Invoice{}).
+			Where("customer_id = ? AND payment_status IN ('unpaid', 'partial') AND total - paid_amount > 0.005", customer.ID).
+			Count(&unpaid).Error; err != nil {
+			return err
+		}
+		if unpaid > 0 {
+			return errErasureUnpaid
+		}
+
+		var attachments []models.Attachment
+		if err := tx.Where("customer_id = ?", customer.ID).Find(&attachments).Error; err != nil {
+			return err
+		}
+		for _, attachment := range attachments {
+			storedFiles = append(storedFiles, attachment.StorageKey, attachment.ThumbnailKey)
+		}
+
+		summary, err := eraseCustomer(tx, customer)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		return tx.Model(&request).Updates(map[string]interface{}{
+			"status":               "completed",
+			"summary":              summary,
+			"fulfilled_by_user_id": currentUser.ID,
+			"completed_at":         now,
+		}).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errErasureWalletBalance), errors.Is(err, errErasureUnpaid):
+			utils.RespondWithError(c, http.StatusConflict, err.Error())
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		default:
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to erase customer data")
+		}
+		return
+	}
+
+	// Files go once their rows are gone, so a failed erasure leaves them intact
+	removeStoredFiles(dc.Storage, storedFiles...)
+
+	config.DB.First(&request, "id = ?", request.ID)
+	c.JSON(http.StatusOK, request)
+}
+
+// RejectDataSubjectRequest closes a request without acting on it, recording
+// why. Owners only.
+func RejectDataSubjectRequest(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners can handle data requests")
+		return
+	}
+
+	var input RejectDataSubjectRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	request, ok := loadDataSubjectRequest(c, salonUUID)
+	if !ok {
+		return
+	}
+	if request.Status != "pending" {
+		utils.RespondWithError(c, http.StatusConflict, "Request is already "+request.Status)
+		return
+	}
+
+	now := time.Now()
+	request.Status = "rejected"
+	request.RejectionReason = input.Reason
+	request.FulfilledByUserID = &currentUser.ID
+	request.CompletedAt = &now
+	if err := config.DB.Save(&request).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to reject data request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func loadDataSubjectRequest(c *gin.Context, salonID uuid.UUID) (models.DataSubjectRequest, bool) {
+	var request models.DataSubjectRequest
+	requestUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid request ID format")
+		return request, false
+	}
+
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonID, requestUUID).First(&request).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Data request not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return request, false
+	}
+	return request, true
+}
+
+// writeCustomerDataArchive writes one JSON file per kind of record held about
+// the customer, their uploaded files under attachments/, and a manifest, and
+// returns how many records each file holds
+func writeCustomerDataArchive(out io.Writer, db *gorm.DB, storage services.Storage, salon models.Salon, customer models.Customer) (models.IntMap, error) {
+	var invoices []models.Invoice
+	var voids []models.InvoiceVoid
+	var messages []models.ReminderLog
+	var consents []models.CustomerConsent
+	var loyalty []models.LoyaltyTransaction
+	var wallet []models.WalletTransaction
+	var memberships []models.CustomerMembership
+	var packages []models.CustomerPackage
+	var giftCards []models.GiftCard
+	var coupons []models.CouponRedemption
+	var merges []models.CustomerMerge
+	var changes []models.CustomerChange
+	var serviceRecords []models.ServiceRecord
+	var allergies []models.CustomerAllergy
+	var patchTests []models.PatchTest
+	var attachments []models.Attachment
+
+	sections := []struct {
+		file  string
+		dest  interface{}
+		query *gorm.DB
+		count func() int
+	}{
+		{"invoices.json", &invoices, db.Preload("Items").Preload("Payments").Where("customer_id = ?", customer.ID).Order("invoice_date"), func() int { return len(invoices) }},
+		{"voided_invoices.json", &voids, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(voids) }},
+		{"messages.json", &messages, db.Where("customer_id = ?", customer.ID).Order("sent_at"), func() int { return len(messages) }},
+		{"consents.json", &consents, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(consents) }},
+		{"loyalty.json", &loyalty, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(loyalty) }},
+		{"wallet.json", &wallet, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(wallet) }},
+		{"memberships.json", &memberships, db.Where("customer_id = ?", customer.ID).Order("start_date"), func() int { return len(memberships) }},
+		{"packages.json", &packages, db.Preload("Sessions").Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(packages) }},
+		{"gift_cards.json", &giftCards, db.Where("purchaser_customer_id = ? OR recipient_customer_id = ?", customer.ID, customer.ID).Order("created_at"), func() int { return len(giftCards) }},
+		{"coupon_redemptions.json", &coupons, db.Where("customer_id = ?", customer.ID).Order("redeemed_at"), func() int { return len(coupons) }},
+		{"service_records.json", &serviceRecords, db.Where("customer_id = ?", customer.ID).Order("performed_at"), func() int { return len(serviceRecords) }},
+		{"allergies.json", &allergies, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(allergies) }},
+		{"patch_tests.json", &patchTests, db.Where("customer_id = ?", customer.ID).Order("tested_at"), func() int { return len(patchTests) }},
+		{"attachments.json", &attachments, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(attachments) }},
+		{"profile_changes.json", &changes, db.Where("customer_id = ?", customer.ID).Order("created_at"), func() int { return len(changes) }},
+		{"merged_records.json", &merges, db.Where("survivor_id = ? OR merged_customer_id = ?", customer.ID, customer.ID).Order("created_at"), func() int { return len(merges) }},
+	}
+
+	archive := zip.NewWriter(out)
+	writeJSON := func(name string, v interface{}) error {
+		f, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	summary := models.IntMap{"customer.json": 1}
+	if err := writeJSON("customer.json", customer); err != nil {
+		return nil, err
+	}
+	for _, section := range sections {
+		if err := section.query.Where("salon_id = ?", salon.ID).Find(section.dest).Error; err != nil {
+			return nil, fmt.Errorf("%s: %w", section.file, err)
+		}
+		if err := writeJSON(section.file, section.dest); err != nil {
+			return nil, err
+		}
+		summary[section.file] = section.count()
+	}
+
+	for _, attachment := range attachments {
+		body, err := storage.Get(attachment.StorageKey)
+		if errors.Is(err, services.ErrStoredFileNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("attachment %s: %w", attachment.ID, err)
+		}
+		f, err := archive.Create("attachments/" + attachment.ID.String() + "-" + attachment.Filename)
+		if err == nil {
+			_, err = io.Copy(f, body)
+		}
+		body.Close()
+		if err != nil {
+			return nil, err
+		}
+		summary["attachments/"]++
+	}
+
+	if err := writeJSON("manifest.json", gin.H{
+		"salon":       salon.Name,
+		"customerId":  customer.ID,
+		"generatedAt": time.Now(),
+		"files":       summary,
+	}); err != nil {
+		return nil, err
+	}
+	return summary, archive.Close()
+}
+
+// eraseCustomer anonymises the customer's personal details wherever they are
+// stored outside the financial records, and returns how many rows changed
+func eraseCustomer(tx *gorm.DB, customer models.Customer) (models.IntMap, error) {
+	summary := models.IntMap{}
+
+	// Other members of a household still use the number the customer shared
+	var sharingPhone int64
+	if err := tx.Model(&models.Customer{}).
+		Where("salon_id = ? AND phone = ? AND id <> ?", customer.SalonID, customer.Phone, customer.ID).
+		Count(&sharingPhone).Error; err != nil {
+		return nil, err
+	}
+
+	// Keep the contact points suppressed for marketing after they are erased
+	for _, channel := range services.ConsentChannels {
+		address := services.CustomerAddress(customer, channel)
+		if address == "" || (channel != "email" && sharingPhone > 0) {
+			continue
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.MessageSuppression{
+			SalonID: customer.SalonID,
+			Channel: channel,
+			Purpose: services.ConsentMarketing,
+			Address: address,
+			Reason:  "erasure",
+			Source:  "data_request",
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// The phone column is unique per salon, so each erased customer gets its own placeholder
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).Updates(map[string]interface{}{
+		"name":                 ErasedCustomerName,
+		"phone":                "erased-" + customer.ID.String(),
+		"email":                "",
+		"birthday":             nil,
+		"anniversary":          nil,
+		"notes":                "",
+		"gender":               "",
+		"tags":                 models.StringList{},
+		"is_active":            false,
+		"household_primary_id": nil,
+		"relationship":         "",
+	}).Error; err != nil {
+		return nil, err
+	}
+	summary["customers"] = 1
+
+	// An erased primary contact hands the household to its first member
+	var members []models.Customer
+	if err := tx.Where("household_primary_id = ?", customer.ID).Order("name").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) > 0 {
+		if err := tx.Model(&models.Customer{}).Where("id = ?", members[0].ID).Updates(map[string]interface{}{
+			"household_primary_id": nil,
+			"relationship":         "",
+		}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.Customer{}).Where("household_primary_id = ?", customer.ID).
+			Update("household_primary_id", members[0].ID).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	updates := []struct {
+		name   string
+		query  *gorm.DB
+		values map[string]interface{}
+	}{
+		{"reminder_logs", tx.Model(&models.ReminderLog{}).Where("customer_id = ?", customer.ID),
+			map[string]interface{}{"message": "[erased]"}},
+		{"customer_consents", tx.Model(&models.CustomerConsent{}).Where("customer_id = ? AND note <> ''", customer.ID),
+			map[string]interface{}{"note": ""}},
+		{"gift_cards", tx.Model(&models.GiftCard{}).Where("recipient_customer_id = ?", customer.ID),
+			map[string]interface{}{"recipient_name": "", "recipient_phone": "", "recipient_email": "", "message": ""}},
+	}
+	for _, update := range updates {
+		result := update.query.Updates(update.values)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		summary[update.name] = int(result.RowsAffected)
+	}
+
+	// Merge records keep the figures but not the personal details
+	var merges []models.CustomerMerge
+	if err := tx.Where("survivor_id = ? OR merged_customer_id = ?", customer.ID, customer.ID).Find(&merges).Error; err != nil {
+		return nil, err
+	}
+	for _, merge := range merges {
+		if merge.MergedCustomerID == customer.ID {
+			merge.MergedCustomer = anonymisedSnapshot(merge.MergedCustomer)
+		}
+		if merge.SurvivorID == customer.ID {
+			merge.SurvivorBefore = anonymisedSnapshot(merge.SurvivorBefore)
+		}
+		if err := tx.Model(&merge).Updates(map[string]interface{}{
+			"merged_customer": merge.MergedCustomer,
+			"survivor_before": merge.SurvivorBefore,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	summary["customer_merges"] = len(merges)
+
+	// Profile changes keep which fields changed and when, not the values
+	var changes []models.CustomerChange
+	if err := tx.Where("customer_id = ?", customer.ID).Find(&changes).Error; err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		for i := range change.Changes {
+			change.Changes[i].Old = "[erased]"
+			change.Changes[i].New = "[erased]"
+		}
+		if err := tx.Model(&change).Update("changes", change.Changes).Error; err != nil {
+			return nil, err
+		}
+	}
+	summary["customer_changes"] = len(changes)
+
+	// Health and technical notes and photos serve no purpose once the customer
+	// is gone. The caller deletes the attachments' stored files after commit.
+	deletes := []struct {
+		name  string
+		model interface{}
+	}{
+		{"service_records", &models.ServiceRecord{}},
+		{"customer_allergies", &models.CustomerAllergy{}},
+		{"patch_tests", &models.PatchTest{}},
+		{"attachments", &models.Attachment{}},
+	}
+	for _, del := range deletes {
+		result := tx.Where("customer_id = ?", customer.ID).Delete(del.model)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		summary[del.name] = int(result.RowsAffected)
+	}
+
+	return summary, nil
+}
+
+func anonymisedSnapshot(snapshot models.CustomerSnapshot) models.CustomerSnapshot {
+	snapshot.Name = ErasedCustomerName
+	snapshot.Phone = ""
+	snapshot.Email = ""
+	snapshot.Birthday = nil
+	snapshot.Anniversary = nil
+	snapshot.Notes = ""
+	return snapshot
+}
+
+// maskPhone keeps the last four digits of a phone number
+func maskPhone(phone string) string {
+	digits := phoneSuffix(phone)
+	if len(digits) <= 4 {
+		return digits
+	}
+	masked := make([]byte, len(digits))
+	for i := range masked {
+		masked[i] = '*'
+	}
+	copy(masked[len(digits)-4:], digits[len(digits)-4:])
+	return string(masked)
+}
@@ -0,0 +1,63 @@
+// controllers/data_subject_request_test.go
+package controllers
+
+import (
+	"salonpro-backend/models"
+	"testing"
+	"time"
+)
+
+func TestMaskPhone(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"9876543210", "******3210"},
+		{"+91 98765 43210", "******3210"},
+		{"080-2345", "***2345"},
+		{"3210", "3210"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := maskPhone(tt.in); got != tt.want {
+				t.Errorf("maskPhone(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnonymisedSnapshot(t *testing.T) {
+	birthday := time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)
+	lastVisit := time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)
+	snapshot := models.CustomerSnapshot{
+		Name:          "Asha Rao",
+		Phone:         "9876543210",
+		Email:         "asha@example.com",
+		Birthday:      &birthday,
+		Anniversary:   &birthday,
+		Notes:         "Allergic to PPD",
+		IsActive:      true,
+		TotalVisits:   12,
+		TotalSpent:    18500,
+		LastVisit:     &lastVisit,
+		LoyaltyPoints: 340,
+		WalletBalance: 250,
+	}
+
+	got := anonymisedSnapshot(snapshot)
+	want := models.CustomerSnapshot{
+		Name:          ErasedCustomerName,
+		IsActive:      true,
+		TotalVisits:   12,
+		TotalSpent:    18500,
+		LastVisit:     &lastVisit,
+		LoyaltyPoints: 340,
+		WalletBalance: 250,
+	}
+	if got != want {
+		t.Errorf("anonymisedSnapshot() = %+v, want %+v", got, want)
+	}
+	if snapshot.Name != "Asha Rao" {
+		t.Errorf("anonymisedSnapshot() changed its argument")
+	}
+}
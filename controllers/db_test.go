@@ -0,0 +1,57 @@
+// controllers/db_test.go
+package controllers
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDB opens TEST_DATABASE_URL and returns a transaction with tables created
+// in a throwaway schema. Everything is rolled back at the end of the test.
+func testDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to connect database: %v", err)
+	}
+
+	tx := db.Begin()
+	t.Cleanup(func() { tx.Rollback() })
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	for _, statement := range []string{
+		`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		"CREATE SCHEMA " + schema,
+		"SET LOCAL search_path TO " + schema + ", public",
+		"CREATE TYPE payment_status AS ENUM ('unpaid', 'partial', 'paid')",
+	} {
+		if err := tx.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	if err := tx.AutoMigrate(tables...); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+
+	// main.go adds deleted_at outside the models; queries filter on it
+	for _, table := range []string{"invoices", "customers", "services", "users"} {
+		if tx.Migrator().HasTable(table) {
+			if err := tx.Exec("ALTER TABLE " + table + " ADD COLUMN deleted_at timestamptz").Error; err != nil {
+				t.Fatalf("Failed to add %s.deleted_at: %v", table, err)
+			}
+		}
+	}
+	return tx
+}
@@ -0,0 +1,500 @@
+// controllers/gift_card.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SellGiftCardInput defines the expected JSON structure for selling a gift card
+type SellGiftCardInput struct {
+	PurchaserID         uuid.UUID  `json:"purchaserId" binding:"required"`
+	Value               float64    `json:"value" binding:"required,gt=0"`
+	ExpiresAt           *time.Time `json:"expiresAt"`
+	RecipientCustomerID *uuid.UUID `json:"recipientCustomerId"`
+	RecipientName       string     `json:"recipientName"`
+	RecipientPhone      string     `json:"recipientPhone"`
+	RecipientEmail      string     `json:"recipientEmail" binding:"omitempty,email"`
+	Message             string     `json:"message"`
+	PaymentMethod       string     `json:"paymentMethod" binding:"required"`
+}
+
+// GiftCardLiability summarises outstanding stored value owed to customers
+type GiftCardLiability struct {
+	ActiveCards        int     `json:"activeCards"`
+	OutstandingBalance float64 `json:"outstandingBalance"`
+	ExpiredCards       int     `json:"expiredCards"`
+	ExpiredBalance     float64 `json:"expiredBalance"`
+	TotalIssued        float64 `json:"totalIssued"`
+	TotalRedeemed      float64 `json:"totalRedeemed"`
+}
+
+// Gift cards are valid for a year unless an expiry is given
+const defaultGiftCardValidity = 12
+
+// SellGiftCard issues a new gift card and bills it to the purchaser on its own invoice
+func SellGiftCard(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+	userUUID := uuid.Must(uuid.Parse(userID.(string)))
+
+	var input SellGiftCardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Validate purchaser (and recipient, if an existing customer) belong to the salon
+	var purchaser models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.PurchaserID).
+		First(&purchaser).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Purchaser not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	recipientName := input.RecipientName
+	if input.RecipientCustomerID != nil {
+		var recipient models.Customer
+		if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, *input.RecipientCustomerID).
+			First(&recipient).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Recipient not found")
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return
+		}
+		if recipientName == "" {
+			recipientName = recipient.Name
+		}
+	}
+
+	now := time.Now()
+	expiresAt := now.AddDate(0, defaultGiftCardValidity, 0)
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(now) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Expiry must be in the future")
+			return
+		}
+		expiresAt = *input.ExpiresAt
+	}
+
+	code, err := newGiftCardCode()
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate gift card code")
+		return
+	}
+
+	giftCardID := uuid.New()
+	invoice := models.Invoice{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CreatedByUserID: userUUID,
+		InvoiceNumber:   newInvoiceNumber(),
+		CustomerID:      purchaser.ID,
+		InvoiceDate:     now,
+		Subtotal:        input.Value,
+		Total:           input.Value,
+		PaymentStatus:   "paid",
+		PaidAmount:      input.Value,
+		PaymentMethod:   input.PaymentMethod,
+		Notes:           "Gift card " + code,
+		Items: []models.InvoiceItem{{
+			ID:          uuid.New(),
+			ItemType:    "gift_card",
+			GiftCardID:  &giftCardID,
+			ServiceName: "Gift card " + code,
+			Quantity:    1,
+			UnitPrice:   input.Value,
+			TotalPrice:  input.Value,
+		}},
+	}
+
+	giftCard := models.GiftCard{
+		ID:                  giftCardID,
+		SalonID:             salonUUID,
+		CreatedByUserID:     userUUID,
+		Code:                code,
+		InitialValue:        input.Value,
+		Balance:             input.Value,
+		ExpiresAt:           &expiresAt,
+		Status:              "active",
+		PurchaserCustomerID: purchaser.ID,
+		RecipientCustomerID: input.RecipientCustomerID,
+		RecipientName:       recipientName,
+		RecipientPhone:      input.RecipientPhone,
+		RecipientEmail:      input.RecipientEmail,
+		Message:             input.Message,
+		SoldInvoiceID:       invoice.ID,
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(&invoice).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create invoice")
+		return
+	}
+
+	if err := tx.Create(&models.InvoicePayment{
+		ID:              uuid.New(),
+		InvoiceID:       invoice.ID,
+		SalonID:         salonUUID,
+		CreatedByUserID: userUUID,
+		Method:          input.PaymentMethod,
+		Amount:          input.Value,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment")
+		return
+	}
+
+	if err := tx.Create(&giftCard).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create gift card")
+		return
+	}
+
+	if err := tx.Create(&models.GiftCardTransaction{
+		ID:              uuid.New(),
+		GiftCardID:      giftCard.ID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: userUUID,
+		Type:            "issue",
+		Amount:          input.Value,
+		BalanceAfter:    input.Value,
+	}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record gift card transaction")
+		return
+	}
+
+	// The purchaser's visits and spend are left alone: the card is spent when it is redeemed
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"giftCard": giftCard,
+		"invoice":  invoice,
+	})
+}
+
+// GetGiftCards retrieves all gift cards for the salon
+func GetGiftCards(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var giftCards []models.GiftCard
+	if err := config.DB.Where("salon_id = ?", salonUUID).
+		Order("created_at DESC").
+		Find(&giftCards).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve gift cards")
+		return
+	}
+
+	c.JSON(http.StatusOK, giftCards)
+}
+
+// GetGiftCard retrieves a gift card with its transaction history
+func GetGiftCard(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	giftCardUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid gift card ID format")
+		return
+	}
+
+	var giftCard models.GiftCard
+	if err := config.DB.Preload("Transactions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at")
+	}).Where("salon_id = ? AND id = ?", salonUUID, giftCardUUID).
+		First(&giftCard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Gift card not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, giftCard)
+}
+
+// LookupGiftCardBalance returns the balance and validity for a gift card code
+func LookupGiftCardBalance(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var giftCard models.GiftCard
+	if err := config.DB.Where("salon_id = ? AND code = ?", salonUUID, normaliseGiftCardCode(c.Param("code"))).
+		First(&giftCard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Gift card not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":         giftCard.Code,
+		"balance":      giftCard.Balance,
+		"initialValue": giftCard.InitialValue,
+		"expiresAt":    giftCard.ExpiresAt,
+		"status":       giftCard.Status,
+		"isExpired":    giftCard.IsExpired(time.Now()),
+		"isRedeemable": giftCard.Status == "active" && !giftCard.IsExpired(time.Now()) && giftCard.Balance > 0,
+	})
+}
+
+// GetGiftCardLiability reports outstanding gift card value the salon owes
+func GetGiftCardLiability(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var liability GiftCardLiability
+	if err := config.DB.Raw(`
+		SELECT
+			COUNT(*) FILTER (WHERE balance > 0 AND (expires_at IS NULL OR expires_at >= NOW())) as active_cards,
+			COALESCE(SUM(balance) FILTER (WHERE expires_at IS NULL OR expires_at >= NOW()), 0) as outstanding_balance,
+			COUNT(*) FILTER (WHERE balance > 0 AND expires_at < NOW()) as expired_cards,
+			COALESCE(SUM(balance) FILTER (WHERE expires_at < NOW()), 0) as expired_balance,
+			COALESCE(SUM(initial_value), 0) as total_issued,
+			COALESCE(SUM(initial_value - balance), 0) as total_redeemed
+		FROM gift_cards
+		WHERE salon_id = ? AND status = 'active'
+	`, salonUUID).Scan(&liability).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate gift card liability")
+		return
+	}
+
+	c.JSON(http.StatusOK, liability)
+}
+
+// applyGiftCardPayment redeems a gift card against the invoice's unpaid balance
+func applyGiftCardPayment(tx *gorm.DB, invoice *models.Invoice, userID uuid.UUID, code string, amount *float64) error {
+	var giftCard models.GiftCard
+	if err := tx.Where("salon_id = ? AND code = ?", invoice.SalonID, normaliseGiftCardCode(code)).
+		First(&giftCard).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &invoiceInputError{"Gift card not found"}
+		}
+		return err
+	}
+	if giftCard.Status != "active" {
+		return &invoiceInputError{"Gift card is not active"}
+	}
+	if giftCard.IsExpired(time.Now()) {
+		return &invoiceInputError{"Gift card has expired"}
+	}
+
+	due := invoice.Total - invoice.PaidAmount
+	redeem := math.Min(giftCard.Balance, due)
+	if amount != nil {
+		if *amount > giftCard.Balance {
+			return &invoiceInputError{"Gift card balance is insufficient"}
+		}
+		if *amount > due {
+			return &invoiceInputError{"Gift card amount exceeds the amount due"}
+		}
+		redeem = *amount
+	}
+	if redeem <= 0 {
+		return &invoiceInputError{"Nothing to redeem on this gift card"}
+	}
+
+	// Guarded decrement so concurrent redemptions cannot overdraw the card;
+	// RETURNING gives the balance this redemption left
+	var updated models.GiftCard
+	result := tx.Model(&updated).Clauses(clause.Returning{Columns: []clause.Column{{Name: "balance"}}}).
+		Where("id = ? AND balance >= ?", giftCard.ID, redeem).
+		Update("balance", gorm.Expr("balance - ?", redeem))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &invoiceInputError{"Gift card balance is insufficient"}
+	}
+
+	if err := tx.Create(&models.GiftCardTransaction{
+		ID:              uuid.New(),
+		GiftCardID:      giftCard.ID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: userID,
+		Type:            "redeem",
+		Amount:          -redeem,
+		BalanceAfter:    updated.Balance,
+	}).Error; err != nil {
+		return err
+	}
+
+	return recordTenderPayment(tx, invoice, models.InvoicePayment{
+		CreatedByUserID: userID,
+		Method:          "gift_card",
+		Amount:          redeem,
+		GiftCardID:      &giftCard.ID,
+		Reference:       giftCard.Code,
+	})
+}
+
+// reverseGiftCardActivity refunds gift card redemptions on an invoice being deleted
+// and voids any gift card it sold, provided that card has not been used yet
+func reverseGiftCardActivity(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	var payments []models.InvoicePayment
+	if err := tx.Where("invoice_id = ? AND gift_card_id IS NOT NULL", invoice.ID).Find(&payments).Error; err != nil {
+		return err
+	}
+	for _, payment := range payments {
+		var giftCard models.GiftCard
+		result := tx.Model(&giftCard).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}, {Name: "balance"}}}).
+			Where("id = ?", *payment.GiftCardID).
+			Update("balance", gorm.Expr("balance + ?", payment.Amount))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Create(&models.GiftCardTransaction{
+			ID:              uuid.New(),
+			GiftCardID:      giftCard.ID,
+			InvoiceID:       &invoice.ID,
+			CreatedByUserID: userID,
+			Type:            "refund",
+			Amount:          payment.Amount,
+			BalanceAfter:    giftCard.Balance,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	for _, item := range invoice.Items {
+		if item.GiftCardID == nil {
+			continue
+		}
+		// Locked so a redemption cannot land between the check and the void
+		var giftCard models.GiftCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&giftCard, "id = ?", *item.GiftCardID).Error; err != nil {
+			return err
+		}
+		if giftCard.Balance < giftCard.InitialValue {
+			return &invoiceInputError{"Gift card " + giftCard.Code + " sold on this invoice has already been redeemed"}
+		}
+		voided := giftCard.Balance
+		if err := tx.Model(&giftCard).Updates(map[string]interface{}{"status": "void", "balance": 0}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.GiftCardTransaction{
+			ID:              uuid.New(),
+			GiftCardID:      giftCard.ID,
+			InvoiceID:       &invoice.ID,
+			CreatedByUserID: userID,
+			Type:            "void",
+			Amount:          -voided,
+			BalanceAfter:    0,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newGiftCardCode generates a code such as "GC-7KQ2-M9XD-P4TA" not yet in use
+func newGiftCardCode() (string, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		raw := utils.GenerateCode(12)
+		code := "GC-" + raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12]
+
+		var count int64
+		if err := config.DB.Model(&models.GiftCard{}).Where("code = ?", code).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return code, nil
+		}
+	}
+	return "", errors.New("could not generate a unique gift card code")
+}
+
+// normaliseGiftCardCode accepts codes typed in lower case or with spaces
+func normaliseGiftCardCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+}
@@ -0,0 +1,154 @@
+// controllers/gift_card_test.go
+package controllers
+
+import (
+	"errors"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNormaliseGiftCardCode(t *testing.T) {
+	tests := map[string]string{
+		"GC-7KQ2-M9XD-P4TA":     "GC-7KQ2-M9XD-P4TA",
+		"gc-7kq2-m9xd-p4ta":     "GC-7KQ2-M9XD-P4TA",
+		"  GC-7KQ2 M9XD P4TA  ": "GC-7KQ2M9XDP4TA",
+		"":                      "",
+	}
+	for code, want := range tests {
+		if got := normaliseGiftCardCode(code); got != want {
+			t.Errorf("normaliseGiftCardCode(%q) = %q, want %q", code, got, want)
+		}
+	}
+}
+
+func TestInvoiceCustomerStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		invoice    models.Invoice
+		wantVisits int
+		wantSpent  float64
+	}{
+		{"no items", models.Invoice{Total: 500}, 1, 500},
+		{"service", models.Invoice{Total: 1180, Items: []models.InvoiceItem{
+			{ItemType: "service", TotalPrice: 1000},
+		}}, 1, 1180},
+		{"gift card only", models.Invoice{Total: 2000, Items: []models.InvoiceItem{
+			{ItemType: "gift_card", TotalPrice: 2000},
+		}}, 0, 0},
+		{"gift card with a service", models.Invoice{Total: 3000, Items: []models.InvoiceItem{
+			{ItemType: "gift_card", TotalPrice: 2000},
+			{ItemType: "service", TotalPrice: 1000},
+		}}, 1, 1000},
+		{"membership only", models.Invoice{Total: 5000, Items: []models.InvoiceItem{
+			{ItemType: "membership", TotalPrice: 5000},
+		}}, 0, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visits, spent := invoiceCustomerStats(tt.invoice)
+			if visits != tt.wantVisits || spent != tt.wantSpent {
+				t.Errorf("invoiceCustomerStats() = %d, %v; want %d, %v", visits, spent, tt.wantVisits, tt.wantSpent)
+			}
+		})
+	}
+}
+
+func TestApplyGiftCardPayment(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	amount := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name        string
+		card        models.GiftCard
+		code        string
+		amount      *float64
+		wantErr     string
+		wantRedeem  float64
+		wantBalance float64
+	}{
+		{"whole due from a larger card", models.GiftCard{Balance: 1500}, "gc-test-0001", nil, "", 1000, 500},
+		{"whole card when it is smaller", models.GiftCard{Balance: 400}, "GC-TEST-0001", nil, "", 400, 0},
+		{"chosen amount", models.GiftCard{Balance: 1500}, "GC-TEST-0001", amount(250), "", 250, 1250},
+		{"more than the balance", models.GiftCard{Balance: 200}, "GC-TEST-0001", amount(250), "Gift card balance is insufficient", 0, 200},
+		{"more than the amount due", models.GiftCard{Balance: 1500}, "GC-TEST-0001", amount(1200), "Gift card amount exceeds the amount due", 0, 1500},
+		{"empty card", models.GiftCard{Balance: 0}, "GC-TEST-0001", nil, "Nothing to redeem on this gift card", 0, 0},
+		{"void card", models.GiftCard{Balance: 500, Status: "void"}, "GC-TEST-0001", nil, "Gift card is not active", 0, 500},
+		{"expired card", models.GiftCard{Balance: 500, ExpiresAt: &expired}, "GC-TEST-0001", nil, "Gift card has expired", 0, 500},
+		{"unknown code", models.GiftCard{Balance: 500}, "GC-NOPE", nil, "Gift card not found", 0, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t,
+				&models.Invoice{},
+				&models.InvoicePayment{},
+				&models.GiftCard{},
+				&models.GiftCardTransaction{},
+			)
+			userID := uuid.New()
+
+			invoice := models.Invoice{
+				ID:              uuid.New(),
+				SalonID:         uuid.New(),
+				CreatedByUserID: userID,
+				InvoiceNumber:   "INV-" + uuid.NewString()[:8],
+				CustomerID:      uuid.New(),
+				Subtotal:        1000,
+				Total:           1000,
+				PaymentStatus:   "unpaid",
+			}
+			if err := db.Create(&invoice).Error; err != nil {
+				t.Fatalf("Failed to create invoice: %v", err)
+			}
+
+			card := tt.card
+			card.ID = uuid.New()
+			card.SalonID = invoice.SalonID
+			card.CreatedByUserID = userID
+			card.Code = "GC-TEST-0001"
+			card.InitialValue = 2000
+			card.PurchaserCustomerID = uuid.New()
+			card.SoldInvoiceID = uuid.New()
+			if card.Status == "" {
+				card.Status = "active"
+			}
+			if err := db.Create(&card).Error; err != nil {
+				t.Fatalf("Failed to create gift card: %v", err)
+			}
+
+			err := applyGiftCardPayment(db, &invoice, userID, tt.code, tt.amount)
+			if tt.wantErr != "" {
+				var inputErr *invoiceInputError
+				if !errors.As(err, &inputErr) || inputErr.Error() != tt.wantErr {
+					t.Fatalf("applyGiftCardPayment() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("applyGiftCardPayment() error = %v", err)
+			}
+
+			db.First(&card, "id = ?", card.ID)
+			if card.Balance != tt.wantBalance {
+				t.Errorf("card balance = %v, want %v", card.Balance, tt.wantBalance)
+			}
+			if invoice.PaidAmount != tt.wantRedeem {
+				t.Errorf("invoice paid amount = %v, want %v", invoice.PaidAmount, tt.wantRedeem)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			var entry models.GiftCardTransaction
+			if err := db.First(&entry, "gift_card_id = ?", card.ID).Error; err != nil {
+				t.Fatalf("no gift card transaction: %v", err)
+			}
+			if entry.Amount != -tt.wantRedeem || entry.BalanceAfter != tt.wantBalance {
+				t.Errorf("transaction = %v, balance after %v; want %v, %v", entry.Amount, entry.BalanceAfter, -tt.wantRedeem, tt.wantBalance)
+			}
+			if want := models.PaymentStatusFor(invoice.Total, tt.wantRedeem); invoice.PaymentStatus != want {
+				t.Errorf("invoice status = %s, want %s", invoice.PaymentStatus, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+// controllers/household.go
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddHouseholdMemberInput defines the expected JSON structure for adding a
+// family member who shares the primary contact's phone number
+type AddHouseholdMemberInput struct {
+	Name         string     `json:"name" binding:"required"`
+	Relationship string     `json:"relationship" binding:"required,oneof=spouse partner child parent sibling other"`
+	Email        string     `json:"email"`
+	Birthday     *time.Time `json:"birthday"`
+	Anniversary  *time.Time `json:"anniversary"`
+	Notes        string     `json:"notes"`
+	Gender       string     `json:"gender" binding:"omitempty,oneof=female male other"`
+	Tags         []string   `json:"tags"`
+}
+
+// LinkHouseholdMemberInput links an existing customer into a household
+type LinkHouseholdMemberInput struct {
+	CustomerID   uuid.UUID `json:"customerId" binding:"required"`
+	Relationship string    `json:"relationship" binding:"required,oneof=spouse partner child parent sibling other"`
+}
+
+// UnlinkHouseholdMemberInput gives a member leaving a household their own
+// number. It is required when they were sharing the primary's.
+type UnlinkHouseholdMemberInput struct {
+	Phone string `json:"phone"`
+}
+
+// MakeHouseholdPrimaryInput says how the current primary relates to the new one
+type MakeHouseholdPrimaryInput struct {
+	PreviousPrimaryRelationship string `json:"previousPrimaryRelationship" binding:"required,oneof=spouse partner child parent sibling other"`
+}
+
+var (
+	errHouseholdMember   = errors.New("customer already belongs to a household")
+	errHouseholdHasOwn   = errors.New("customer is the primary contact of another household")
+	errHouseholdPhoneDup = errors.New("another customer already has this phone number")
+)
+
+// GetCustomerHousehold returns the household the customer belongs to: its
+// primary contact and members. A customer with no household is returned as the
+// primary of a household with no members.
+func GetCustomerHousehold(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	primary, members, err := loadHousehold(config.DB, customer)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch household")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"primary": primary,
+		"members": members,
+	})
+}
+
+// AddHouseholdMember creates a customer who shares the household's phone number
+// but has their own name, dates and visit history. Added to a member, the new
+// customer joins that member's household.
+func AddHouseholdMember(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var input AddHouseholdMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	primary, _, err := loadHousehold(config.DB, customer)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	member := models.Customer{
+		ID:                 uuid.New(),
+		SalonID:            salonUUID,
+		CreatedByUserID:    userUUID,
+		Name:               input.Name,
+		Phone:              primary.Phone,
+		Email:              input.Email,
+		Birthday:           input.Birthday,
+		Anniversary:        input.Anniversary,
+		Notes:              input.Notes,
+		Gender:             input.Gender,
+		IsActive:           true,
+		HouseholdPrimaryID: &primary.ID,
+		Relationship:       input.Relationship,
+	}
+	if input.Tags != nil {
+		tags, err := canonicalCustomerTags(config.DB, salonUUID, input.Tags)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		member.Tags = tags
+	}
+
+	if err := config.DB.Create(&member).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to add household member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// LinkHouseholdMember moves an existing customer into the household. They keep
+// their own phone number; give them the household's number through the
+// customer update if they share it.
+func LinkHouseholdMember(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var input LinkHouseholdMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	primary, _, err := loadHousehold(config.DB, customer)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if input.CustomerID == primary.ID {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer is already this household's primary contact")
+		return
+	}
+
+	var member models.Customer
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+			First(&member).Error; err != nil {
+			return err
+		}
+		if member.HouseholdPrimaryID != nil {
+			return errHouseholdMember
+		}
+		var ownMembers int64
+		if err := tx.Model(&models.Customer{}).Where("household_primary_id = ?", member.ID).Count(&ownMembers).Error; err != nil {
+			return err
+		}
+		if ownMembers > 0 {
+			return errHouseholdHasOwn
+		}
+
+		before := member
+		member.HouseholdPrimaryID = &primary.ID
+		member.Relationship = input.Relationship
+		if err := tx.Model(&member).Updates(map[string]interface{}{
+			"household_primary_id": primary.ID,
+			"relationship":         input.Relationship,
+		}).Error; err != nil {
+			return err
+		}
+		return recordCustomerChange(tx, before, member, userUUID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(c, http.StatusNotFound, "Customer to link not found")
+		case errors.Is(err, errHouseholdMember), errors.Is(err, errHouseholdHasOwn):
+			utils.RespondWithError(c, http.StatusConflict, err.Error())
+		default:
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to link household member")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// UnlinkHouseholdMember makes a household member a standalone customer again,
+// keeping their history. A member sharing the household's number needs a
+// number of their own.
+func UnlinkHouseholdMember(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	member, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+	if member.HouseholdPrimaryID == nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer is not a household member")
+		return
+	}
+
+	var input UnlinkHouseholdMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	before := member
+	if input.Phone != "" {
+		if !utils.ValidatePhone(input.Phone) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid phone number format")
+			return
+		}
+		member.Phone = input.Phone
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.Customer{}).
+			Where("salon_id = ? AND phone = ? AND household_primary_id IS NULL AND id <> ?", salonUUID, member.Phone, member.ID).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errHouseholdPhoneDup
+		}
+
+		member.HouseholdPrimaryID = nil
+		member.Relationship = ""
+		if err := tx.Model(&member).Updates(map[string]interface{}{
+			"phone":                member.Phone,
+			"household_primary_id": nil,
+			"relationship":         "",
+		}).Error; err != nil {
+			return err
+		}
+		return recordCustomerChange(tx, before, member, userUUID)
+	})
+	if err != nil {
+		if errors.Is(err, errHouseholdPhoneDup) {
+			if input.Phone == "" {
+				utils.RespondWithError(c, http.StatusBadRequest, "Member shares the household's phone number; give them their own")
+			} else {
+				utils.RespondWithError(c, http.StatusConflict, "Another customer with this phone number already exists")
+			}
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to unlink household member")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+// MakeHouseholdPrimary makes a member the household's primary contact, for
+// example when the number now belongs to them. The previous primary and the
+// other members become members under them.
+func MakeHouseholdPrimary(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	member, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+	if member.HouseholdPrimaryID == nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer is not a household member")
+		return
+	}
+
+	var input MakeHouseholdPrimaryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var previous models.Customer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&previous, "id = ?", *member.HouseholdPrimaryID).Error; err != nil {
+			return err
+		}
+
+		// The old primary steps down first, so its number is free if the two share it
+		previousBefore := previous
+		previous.HouseholdPrimaryID = &member.ID
+		previous.Relationship = input.PreviousPrimaryRelationship
+		if err := tx.Model(&previous).Updates(map[string]interface{}{
+			"household_primary_id": member.ID,
+			"relationship":         input.PreviousPrimaryRelationship,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Customer{}).
+			Where("household_primary_id = ? AND id <> ?", previous.ID, member.ID).
+			Update("household_primary_id", member.ID).Error; err != nil {
+			return err
+		}
+
+		memberBefore := member
+		member.HouseholdPrimaryID = nil
+		member.Relationship = ""
+		if err := tx.Model(&member).Updates(map[string]interface{}{
+			"household_primary_id": nil,
+			"relationship":         "",
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := recordCustomerChange(tx, previousBefore, previous, userUUID); err != nil {
+			return err
+		}
+		return recordCustomerChange(tx, memberBefore, member, userUUID)
+	})
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to change household primary")
+		return
+	}
+
+	primary, members, err := loadHousehold(config.DB, member)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch household")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"primary": primary,
+		"members": members,
+	})
+}
+
+// loadHousehold returns the primary contact of the customer's household and its
+// members, ordered by name
+func loadHousehold(db *gorm.DB, customer models.Customer) (models.Customer, []models.Customer, error) {
+	primary := customer
+	if customer.HouseholdPrimaryID != nil {
+		if err := db.First(&primary, "id = ?", *customer.HouseholdPrimaryID).Error; err != nil {
+			return primary, nil, err
+		}
+	}
+
+	members := []models.Customer{}
+	err := db.Where("household_primary_id = ?", primary.ID).Order("name").Find(&members).Error
+	return primary, members, err
+}
@@ -0,0 +1,112 @@
+// controllers/household_test.go
+package controllers
+
+import (
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHouseholdRoot(t *testing.T) {
+	primary := uuid.New()
+	member := models.Customer{ID: uuid.New(), HouseholdPrimaryID: &primary}
+	standalone := models.Customer{ID: uuid.New()}
+
+	if got := householdRoot(member); got != primary {
+		t.Errorf("householdRoot(member) = %s, want the primary %s", got, primary)
+	}
+	if got := householdRoot(standalone); got != standalone.ID {
+		t.Errorf("householdRoot(standalone) = %s, want its own ID %s", got, standalone.ID)
+	}
+}
+
+func TestHouseholdFieldChanges(t *testing.T) {
+	primary := uuid.New()
+	standalone := models.Customer{Name: "Riya Rao", Phone: "9876543210", IsActive: true}
+	member := standalone
+	member.HouseholdPrimaryID, member.Relationship = &primary, "child"
+
+	tests := []struct {
+		name          string
+		before, after models.Customer
+		want          models.FieldChangeList
+	}{
+		{"linked", standalone, member, models.FieldChangeList{
+			{Field: "householdPrimaryId", Old: "", New: primary.String()},
+			{Field: "relationship", Old: "", New: "child"},
+		}},
+		{"unlinked", member, standalone, models.FieldChangeList{
+			{Field: "householdPrimaryId", Old: primary.String(), New: ""},
+			{Field: "relationship", Old: "child", New: ""},
+		}},
+		{"same primary, new pointer", member, func() models.Customer {
+			c, id := member, primary
+			c.HouseholdPrimaryID = &id
+			return c
+		}(), models.FieldChangeList{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := customerFieldChanges(tt.before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("customerFieldChanges() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadHousehold(t *testing.T) {
+	db := testDB(t, &models.Customer{})
+	salonID, userID := uuid.New(), uuid.New()
+
+	newCustomer := func(name, phone string, primary *uuid.UUID) models.Customer {
+		customer := models.Customer{
+			ID:                 uuid.New(),
+			SalonID:            salonID,
+			CreatedByUserID:    userID,
+			Name:               name,
+			Phone:              phone,
+			HouseholdPrimaryID: primary,
+		}
+		if err := db.Create(&customer).Error; err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		return customer
+	}
+	asha := newCustomer("Asha Rao", "9876543210", nil)
+	// Members may share the primary's number
+	riya := newCustomer("Riya Rao", "9876543210", &asha.ID)
+	dev := newCustomer("Dev Rao", "9123456780", &asha.ID)
+	ravi := newCustomer("Ravi Kumar", "9988776655", nil)
+
+	tests := []struct {
+		name     string
+		customer models.Customer
+		primary  uuid.UUID
+		members  []string
+	}{
+		{"from the primary", asha, asha.ID, []string{"Dev Rao", "Riya Rao"}},
+		{"from a member", riya, asha.ID, []string{"Dev Rao", "Riya Rao"}},
+		{"from another member", dev, asha.ID, []string{"Dev Rao", "Riya Rao"}},
+		{"no household", ravi, ravi.ID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, members, err := loadHousehold(db, tt.customer)
+			if err != nil {
+				t.Fatalf("loadHousehold() error = %v", err)
+			}
+			if primary.ID != tt.primary {
+				t.Errorf("primary = %s, want %s", primary.Name, tt.primary)
+			}
+			var names []string
+			for _, member := range members {
+				names = append(names, member.Name)
+			}
+			if !reflect.DeepEqual(names, tt.members) {
+				t.Errorf("members = %v, want %v", names, tt.members)
+			}
+		})
+	}
+}
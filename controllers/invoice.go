@@ -3,6 +3,7 @@ package controllers
 
 import (
 	"errors"
+	"math"
 	"net/http"
 	"time"
 
@@ -13,12 +14,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// InvoiceItemInput defines the structure for an invoice item
+// InvoiceItemInput defines the structure for an invoice item.
+// Exactly one of ServiceID or ProductID must be set. A service line may name
+// one of the customer's packages to use a prepaid session instead of paying.
 type InvoiceItemInput struct {
-	ServiceID uuid.UUID `json:"serviceId" binding:"required"`
-	Quantity  int       `json:"quantity" binding:"min=1"`
+	ServiceID         *uuid.UUID `json:"serviceId"`
+	ProductID         *uuid.UUID `json:"productId"`
+	Quantity          int        `json:"quantity" binding:"min=1"`
+	CustomerPackageID *uuid.UUID `json:"customerPackageId"`
 }
 
 // CreateInvoiceInput defines the expected JSON structure for creating an invoice
@@ -32,6 +38,19 @@ type CreateInvoiceInput struct {
 	PaidAmount    float64            `json:"paidAmount" binding:"min=0"`
 	PaymentMethod string             `json:"paymentMethod"`
 	Notes         string             `json:"notes"`
+
+	// Optional promo code; its discount is added to any manual discount
+	CouponCode string `json:"couponCode"`
+
+	// Optional gift card tender; amount defaults to as much of the balance as the invoice needs
+	GiftCardCode   string   `json:"giftCardCode"`
+	GiftCardAmount *float64 `json:"giftCardAmount" binding:"omitempty,gt=0"`
+
+	// Optional loyalty points to pay with, at the salon's point value
+	RedeemPoints int `json:"redeemPoints" binding:"min=0"`
+
+	// Optional amount to take from the customer's wallet, alongside PaidAmount in cash or card
+	WalletAmount *float64 `json:"walletAmount" binding:"omitempty,gt=0"`
 }
 
 // UpdateInvoiceInput defines the expected JSON structure for updating an invoice
@@ -90,34 +109,48 @@ func CreateInvoice(c *gin.Context) {
 	var invoiceItems []models.InvoiceItem
 
 	for _, item := range input.Items {
-		// Validate service exists and belongs to the same salon
-		var service models.Service
-		if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, item.ServiceID).
-			First(&service).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+item.ServiceID.String())
-			} else {
-				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
-			}
+		// Validate the service or product exists and belongs to the same salon
+		lineItem, err := resolveInvoiceItem(config.DB, salonUUID, item)
+		if err != nil {
+			respondInvoiceInputError(c, err)
 			return
 		}
+		lineItem.ID = uuid.New()
 
-		// Calculate item total
-		itemTotal := service.Price * float64(item.Quantity)
-		subtotal += itemTotal
+		invoiceItems = append(invoiceItems, lineItem)
+	}
 
-		invoiceItems = append(invoiceItems, models.InvoiceItem{
-			ID:          uuid.New(),
-			ServiceID:   service.ID,
-			ServiceName: service.Name,
-			Quantity:    item.Quantity,
-			UnitPrice:   service.Price,
-			TotalPrice:  itemTotal,
-		})
+	// Prepaid package sessions are free; members get their plan pricing on the rest
+	if err := applyPackageSessions(config.DB, customer.ID, invoiceItems, time.Now()); err != nil {
+		respondInvoiceInputError(c, err)
+		return
+	}
+	if err := applyMembershipBenefits(config.DB, customer.ID, invoiceItems, time.Now()); err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to apply membership benefits")
+		return
+	}
+	for _, lineItem := range invoiceItems {
+		subtotal += lineItem.TotalPrice
 	}
 
-	// Calculate total
-	total := subtotal - input.Discount + (subtotal * input.Tax / 100)
+	// Apply coupon, if any, on top of the manual discount
+	discount := input.Discount
+	var coupon *models.Coupon
+	var couponDiscount float64
+	if input.CouponCode != "" {
+		applied, amount, err := evaluateCoupon(config.DB, salonUUID, input.CouponCode, customer, invoiceItems, time.Now())
+		if err != nil {
+			respondInvoiceInputError(c, err)
+			return
+		}
+		coupon = &applied
+		couponDiscount = amount
+		discount += amount
+	}
+	if discount > subtotal+0.005 {
+		respondInvoiceInputError(c, &invoiceInputError{"Discount cannot be more than the subtotal"})
+		return
+	}
 
 	// Set default invoice date to now if not provided
 	invoiceDate := time.Now()
@@ -133,9 +166,8 @@ func CreateInvoice(c *gin.Context) {
 		CustomerID:      input.CustomerID,
 		InvoiceDate:     invoiceDate,
 		Subtotal:        subtotal,
-		Discount:        input.Discount,
+		Discount:        discount,
 		Tax:             input.Tax,
-		Total:           total,
 		PaymentStatus:   input.PaymentStatus,
 		PaidAmount:      input.PaidAmount,
 		PaymentMethod:   input.PaymentMethod,
@@ -143,8 +175,25 @@ func CreateInvoice(c *gin.Context) {
 		Items:           invoiceItems,
 	}
 
-	// Generate invoice number (you might want a better way)
-	invoice.InvoiceNumber = "INV-" + time.Now().Format("20060102") + "-" + utils.GenerateRandomString(6)
+	// Calculate total
+	total := subtotal - discount + invoice.TaxAmount()
+	invoice.Total = total
+
+	if coupon != nil {
+		invoice.CouponID = &coupon.ID
+		invoice.CouponCode = coupon.Code
+	}
+
+	// Generate invoice number
+	invoice.InvoiceNumber = newInvoiceNumber()
+
+	// Warn, without blocking the sale, about services that need a patch test
+	warnings, err := invoicePatchTestWarnings(config.DB, invoice)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to check patch tests")
+		return
+	}
+	invoice.PatchTestWarnings = warnings
 
 	// Start transaction
 	tx := config.DB.Begin()
@@ -161,6 +210,84 @@ func CreateInvoice(c *gin.Context) {
 		return
 	}
 
+	// Record the coupon redemption for the audit trail
+	if coupon != nil {
+		if err := redeemCoupon(tx, *coupon, invoice, couponDiscount); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+	}
+
+	// Record the upfront payment
+	userUUID := uuid.Must(uuid.Parse(userID.(string)))
+	if input.PaidAmount > 0 {
+		method := input.PaymentMethod
+		if method == "" {
+			method = "cash"
+		}
+		if err := tx.Create(&models.InvoicePayment{
+			ID:              uuid.New(),
+			InvoiceID:       invoice.ID,
+			SalonID:         salonUUID,
+			CreatedByUserID: userUUID,
+			Method:          method,
+			Amount:          input.PaidAmount,
+		}).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment")
+			return
+		}
+	}
+
+	// Redeem a gift card against the remaining balance
+	if input.GiftCardCode != "" {
+		if err := applyGiftCardPayment(tx, &invoice, userUUID, input.GiftCardCode, input.GiftCardAmount); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+	}
+
+	// Pay with loyalty points
+	if input.RedeemPoints > 0 {
+		if err := applyLoyaltyRedemption(tx, &invoice, userUUID, input.RedeemPoints); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+	}
+
+	// Pay from the customer's wallet
+	if input.WalletAmount != nil {
+		if err := applyWalletPayment(tx, &invoice, userUUID, *input.WalletAmount); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+	}
+
+	// Fully paid invoices earn loyalty points
+	if err := awardLoyaltyPoints(tx, invoice, userUUID); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to award loyalty points")
+		return
+	}
+
+	// Take retail products out of stock now that the invoice is issued
+	if err := adjustProductStock(tx, invoiceItems, -1); err != nil {
+		tx.Rollback()
+		respondInvoiceInputError(c, err)
+		return
+	}
+
+	// Use up the package sessions redeemed on this invoice
+	if err := adjustPackageSessions(tx, invoiceItems, -1); err != nil {
+		tx.Rollback()
+		respondInvoiceInputError(c, err)
+		return
+	}
+
 	// Update customer stats
 	if err := tx.Model(&models.Customer{}).Where("id = ?", input.CustomerID).
 		Updates(map[string]interface{}{
@@ -247,6 +374,12 @@ func UpdateInvoice(c *gin.Context) {
 		return
 	}
 
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
 	salonUUID, err := uuid.Parse(salonID.(string))
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
@@ -274,9 +407,9 @@ func UpdateInvoice(c *gin.Context) {
 		}
 	}()
 
-	// Retrieve existing invoice
+	// Retrieve existing invoice, locked so a payment posted meanwhile is not lost
 	var invoice models.Invoice
-	if err := tx.Preload("Items").
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").
 		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
 		First(&invoice).Error; err != nil {
 		tx.Rollback()
@@ -287,8 +420,22 @@ func UpdateInvoice(c *gin.Context) {
 		}
 		return
 	}
+	before := invoice
 
 	// Update fields if provided
+	if input.CustomerID != nil && *input.CustomerID != invoice.CustomerID {
+		hasLedger, err := invoiceHasCustomerLedger(tx, invoice)
+		if err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if hasLedger {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusBadRequest, "The customer cannot be changed on an invoice with loyalty, wallet, package, membership or coupon activity")
+			return
+		}
+	}
 	if input.CustomerID != nil {
 		// Validate customer exists in the same salon
 		var customer models.Customer
@@ -311,9 +458,38 @@ func UpdateInvoice(c *gin.Context) {
 
 	// If items are being updated, recalculate the invoice
 	if input.Items != nil {
+		for _, item := range invoice.Items {
+			if item.ItemType == "gift_card" || item.ItemType == "membership" || item.ItemType == "package" {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusBadRequest, "Items on a gift card, membership or package sale cannot be changed")
+				return
+			}
+		}
+
 		var subtotal float64 = 0
 		var newInvoiceItems []models.InvoiceItem
 
+		// Return stock held by the old product lines before they are replaced
+		if err := adjustProductStock(tx, invoice.Items, 1); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+		if err := adjustPackageSessions(tx, invoice.Items, 1); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+
+		// Service records keep their notes but lose the link to the replaced lines
+		if err := tx.Model(&models.ServiceRecord{}).
+			Where("invoice_item_id IN (SELECT id FROM invoice_items WHERE invoice_id = ?)", invoice.ID).
+			Update("invoice_item_id", nil).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
+			return
+		}
+
 		// Delete existing items
 		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
 			tx.Rollback()
@@ -323,31 +499,51 @@ func UpdateInvoice(c *gin.Context) {
 
 		// Create new items
 		for _, item := range *input.Items {
-			// Validate service exists and belongs to the same salon
-			var service models.Service
-			if err := tx.Where("salon_id = ? AND id = ?", salonUUID, item.ServiceID).
-				First(&service).Error; err != nil {
+			// Validate the service or product exists and belongs to the same salon
+			lineItem, err := resolveInvoiceItem(tx, salonUUID, item)
+			if err != nil {
 				tx.Rollback()
-				if errors.Is(err, gorm.ErrRecordNotFound) {
-					utils.RespondWithError(c, http.StatusBadRequest, "Service not found: "+item.ServiceID.String())
-				} else {
-					utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
-				}
+				respondInvoiceInputError(c, err)
 				return
 			}
+			lineItem.InvoiceID = invoice.ID
+
+			newInvoiceItems = append(newInvoiceItems, lineItem)
+		}
+
+		// Free services on the old items were released when they were deleted above
+		if err := applyPackageSessions(tx, invoice.CustomerID, newInvoiceItems, invoice.InvoiceDate); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+		if err := applyMembershipBenefits(tx, invoice.CustomerID, newInvoiceItems, invoice.InvoiceDate); err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to apply membership benefits")
+			return
+		}
+		for _, lineItem := range newInvoiceItems {
+			subtotal += lineItem.TotalPrice
+		}
 
-			// Calculate item total
-			itemTotal := service.Price * float64(item.Quantity)
-			subtotal += itemTotal
+		if err := adjustProductStock(tx, newInvoiceItems, -1); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+		if err := adjustPackageSessions(tx, newInvoiceItems, -1); err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
 
-			newInvoiceItems = append(newInvoiceItems, models.InvoiceItem{
-				InvoiceID:   invoice.ID,
-				ServiceID:   service.ID,
-				ServiceName: service.Name,
-				Quantity:    item.Quantity,
-				UnitPrice:   service.Price,
-				TotalPrice:  itemTotal,
-			})
+		// Work the coupon out again on the new lines, keeping any manual discount
+		if invoice.CouponID != nil {
+			if err := reapplyCoupon(tx, &invoice, newInvoiceItems); err != nil {
+				tx.Rollback()
+				respondInvoiceInputError(c, err)
+				return
+			}
 		}
 
 		invoice.Items = newInvoiceItems
@@ -357,6 +553,11 @@ func UpdateInvoice(c *gin.Context) {
 	if input.Discount != nil {
 		invoice.Discount = *input.Discount
 	}
+	if (input.Items != nil || input.Discount != nil) && (invoice.Discount < 0 || invoice.Discount > invoice.Subtotal+0.005) {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, "Discount cannot be more than the subtotal")
+		return
+	}
 
 	if input.Tax != nil {
 		invoice.Tax = *input.Tax
@@ -364,15 +565,31 @@ func UpdateInvoice(c *gin.Context) {
 
 	// Recalculate total if needed
 	if input.Items != nil || input.Discount != nil || input.Tax != nil {
-		invoice.Total = invoice.Subtotal - invoice.Discount + (invoice.Subtotal * invoice.Tax / 100)
-	}
-
-	if input.PaymentStatus != nil {
-		invoice.PaymentStatus = *input.PaymentStatus
+		invoice.Total = invoice.Subtotal - invoice.Discount + invoice.TaxAmount()
 	}
 
 	if input.PaidAmount != nil {
-		invoice.PaidAmount = *input.PaidAmount
+		// Post the change as a payment, so the ledger and the register session
+		// see it and the status follows from the new paid amount
+		if change := *input.PaidAmount - invoice.PaidAmount; math.Abs(change) >= 0.005 {
+			method := "cash"
+			if input.PaymentMethod != nil && *input.PaymentMethod != "" && change > 0 {
+				method = *input.PaymentMethod
+			}
+			if err := recordTenderPayment(tx, &invoice, models.InvoicePayment{
+				CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+				Method:          method,
+				Amount:          change,
+				Reference:       "Paid amount edited",
+			}); err != nil {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment")
+				return
+			}
+		}
+		invoice.PaymentStatus = models.PaymentStatusFor(invoice.Total, invoice.PaidAmount)
+	} else if input.PaymentStatus != nil {
+		invoice.PaymentStatus = *input.PaymentStatus
 	}
 
 	if input.PaymentMethod != nil {
@@ -383,6 +600,16 @@ func UpdateInvoice(c *gin.Context) {
 		invoice.Notes = *input.Notes
 	}
 
+	if input.Items != nil || input.CustomerID != nil || input.InvoiceDate != nil {
+		warnings, err := invoicePatchTestWarnings(tx, invoice)
+		if err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to check patch tests")
+			return
+		}
+		invoice.PatchTestWarnings = warnings
+	}
+
 	// Save updated invoice
 	if err := tx.Save(&invoice).Error; err != nil {
 		tx.Rollback()
@@ -390,6 +617,31 @@ func UpdateInvoice(c *gin.Context) {
 		return
 	}
 
+	// Count the invoice's visit and spend towards whoever it now belongs to
+	if before.CustomerID != invoice.CustomerID || math.Abs(before.Total-invoice.Total) >= 0.005 || input.Items != nil {
+		for _, customerID := range []uuid.UUID{before.CustomerID, invoice.CustomerID} {
+			if err := recomputeCustomerStats(tx, customerID); err != nil {
+				tx.Rollback()
+				utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
+				return
+			}
+		}
+	}
+
+	if err := recordInvoiceRevision(tx, before, invoice, uuid.Must(uuid.Parse(userID.(string)))); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record invoice revision")
+		return
+	}
+
+	// Invoices settled later earn their loyalty points now, and edited ones gain
+	// or lose the difference
+	if err := syncLoyaltyPoints(tx, invoice, uuid.Must(uuid.Parse(userID.(string)))); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to award loyalty points")
+		return
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, invoice)
@@ -403,6 +655,12 @@ func DeleteInvoice(c *gin.Context) {
 		return
 	}
 
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
 	salonUUID, err := uuid.Parse(salonID.(string))
 	if err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
@@ -424,9 +682,10 @@ func DeleteInvoice(c *gin.Context) {
 		}
 	}()
 
-	// Retrieve invoice to get customer and total
+	// Retrieve invoice to get customer, total and items
 	var invoice models.Invoice
-	if err := tx.Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+	if err := tx.Preload("Items").
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
 		First(&invoice).Error; err != nil {
 		tx.Rollback()
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -437,6 +696,79 @@ func DeleteInvoice(c *gin.Context) {
 		return
 	}
 
+	// Put sold products back into stock
+	if err := adjustProductStock(tx, invoice.Items, 1); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to restore product stock")
+		return
+	}
+
+	// Give back package sessions used on this invoice and cancel packages sold on it
+	if err := adjustPackageSessions(tx, invoice.Items, 1); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to restore package sessions")
+		return
+	}
+	if err := reversePackageSale(tx, invoice); err != nil {
+		tx.Rollback()
+		respondInvoiceInputError(c, err)
+		return
+	}
+
+	// Refund gift card redemptions and void gift cards sold on this invoice
+	if err := reverseGiftCardActivity(tx, invoice, uuid.Must(uuid.Parse(userID.(string)))); err != nil {
+		tx.Rollback()
+		respondInvoiceInputError(c, err)
+		return
+	}
+
+	// Cancel memberships sold on this invoice
+	if err := tx.Model(&models.CustomerMembership{}).
+		Where("sold_invoice_id = ? AND status = 'active'", invoice.ID).
+		Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": time.Now(),
+		}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel membership")
+		return
+	}
+
+	// Put wallet payments back on account
+	if err := refundWalletPayments(tx, invoice, uuid.Must(uuid.Parse(userID.(string)))); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to refund wallet payments")
+		return
+	}
+
+	// Take back points earned and return points redeemed
+	if err := reverseLoyaltyActivity(tx, invoice, uuid.Must(uuid.Parse(userID.(string)))); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to reverse loyalty points")
+		return
+	}
+
+	// Release the coupon use
+	if err := reverseCouponRedemption(tx, invoice); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to reverse coupon redemption")
+		return
+	}
+
+	// Keep a void record of the invoice and the payments handed back, for the cash drawer
+	if err := recordInvoiceVoid(tx, invoice, uuid.Must(uuid.Parse(userID.(string))), c.Query("reason")); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record invoice void")
+		return
+	}
+
+	// Delete payments
+	if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoicePayment{}).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete invoice payments")
+		return
+	}
+
 	// Delete invoice items
 	if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
 		tx.Rollback()
@@ -452,10 +784,11 @@ func DeleteInvoice(c *gin.Context) {
 	}
 
 	// Update customer stats (decrement)
+	visits, spent := invoiceCustomerStats(invoice)
 	if err := tx.Model(&models.Customer{}).Where("id = ?", invoice.CustomerID).
 		Updates(map[string]interface{}{
-			"total_visits": gorm.Expr("total_visits - ?", 1),
-			"total_spent":  gorm.Expr("total_spent - ?", invoice.Total),
+			"total_visits": gorm.Expr("total_visits - ?", visits),
+			"total_spent":  gorm.Expr("total_spent - ?", spent),
 		}).Error; err != nil {
 		tx.Rollback()
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update customer stats")
@@ -466,3 +799,297 @@ func DeleteInvoice(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice deleted successfully"})
 }
+
+// invoiceInputError is a client-side problem with an invoice (bad reference, no stock, ...)
+type invoiceInputError struct {
+	message string
+}
+
+func (e *invoiceInputError) Error() string {
+	return e.message
+}
+
+// newInvoiceNumber generates a human-readable invoice number (you might want a better way)
+func newInvoiceNumber() string {
+	return "INV-" + time.Now().Format("20060102") + "-" + utils.GenerateRandomString(6)
+}
+
+// resolveInvoiceItem prices one input line against the salon's services or products
+func resolveInvoiceItem(db *gorm.DB, salonID uuid.UUID, item InvoiceItemInput) (models.InvoiceItem, error) {
+	if (item.ServiceID == nil) == (item.ProductID == nil) {
+		return models.InvoiceItem{}, &invoiceInputError{"Each item needs exactly one of serviceId or productId"}
+	}
+
+	if item.ServiceID != nil {
+		var service models.Service
+		if err := db.Where("salon_id = ? AND id = ?", salonID, *item.ServiceID).
+			First(&service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.InvoiceItem{}, &invoiceInputError{"Service not found: " + item.ServiceID.String()}
+			}
+			return models.InvoiceItem{}, err
+		}
+
+		itemTotal := service.Price * float64(item.Quantity)
+		return models.InvoiceItem{
+			ItemType:    "service",
+			ServiceID:   &service.ID,
+			ServiceName: service.Name,
+			Category:    service.Category,
+			Quantity:    item.Quantity,
+			UnitPrice:   service.Price,
+			TotalPrice:  itemTotal,
+			PackageID:   item.CustomerPackageID,
+		}, nil
+	}
+
+	if item.CustomerPackageID != nil {
+		return models.InvoiceItem{}, &invoiceInputError{"Package sessions can only be used on service items"}
+	}
+
+	var product models.Product
+	if err := db.Where("salon_id = ? AND id = ?", salonID, *item.ProductID).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.InvoiceItem{}, &invoiceInputError{"Product not found: " + item.ProductID.String()}
+		}
+		return models.InvoiceItem{}, err
+	}
+	if !product.IsActive {
+		return models.InvoiceItem{}, &invoiceInputError{"Product is not available: " + product.Name}
+	}
+
+	itemTotal := product.Price * float64(item.Quantity)
+	return models.InvoiceItem{
+		ItemType:    "product",
+		ProductID:   &product.ID,
+		SKU:         product.SKU,
+		ServiceName: product.Name,
+		Category:    product.Category,
+		Quantity:    item.Quantity,
+		UnitPrice:   product.Price,
+		TotalPrice:  itemTotal,
+		TaxRate:     product.TaxRate,
+		TaxAmount:   itemTotal * product.TaxRate / 100,
+	}, nil
+}
+
+// reapplyCoupon recalculates the coupon part of an invoice's discount for
+// replacement lines and updates its redemption. The coupon was already accepted
+// for this invoice, so only its spend rules are checked again.
+func reapplyCoupon(tx *gorm.DB, invoice *models.Invoice, items []models.InvoiceItem) error {
+	var coupon models.Coupon
+	if err := tx.First(&coupon, "id = ?", *invoice.CouponID).Error; err != nil {
+		return err
+	}
+	var redemption models.CouponRedemption
+	if err := tx.Where("invoice_id = ?", invoice.ID).First(&redemption).Error; err != nil {
+		return err
+	}
+
+	amount, err := couponDiscount(coupon, items)
+	if err != nil {
+		return err
+	}
+
+	invoice.Discount = math.Max(0, invoice.Discount-redemption.DiscountAmount) + amount
+	return tx.Model(&redemption).Update("discount_amount", amount).Error
+}
+
+// createSaleInvoice bills a single prepaid line (membership, package, ...) as a
+// paid invoice inside tx, records the payment and updates the customer's spend
+// and loyalty points. A sale is not a visit.
+func createSaleInvoice(tx *gorm.DB, customer models.Customer, userID uuid.UUID, item models.InvoiceItem,
+	tax float64, paymentMethod string) (models.Invoice, error) {
+
+	now := time.Now()
+	item.ID = uuid.New()
+
+	invoice := models.Invoice{
+		ID:              uuid.New(),
+		SalonID:         customer.SalonID,
+		CreatedByUserID: userID,
+		InvoiceNumber:   newInvoiceNumber(),
+		CustomerID:      customer.ID,
+		InvoiceDate:     now,
+		Subtotal:        item.TotalPrice,
+		Tax:             tax,
+		PaymentStatus:   "paid",
+		PaymentMethod:   paymentMethod,
+		Notes:           item.ServiceName,
+		Items:           []models.InvoiceItem{item},
+	}
+	invoice.Total = invoice.Subtotal + invoice.TaxAmount()
+	invoice.PaidAmount = invoice.Total
+
+	if err := tx.Create(&invoice).Error; err != nil {
+		return invoice, err
+	}
+	if err := tx.Create(&models.InvoicePayment{
+		ID:              uuid.New(),
+		InvoiceID:       invoice.ID,
+		SalonID:         customer.SalonID,
+		CreatedByUserID: userID,
+		Method:          paymentMethod,
+		Amount:          invoice.Total,
+	}).Error; err != nil {
+		return invoice, err
+	}
+
+	if err := tx.Model(&models.Customer{}).Where("id = ?", customer.ID).
+		Update("total_spent", gorm.Expr("total_spent + ?", invoice.Total)).Error; err != nil {
+		return invoice, err
+	}
+
+	return invoice, awardLoyaltyPoints(tx, invoice, userID)
+}
+
+// saleItemTypes are lines that sell stored value or a plan rather than a service
+var saleItemTypes = map[string]bool{"gift_card": true, "membership": true, "package": true}
+
+// invoiceCustomerStats returns what an invoice counts towards the customer's
+// visits and spend. Invoices that only sell gift cards, memberships or packages
+// are not visits, and a gift card is not spend until it is redeemed.
+func invoiceCustomerStats(invoice models.Invoice) (visits int, spent float64) {
+	visits, spent = 0, invoice.Total
+	for _, item := range invoice.Items {
+		if item.ItemType == "gift_card" {
+			spent -= item.TotalPrice
+		}
+		if !saleItemTypes[item.ItemType] {
+			visits = 1
+		}
+	}
+	if len(invoice.Items) == 0 {
+		visits = 1
+	}
+	return visits, spent
+}
+
+// invoiceHasCustomerLedger reports whether an invoice moved points, wallet
+// money, package sessions, membership benefits or a coupon redemption for its
+// customer, which would stay with the old customer if the invoice changed hands
+func invoiceHasCustomerLedger(tx *gorm.DB, invoice models.Invoice) (bool, error) {
+	if invoice.CouponID != nil {
+		return true, nil
+	}
+	for _, item := range invoice.Items {
+		if saleItemTypes[item.ItemType] || item.PackageID != nil || item.MembershipID != nil {
+			return true, nil
+		}
+	}
+
+	for _, model := range []interface{}{&models.LoyaltyTransaction{}, &models.WalletTransaction{}} {
+		var count int64
+		if err := tx.Model(model).Where("invoice_id = ?", invoice.ID).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordTenderPayment adds a payment from a stored-value tender (gift card,
+// points, ...) to the invoice and updates its paid amount, status and method
+func recordTenderPayment(tx *gorm.DB, invoice *models.Invoice, payment models.InvoicePayment) error {
+	if payment.ID == uuid.Nil {
+		payment.ID = uuid.New()
+	}
+	payment.InvoiceID = invoice.ID
+	payment.SalonID = invoice.SalonID
+	if err := tx.Create(&payment).Error; err != nil {
+		return err
+	}
+
+	// Add to the stored paid amount rather than writing it back, so a payment
+	// posted meanwhile is not lost. The update locks the row for the read below.
+	if err := tx.Model(&models.Invoice{}).Where("id = ?", invoice.ID).
+		Update("paid_amount", gorm.Expr("paid_amount + ?", payment.Amount)).Error; err != nil {
+		return err
+	}
+	var current models.Invoice
+	if err := tx.Select("paid_amount", "payment_method").First(&current, "id = ?", invoice.ID).Error; err != nil {
+		return err
+	}
+
+	invoice.PaidAmount = current.PaidAmount
+	invoice.PaymentStatus = models.PaymentStatusFor(invoice.Total, invoice.PaidAmount)
+	switch current.PaymentMethod {
+	case "", payment.Method:
+		invoice.PaymentMethod = payment.Method
+	default:
+		invoice.PaymentMethod = current.PaymentMethod + "+" + payment.Method
+	}
+
+	return tx.Model(invoice).Updates(map[string]interface{}{
+		"payment_status": invoice.PaymentStatus,
+		"payment_method": invoice.PaymentMethod,
+	}).Error
+}
+
+// recordInvoiceVoid snapshots an invoice and its payments before it is deleted
+func recordInvoiceVoid(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID, reason string) error {
+	var payments []models.InvoicePayment
+	if err := tx.Where("invoice_id = ?", invoice.ID).Find(&payments).Error; err != nil {
+		return err
+	}
+
+	voided := make(models.VoidedPaymentList, 0, len(payments))
+	for _, payment := range payments {
+		voided = append(voided, models.VoidedPayment{
+			Method: payment.Method,
+			Amount: payment.Amount,
+			PaidAt: payment.PaidAt,
+		})
+	}
+
+	return tx.Create(&models.InvoiceVoid{
+		ID:             uuid.New(),
+		SalonID:        invoice.SalonID,
+		InvoiceID:      invoice.ID,
+		InvoiceNumber:  invoice.InvoiceNumber,
+		CustomerID:     invoice.CustomerID,
+		VoidedByUserID: userID,
+		InvoiceDate:    invoice.InvoiceDate,
+		Total:          invoice.Total,
+		Discount:       invoice.Discount,
+		Payments:       voided,
+		Reason:         reason,
+	}).Error
+}
+
+// adjustProductStock moves stock for every product line; direction is -1 to
+// sell and +1 to return. Selling fails if a product does not have enough stock.
+func adjustProductStock(tx *gorm.DB, items []models.InvoiceItem, direction int) error {
+	for _, item := range items {
+		if item.ProductID == nil {
+			continue
+		}
+
+		query := tx.Model(&models.Product{}).Where("id = ?", *item.ProductID)
+		if direction < 0 {
+			query = query.Where("stock_quantity >= ?", item.Quantity)
+		}
+
+		result := query.Update("stock_quantity", gorm.Expr("stock_quantity + ?", direction*item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if direction < 0 && result.RowsAffected == 0 {
+			return &invoiceInputError{"Insufficient stock for " + item.ServiceName}
+		}
+	}
+	return nil
+}
+
+// respondInvoiceInputError maps invoice input errors to a 400 and anything else to a 500
+func respondInvoiceInputError(c *gin.Context, err error) {
+	var itemErr *invoiceInputError
+	if errors.As(err, &itemErr) {
+		utils.RespondWithError(c, http.StatusBadRequest, itemErr.Error())
+		return
+	}
+	utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+}
@@ -0,0 +1,254 @@
+// controllers/invoice_delivery.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceDeliveryController sends invoices and receipts to customers
+type InvoiceDeliveryController struct {
+	Mailer services.Mailer
+}
+
+// SendInvoiceInput optionally overrides the recipient address
+type SendInvoiceInput struct {
+	Email string `json:"email" binding:"omitempty,email"`
+}
+
+// SendInvoice emails the invoice (or receipt, once paid) to the customer
+func (dc *InvoiceDeliveryController) SendInvoice(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var input SendInvoiceInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+			return
+		}
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, invoice.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	recipient := strings.TrimSpace(customer.Email)
+	if input.Email != "" {
+		recipient = input.Email
+	}
+	if recipient == "" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer has no email address")
+		return
+	}
+
+	// Invoices are transactional, so only a transactional opt-out or a
+	// suppressed address stops them. An override address is checked too.
+	addressee := customer
+	addressee.Email = recipient
+	allowed, reason, err := services.CanMessage(config.DB, salon, addressee, "email", services.ConsentTransactional)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to check email consent")
+		return
+	}
+	if !allowed {
+		utils.RespondWithError(c, http.StatusConflict, "Cannot email this customer: "+reason)
+		return
+	}
+
+	doc := services.InvoiceDocument{Salon: salon, Customer: customer, Invoice: invoice}
+	body, err := services.RenderInvoiceHTML(doc)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to render invoice")
+		return
+	}
+
+	delivery := models.InvoiceDelivery{
+		ID:           uuid.New(),
+		SalonID:      salonUUID,
+		InvoiceID:    invoice.ID,
+		SentByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		Channel:      "email",
+		Recipient:    recipient,
+		DocumentType: strings.ToLower(doc.Title()),
+		Status:       "sent",
+	}
+
+	attachments := []services.MailAttachment{{
+		Filename:    doc.Filename(),
+		ContentType: "application/pdf",
+		Data:        services.RenderInvoicePDF(doc),
+	}}
+	if qr := doc.UPIQRCode(); qr != nil {
+		attachments = append(attachments, services.MailAttachment{
+			Filename:    "upi-qr.png",
+			ContentType: "image/png",
+			Data:        qr,
+			ContentID:   "upi-qr",
+		})
+	}
+
+	sendErr := dc.Mailer.Send(services.MailMessage{
+		From:        services.DefaultFromAddress(),
+		To:          recipient,
+		Subject:     doc.Title() + " " + invoice.InvoiceNumber + " from " + salon.Name,
+		HTMLBody:    body,
+		Attachments: attachments,
+	})
+	if sendErr != nil {
+		delivery.Status = "failed"
+		delivery.ErrorMessage = sendErr.Error()
+	}
+
+	if err := config.DB.Create(&delivery).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record delivery")
+		return
+	}
+
+	if sendErr != nil {
+		utils.RespondWithError(c, http.StatusBadGateway, "Failed to send email: "+sendErr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// GetInvoiceDeliveries lists every delivery attempt for an invoice
+func (dc *InvoiceDeliveryController) GetInvoiceDeliveries(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var deliveries []models.InvoiceDelivery
+	if err := config.DB.Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+		Order("attempted_at DESC").
+		Find(&deliveries).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// DownloadInvoicePDF returns the printable invoice document
+func (dc *InvoiceDeliveryController) DownloadInvoicePDF(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, invoice.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return
+	}
+
+	doc := services.InvoiceDocument{Salon: salon, Customer: customer, Invoice: invoice}
+	c.Header("Content-Disposition", "inline; filename=\""+doc.Filename()+"\"")
+	c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(doc))
+}
@@ -0,0 +1,235 @@
+// controllers/invoice_delivery_test.go
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// failingMailer rejects every message, like an SMTP server that is down
+type failingMailer struct{}
+
+func (failingMailer) Send(services.MailMessage) error {
+	return errors.New("connection refused")
+}
+
+// deliveryTestData is a salon with one customer and one unpaid invoice
+type deliveryTestData struct {
+	salon    models.Salon
+	customer models.Customer
+	invoice  models.Invoice
+	userID   uuid.UUID
+}
+
+func createDeliveryTestData(t *testing.T, db *gorm.DB, email string) deliveryTestData {
+	t.Helper()
+
+	d := deliveryTestData{userID: uuid.New()}
+	d.salon = models.Salon{ID: uuid.New(), Name: "Glow Salon", CurrencyCode: "INR", DecimalPlaces: 2, Locale: "en-IN"}
+	if err := db.Create(&d.salon).Error; err != nil {
+		t.Fatalf("Failed to create salon: %v", err)
+	}
+
+	d.customer = models.Customer{
+		ID:              uuid.New(),
+		SalonID:         d.salon.ID,
+		CreatedByUserID: d.userID,
+		Name:            "Asha Rao",
+		Phone:           "9876543210",
+		Email:           email,
+		IsActive:        true,
+	}
+	if err := db.Create(&d.customer).Error; err != nil {
+		t.Fatalf("Failed to create customer: %v", err)
+	}
+
+	d.invoice = models.Invoice{
+		ID:              uuid.New(),
+		SalonID:         d.salon.ID,
+		CreatedByUserID: d.userID,
+		InvoiceNumber:   "INV-" + uuid.NewString()[:8],
+		CustomerID:      d.customer.ID,
+		Subtotal:        1200,
+		Total:           1200,
+		PaymentStatus:   "unpaid",
+		Items: []models.InvoiceItem{{
+			ID:          uuid.New(),
+			ItemType:    "service",
+			ServiceName: "Haircut",
+			Quantity:    1,
+			UnitPrice:   1200,
+			TotalPrice:  1200,
+		}},
+	}
+	if err := db.Create(&d.invoice).Error; err != nil {
+		t.Fatalf("Failed to create invoice: %v", err)
+	}
+	return d
+}
+
+// sendTestInvoice calls SendInvoice for the invoice with an optional JSON body
+func sendTestInvoice(dc *InvoiceDeliveryController, d deliveryTestData, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/invoices/"+d.invoice.ID.String()+"/send", bytes.NewBufferString(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: d.invoice.ID.String()}}
+	c.Set("salonId", d.salon.ID.String())
+	c.Set("userId", d.userID.String())
+	dc.SendInvoice(c)
+	return w
+}
+
+func deliveryTestDB(t *testing.T) *gorm.DB {
+	db := testDB(t,
+		&models.Salon{},
+		&models.Customer{},
+		&models.Invoice{},
+		&models.InvoiceItem{},
+		&models.InvoicePayment{},
+		&models.InvoiceDelivery{},
+		&models.CustomerConsent{},
+		&models.MessageSuppression{},
+	)
+	gin.SetMode(gin.TestMode)
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+	return db
+}
+
+func TestSendInvoiceToCustomerEmail(t *testing.T) {
+	db := deliveryTestDB(t)
+	d := createDeliveryTestData(t, db, "asha@example.com")
+	mailer := &services.MemoryMailer{}
+	dc := &InvoiceDeliveryController{Mailer: mailer}
+
+	if w := sendTestInvoice(dc, d, ""); w.Code != http.StatusOK {
+		t.Fatalf("SendInvoice() = %d %s, want 200", w.Code, w.Body.String())
+	}
+
+	sent := mailer.Sent()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	msg := sent[0]
+	if msg.To != "asha@example.com" {
+		t.Errorf("To = %q, want the customer's email", msg.To)
+	}
+	if want := "Invoice " + d.invoice.InvoiceNumber + " from Glow Salon"; msg.Subject != want {
+		t.Errorf("Subject = %q, want %q", msg.Subject, want)
+	}
+	if !strings.Contains(msg.HTMLBody, "Asha Rao") || !strings.Contains(msg.HTMLBody, d.invoice.InvoiceNumber) {
+		t.Error("HTML body does not name the customer and invoice")
+	}
+	if len(msg.Attachments) != 1 {
+		t.Fatalf("got %d attachments, want the PDF only", len(msg.Attachments))
+	}
+	pdf := msg.Attachments[0]
+	if pdf.Filename != d.invoice.InvoiceNumber+".pdf" || pdf.ContentType != "application/pdf" || !bytes.HasPrefix(pdf.Data, []byte("%PDF-")) {
+		t.Errorf("attachment = %s %s, want the invoice PDF", pdf.Filename, pdf.ContentType)
+	}
+
+	var deliveries []models.InvoiceDelivery
+	db.Where("invoice_id = ?", d.invoice.ID).Find(&deliveries)
+	if len(deliveries) != 1 {
+		t.Fatalf("recorded %d deliveries, want 1", len(deliveries))
+	}
+	got := deliveries[0]
+	if got.Status != "sent" || got.Recipient != "asha@example.com" || got.Channel != "email" ||
+		got.DocumentType != "invoice" || got.SentByUserID != d.userID || got.SalonID != d.salon.ID {
+		t.Errorf("delivery = %+v", got)
+	}
+}
+
+func TestSendInvoiceRecipientOverride(t *testing.T) {
+	db := deliveryTestDB(t)
+	d := createDeliveryTestData(t, db, "")
+	mailer := &services.MemoryMailer{}
+	dc := &InvoiceDeliveryController{Mailer: mailer}
+
+	// No address on file and none given
+	if w := sendTestInvoice(dc, d, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("SendInvoice() without an address = %d, want 400", w.Code)
+	}
+	if len(mailer.Sent()) != 0 {
+		t.Error("a message was sent without an address")
+	}
+
+	if w := sendTestInvoice(dc, d, `{"email":"not-an-email"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("SendInvoice() with an invalid address = %d, want 400", w.Code)
+	}
+
+	if w := sendTestInvoice(dc, d, `{"email":"billing@example.com"}`); w.Code != http.StatusOK {
+		t.Fatalf("SendInvoice() = %d %s, want 200", w.Code, w.Body.String())
+	}
+	if sent := mailer.Sent(); len(sent) != 1 || sent[0].To != "billing@example.com" {
+		t.Errorf("sent %+v, want one message to the given address", sent)
+	}
+
+	var count int64
+	db.Model(&models.InvoiceDelivery{}).Where("invoice_id = ? AND recipient = ?", d.invoice.ID, "billing@example.com").Count(&count)
+	if count != 1 {
+		t.Errorf("recorded %d deliveries to the given address, want 1", count)
+	}
+}
+
+func TestSendInvoiceRecordsFailure(t *testing.T) {
+	db := deliveryTestDB(t)
+	d := createDeliveryTestData(t, db, "asha@example.com")
+	dc := &InvoiceDeliveryController{Mailer: failingMailer{}}
+
+	if w := sendTestInvoice(dc, d, ""); w.Code != http.StatusBadGateway {
+		t.Fatalf("SendInvoice() = %d, want 502", w.Code)
+	}
+
+	var delivery models.InvoiceDelivery
+	if err := db.Where("invoice_id = ?", d.invoice.ID).First(&delivery).Error; err != nil {
+		t.Fatalf("failed delivery was not recorded: %v", err)
+	}
+	if delivery.Status != "failed" || delivery.ErrorMessage != "connection refused" {
+		t.Errorf("delivery = %+v, want failed with the mailer's error", delivery)
+	}
+}
+
+func TestSendInvoiceRespectsOptOut(t *testing.T) {
+	db := deliveryTestDB(t)
+	d := createDeliveryTestData(t, db, "asha@example.com")
+	mailer := &services.MemoryMailer{}
+	dc := &InvoiceDeliveryController{Mailer: mailer}
+
+	consent := models.CustomerConsent{
+		SalonID:    d.salon.ID,
+		CustomerID: d.customer.ID,
+		Channel:    "email",
+		Purpose:    services.ConsentTransactional,
+		Granted:    false,
+		Source:     "in_store",
+	}
+	if err := services.RecordConsent(db, d.customer, &consent); err != nil {
+		t.Fatalf("RecordConsent() error = %v", err)
+	}
+
+	if w := sendTestInvoice(dc, d, ""); w.Code != http.StatusConflict {
+		t.Errorf("SendInvoice() after opt-out = %d, want 409", w.Code)
+	}
+	// The suppression follows the address, whoever it is typed in for
+	if w := sendTestInvoice(dc, d, `{"email":"ASHA@example.com"}`); w.Code != http.StatusConflict {
+		t.Errorf("SendInvoice() to the suppressed address = %d, want 409", w.Code)
+	}
+	if len(mailer.Sent()) != 0 {
+		t.Error("a message was sent to an opted-out customer")
+	}
+}
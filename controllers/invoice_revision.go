@@ -0,0 +1,245 @@
+// controllers/invoice_revision.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceRevisionView is one entry in an invoice's change history
+type InvoiceRevisionView struct {
+	ID          uuid.UUID               `json:"id"`
+	Revision    int                     `json:"revision"`
+	EditedBy    gin.H                   `json:"editedBy"`
+	EditedAt    time.Time               `json:"editedAt"`
+	Changes     models.FieldChangeList  `json:"changes"`
+	ItemChanges []RevisionItemChange    `json:"itemChanges"`
+	OldItems    models.RevisionItemList `json:"oldItems"`
+	NewItems    models.RevisionItemList `json:"newItems"`
+}
+
+// RevisionItemChange describes how one line differs between revisions
+type RevisionItemChange struct {
+	Change string               `json:"change"` // 'added', 'removed' or 'changed'
+	Name   string               `json:"name"`
+	Old    *models.RevisionItem `json:"old,omitempty"`
+	New    *models.RevisionItem `json:"new,omitempty"`
+}
+
+// GetInvoiceHistory lists every edit made to an invoice, newest first, with a
+// field-level diff. Owners and managers only.
+func GetInvoiceHistory(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can view invoice history")
+		return
+	}
+
+	// History outlives the invoice, so fall back to the void record for its number
+	var invoiceNumber string
+	var invoice models.Invoice
+	if err := config.DB.Select("invoice_number").
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err == nil {
+		invoiceNumber = invoice.InvoiceNumber
+	} else if errors.Is(err, gorm.ErrRecordNotFound) {
+		var void models.InvoiceVoid
+		if err := config.DB.Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+			First(&void).Error; err != nil {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+			return
+		}
+		invoiceNumber = void.InvoiceNumber
+	} else {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	var revisions []models.InvoiceRevision
+	if err := config.DB.Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch invoice history")
+		return
+	}
+
+	var editorIDs []uuid.UUID
+	for _, revision := range revisions {
+		editorIDs = append(editorIDs, revision.EditedByUserID)
+	}
+	editors := map[uuid.UUID]string{}
+	if len(editorIDs) > 0 {
+		var users []models.User
+		if err := config.DB.Select("id", "name").Where("id IN ?", editorIDs).Find(&users).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch invoice history")
+			return
+		}
+		for _, user := range users {
+			editors[user.ID] = user.Name
+		}
+	}
+
+	views := make([]InvoiceRevisionView, 0, len(revisions))
+	for _, revision := range revisions {
+		views = append(views, InvoiceRevisionView{
+			ID:          revision.ID,
+			Revision:    revision.Revision,
+			EditedBy:    gin.H{"id": revision.EditedByUserID, "name": editors[revision.EditedByUserID]},
+			EditedAt:    revision.CreatedAt,
+			Changes:     revision.Changes,
+			ItemChanges: diffRevisionItems(revision.OldItems, revision.NewItems),
+			OldItems:    revision.OldItems,
+			NewItems:    revision.NewItems,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoiceId":     invoiceUUID,
+		"invoiceNumber": invoiceNumber,
+		"revisions":     views,
+	})
+}
+
+// recordInvoiceRevision stores what an update changed on the invoice. Updates
+// that change nothing are not recorded.
+func recordInvoiceRevision(tx *gorm.DB, before, after models.Invoice, userID uuid.UUID) error {
+	changes := invoiceFieldChanges(before, after)
+	oldItems := models.RevisionItemsFrom(before.Items)
+	newItems := models.RevisionItemsFrom(after.Items)
+	if len(changes) == 0 && len(diffRevisionItems(oldItems, newItems)) == 0 {
+		return nil
+	}
+
+	var latest int
+	if err := tx.Model(&models.InvoiceRevision{}).
+		Where("invoice_id = ?", after.ID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.InvoiceRevision{
+		ID:             uuid.New(),
+		SalonID:        after.SalonID,
+		InvoiceID:      after.ID,
+		Revision:       latest + 1,
+		EditedByUserID: userID,
+		Changes:        changes,
+		OldItems:       oldItems,
+		NewItems:       newItems,
+	}).Error
+}
+
+// invoiceFieldChanges lists the header fields that differ between two versions
+func invoiceFieldChanges(before, after models.Invoice) models.FieldChangeList {
+	changes := models.FieldChangeList{}
+	add := func(field string, old, new interface{}) {
+		changes = append(changes, models.FieldChange{Field: field, Old: old, New: new})
+	}
+	money := func(field string, old, new float64) {
+		if math.Abs(old-new) >= 0.005 {
+			add(field, old, new)
+		}
+	}
+
+	if before.CustomerID != after.CustomerID {
+		add("customerId", before.CustomerID, after.CustomerID)
+	}
+	if !before.InvoiceDate.Equal(after.InvoiceDate) {
+		add("invoiceDate", before.InvoiceDate, after.InvoiceDate)
+	}
+	money("subtotal", before.Subtotal, after.Subtotal)
+	money("discount", before.Discount, after.Discount)
+	money("tax", before.Tax, after.Tax)
+	money("total", before.Total, after.Total)
+	if before.PaymentStatus != after.PaymentStatus {
+		add("paymentStatus", before.PaymentStatus, after.PaymentStatus)
+	}
+	money("paidAmount", before.PaidAmount, after.PaidAmount)
+	if before.PaymentMethod != after.PaymentMethod {
+		add("paymentMethod", before.PaymentMethod, after.PaymentMethod)
+	}
+	if before.Notes != after.Notes {
+		add("notes", before.Notes, after.Notes)
+	}
+	return changes
+}
+
+// diffRevisionItems pairs lines by service or product and reports what was
+// added, removed or repriced
+func diffRevisionItems(oldItems, newItems models.RevisionItemList) []RevisionItemChange {
+	unmatched := map[string][]models.RevisionItem{}
+	for _, item := range oldItems {
+		unmatched[item.Key()] = append(unmatched[item.Key()], item)
+	}
+
+	changes := []RevisionItemChange{}
+	for _, item := range newItems {
+		item := item
+		candidates := unmatched[item.Key()]
+		if len(candidates) == 0 {
+			changes = append(changes, RevisionItemChange{Change: "added", Name: item.Name, New: &item})
+			continue
+		}
+		old := candidates[0]
+		unmatched[item.Key()] = candidates[1:]
+		if old.Quantity != item.Quantity ||
+			math.Abs(old.UnitPrice-item.UnitPrice) >= 0.005 ||
+			math.Abs(old.DiscountAmount-item.DiscountAmount) >= 0.005 ||
+			math.Abs(old.TotalPrice-item.TotalPrice) >= 0.005 ||
+			math.Abs(old.TaxAmount-item.TaxAmount) >= 0.005 {
+			changes = append(changes, RevisionItemChange{Change: "changed", Name: item.Name, Old: &old, New: &item})
+		}
+	}
+
+	// Report removals in their original order
+	for _, item := range oldItems {
+		candidates := unmatched[item.Key()]
+		if len(candidates) == 0 {
+			continue
+		}
+		removed := candidates[0]
+		unmatched[item.Key()] = candidates[1:]
+		changes = append(changes, RevisionItemChange{Change: "removed", Name: removed.Name, Old: &removed})
+	}
+	return changes
+}
@@ -0,0 +1,96 @@
+// controllers/invoice_revision_test.go
+package controllers
+
+import (
+	"salonpro-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestInvoiceFieldChanges(t *testing.T) {
+	date := time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)
+	base := models.Invoice{
+		CustomerID:    uuid.New(),
+		InvoiceDate:   date,
+		Subtotal:      1000,
+		Tax:           180,
+		Total:         1180,
+		PaymentStatus: "unpaid",
+	}
+
+	tests := []struct {
+		name   string
+		edit   func(*models.Invoice)
+		fields []string
+	}{
+		{"no change", func(i *models.Invoice) {}, nil},
+		{"same date in another zone", func(i *models.Invoice) { i.InvoiceDate = date.In(time.FixedZone("IST", 19800)) }, nil},
+		{"rounding noise", func(i *models.Invoice) { i.Total = 1180.001 }, nil},
+		{"customer", func(i *models.Invoice) { i.CustomerID = uuid.New() }, []string{"customerId"}},
+		{"discount", func(i *models.Invoice) {
+			i.Discount, i.Tax, i.Total = 100, 162, 1062
+		}, []string{"discount", "tax", "total"}},
+		{"payment", func(i *models.Invoice) {
+			i.PaidAmount, i.PaymentStatus, i.PaymentMethod = 1180, "paid", "cash"
+		}, []string{"paymentStatus", "paidAmount", "paymentMethod"}},
+		{"notes", func(i *models.Invoice) { i.Notes = "Walk-in" }, []string{"notes"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := base
+			tt.edit(&after)
+			var got []string
+			for _, change := range invoiceFieldChanges(base, after) {
+				got = append(got, change.Field)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+				t.Errorf("invoiceFieldChanges() fields = %v, want %v", got, tt.fields)
+			}
+		})
+	}
+}
+
+func TestDiffRevisionItems(t *testing.T) {
+	haircut, colour, shampoo := uuid.New(), uuid.New(), uuid.New()
+	item := func(id uuid.UUID, name string, quantity int, price float64) models.RevisionItem {
+		return models.RevisionItem{ItemType: "service", ServiceID: &id, Name: name, Quantity: quantity, UnitPrice: price, TotalPrice: price * float64(quantity)}
+	}
+
+	tests := []struct {
+		name     string
+		old, new models.RevisionItemList
+		want     []string // change:name
+	}{
+		{"unchanged", models.RevisionItemList{item(haircut, "Haircut", 1, 500)}, models.RevisionItemList{item(haircut, "Haircut", 1, 500)}, nil},
+		{"renamed service is the same line", models.RevisionItemList{item(haircut, "Haircut", 1, 500)}, models.RevisionItemList{item(haircut, "Men's haircut", 1, 500)}, nil},
+		{"added", models.RevisionItemList{item(haircut, "Haircut", 1, 500)},
+			models.RevisionItemList{item(haircut, "Haircut", 1, 500), item(colour, "Colour", 1, 2000)},
+			[]string{"added:Colour"}},
+		{"removed", models.RevisionItemList{item(haircut, "Haircut", 1, 500), item(colour, "Colour", 1, 2000)},
+			models.RevisionItemList{item(colour, "Colour", 1, 2000)},
+			[]string{"removed:Haircut"}},
+		{"repriced", models.RevisionItemList{item(colour, "Colour", 1, 2000)}, models.RevisionItemList{item(colour, "Colour", 1, 1800)},
+			[]string{"changed:Colour"}},
+		{"quantity", models.RevisionItemList{item(shampoo, "Shampoo", 1, 200)}, models.RevisionItemList{item(shampoo, "Shampoo", 2, 200)},
+			[]string{"changed:Shampoo"}},
+		{"one of two repeated lines removed", models.RevisionItemList{item(haircut, "Haircut", 1, 500), item(haircut, "Haircut", 1, 500)},
+			models.RevisionItemList{item(haircut, "Haircut", 1, 500)},
+			[]string{"removed:Haircut"}},
+		{"swapped", models.RevisionItemList{item(haircut, "Haircut", 1, 500)}, models.RevisionItemList{item(colour, "Colour", 1, 2000)},
+			[]string{"added:Colour", "removed:Haircut"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range diffRevisionItems(tt.old, tt.new) {
+				got = append(got, change.Change+":"+change.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("diffRevisionItems() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
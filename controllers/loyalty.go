@@ -0,0 +1,560 @@
+// controllers/loyalty.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UpdateLoyaltyProgramInput defines the expected JSON structure for the salon's loyalty rules
+type UpdateLoyaltyProgramInput struct {
+	IsEnabled           *bool               `json:"isEnabled"`
+	SpendPerPoint       *float64            `json:"spendPerPoint" binding:"omitempty,gt=0"`
+	CategoryMultipliers *map[string]float64 `json:"categoryMultipliers"`
+	BirthdayMultiplier  *float64            `json:"birthdayMultiplier" binding:"omitempty,min=1"`
+	PointValue          *float64            `json:"pointValue" binding:"omitempty,gt=0"`
+	MinRedeemPoints     *int                `json:"minRedeemPoints" binding:"omitempty,min=0"`
+	ExpiryMonths        *int                `json:"expiryMonths" binding:"omitempty,min=0"`
+}
+
+// AdjustLoyaltyPointsInput defines a manual correction to a customer's points
+type AdjustLoyaltyPointsInput struct {
+	Points int    `json:"points" binding:"required"` // positive to add, negative to remove
+	Note   string `json:"note" binding:"required"`
+}
+
+// GetLoyaltyProgram returns the salon's loyalty rules
+func GetLoyaltyProgram(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	program, err := loadLoyaltyProgram(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty program")
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// UpdateLoyaltyProgram creates or updates the salon's loyalty rules
+func UpdateLoyaltyProgram(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input UpdateLoyaltyProgramInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	program, err := loadLoyaltyProgram(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty program")
+		return
+	}
+
+	// Update fields if provided
+	if input.IsEnabled != nil {
+		program.IsEnabled = *input.IsEnabled
+	}
+	if input.SpendPerPoint != nil {
+		program.SpendPerPoint = *input.SpendPerPoint
+	}
+	if input.CategoryMultipliers != nil {
+		for category, multiplier := range *input.CategoryMultipliers {
+			if multiplier < 0 {
+				utils.RespondWithError(c, http.StatusBadRequest, "Multiplier for "+category+" cannot be negative")
+				return
+			}
+		}
+		program.CategoryMultipliers = models.FloatMap(*input.CategoryMultipliers)
+	}
+	if input.BirthdayMultiplier != nil {
+		program.BirthdayMultiplier = *input.BirthdayMultiplier
+	}
+	if input.PointValue != nil {
+		program.PointValue = *input.PointValue
+	}
+	if input.MinRedeemPoints != nil {
+		program.MinRedeemPoints = *input.MinRedeemPoints
+	}
+	if input.ExpiryMonths != nil {
+		program.ExpiryMonths = *input.ExpiryMonths
+	}
+
+	if err := config.DB.Save(&program).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update loyalty program")
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// GetCustomerLoyalty returns a customer's points balance and ledger, newest first
+func GetCustomerLoyalty(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	program, err := loadLoyaltyProgram(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty program")
+		return
+	}
+
+	var transactions []models.LoyaltyTransaction
+	if err := config.DB.Where("customer_id = ?", customer.ID).
+		Order("created_at DESC").
+		Find(&transactions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty history")
+		return
+	}
+
+	// Points that will lapse in the next 30 days unless redeemed
+	var expiringSoon int
+	config.DB.Model(&models.LoyaltyTransaction{}).
+		Where("customer_id = ? AND remaining > 0 AND expires_at <= ?", customer.ID, time.Now().AddDate(0, 0, 30)).
+		Select("COALESCE(SUM(remaining), 0)").
+		Scan(&expiringSoon)
+
+	c.JSON(http.StatusOK, gin.H{
+		"balance":      customer.LoyaltyPoints,
+		"value":        float64(customer.LoyaltyPoints) * program.PointValue,
+		"expiringSoon": expiringSoon,
+		"transactions": transactions,
+	})
+}
+
+// AdjustCustomerLoyalty manually adds or removes points, e.g. as a goodwill gesture
+func AdjustCustomerLoyalty(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var input AdjustLoyaltyPointsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can adjust loyalty points")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	program, err := loadLoyaltyProgram(config.DB, salonUUID)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve loyalty program")
+		return
+	}
+
+	userUUID := currentUser.ID
+	entry := models.LoyaltyTransaction{
+		SalonID:         salonUUID,
+		CustomerID:      customer.ID,
+		CreatedByUserID: &userUUID,
+		Type:            "adjust",
+		Note:            input.Note,
+	}
+
+	var transaction models.LoyaltyTransaction
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if input.Points > 0 {
+			entry.Points = input.Points
+			entry.ExpiresAt = services.LoyaltyExpiry(program, time.Now())
+			transaction, err = services.CreditLoyaltyPoints(tx, entry)
+		} else {
+			entry.Points = -input.Points
+			transaction, err = services.DebitLoyaltyPoints(tx, entry)
+		}
+		return err
+	})
+	if errors.Is(err, services.ErrInsufficientPoints) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Customer does not have that many points")
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to adjust loyalty points")
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// loadLoyaltyProgram returns the salon's loyalty rules, or disabled defaults if none are saved
+func loadLoyaltyProgram(db *gorm.DB, salonID uuid.UUID) (models.LoyaltyProgram, error) {
+	var program models.LoyaltyProgram
+	err := db.Where("salon_id = ?", salonID).First(&program).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoyaltyProgram{
+			ID:                  uuid.New(),
+			SalonID:             salonID,
+			SpendPerPoint:       100,
+			CategoryMultipliers: models.FloatMap{},
+			BirthdayMultiplier:  1,
+			PointValue:          1,
+		}, nil
+	}
+	return program, err
+}
+
+// loyaltyPointsFor works out the points a paid invoice earns. Each line earns on
+// its share of the discounted subtotal times its category multiplier; tax, gift
+// card sales and the part paid with points earn nothing.
+func loyaltyPointsFor(program models.LoyaltyProgram, customer models.Customer, invoice models.Invoice, pointsPaid float64) int {
+	if invoice.Subtotal <= 0 || invoice.Total <= 0 || program.SpendPerPoint <= 0 {
+		return 0
+	}
+
+	var weighted float64
+	for _, item := range invoice.Items {
+		if item.ItemType == "gift_card" {
+			continue
+		}
+		multiplier := 1.0
+		if m, ok := program.CategoryMultipliers[item.Category]; ok {
+			multiplier = m
+		}
+		weighted += item.TotalPrice * multiplier
+	}
+
+	weighted *= (invoice.Subtotal - invoice.Discount) / invoice.Subtotal
+	weighted *= 1 - pointsPaid/invoice.Total
+
+	if customer.Birthday != nil && customer.Birthday.Month() == invoice.InvoiceDate.Month() && program.BirthdayMultiplier > 1 {
+		weighted *= program.BirthdayMultiplier
+	}
+
+	points := int(math.Floor(weighted / program.SpendPerPoint))
+	if points < 0 {
+		return 0
+	}
+	return points
+}
+
+// awardLoyaltyPoints credits the points for a fully paid invoice, once
+func awardLoyaltyPoints(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	if invoice.PaymentStatus != "paid" {
+		return nil
+	}
+
+	program, err := loadLoyaltyProgram(tx, invoice.SalonID)
+	if err != nil || !program.IsEnabled {
+		return err
+	}
+
+	var earned int64
+	if err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("invoice_id = ? AND type = 'earn'", invoice.ID).
+		Count(&earned).Error; err != nil {
+		return err
+	}
+	if earned > 0 {
+		return nil
+	}
+
+	points, err := invoiceLoyaltyPoints(tx, program, invoice)
+	if err != nil || points <= 0 {
+		return err
+	}
+
+	_, err = services.CreditLoyaltyPoints(tx, models.LoyaltyTransaction{
+		SalonID:         invoice.SalonID,
+		CustomerID:      invoice.CustomerID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: &userID,
+		Type:            "earn",
+		Points:          points,
+		Note:            "Earned on " + invoice.InvoiceNumber,
+		ExpiresAt:       services.LoyaltyExpiry(program, time.Now()),
+	})
+	return err
+}
+
+// syncLoyaltyPoints keeps an invoice's points in step after it is edited. An
+// invoice that is now paid earns its points; one that already earned gains or
+// loses the difference its new lines, discount or payments make (as far as the
+// balance allows).
+func syncLoyaltyPoints(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	var earned int64
+	if err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("invoice_id = ? AND type = 'earn'", invoice.ID).
+		Count(&earned).Error; err != nil {
+		return err
+	}
+	if earned == 0 {
+		return awardLoyaltyPoints(tx, invoice, userID)
+	}
+	if invoice.PaymentStatus != "paid" {
+		return nil
+	}
+
+	program, err := loadLoyaltyProgram(tx, invoice.SalonID)
+	if err != nil || !program.IsEnabled {
+		return err
+	}
+
+	points, err := invoiceLoyaltyPoints(tx, program, invoice)
+	if err != nil {
+		return err
+	}
+	held, err := invoiceEarnedPoints(tx, invoice.ID)
+	if err != nil {
+		return err
+	}
+
+	entry := models.LoyaltyTransaction{
+		SalonID:         invoice.SalonID,
+		CustomerID:      invoice.CustomerID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: &userID,
+		Type:            "adjust",
+		Note:            "Adjusted: invoice " + invoice.InvoiceNumber + " edited",
+	}
+	switch diff := points - held; {
+	case diff > 0:
+		entry.Points = diff
+		entry.ExpiresAt = services.LoyaltyExpiry(program, time.Now())
+		_, err = services.CreditLoyaltyPoints(tx, entry)
+	case diff < 0:
+		var customer models.Customer
+		if err := tx.Select("loyalty_points").First(&customer, "id = ?", invoice.CustomerID).Error; err != nil {
+			return err
+		}
+		entry.Points = min(-diff, customer.LoyaltyPoints)
+		if entry.Points > 0 {
+			_, err = services.DebitLoyaltyPoints(tx, entry)
+		}
+	}
+	return err
+}
+
+// invoiceLoyaltyPoints works out what a paid invoice earns under the program,
+// loading its lines if they aren't already
+func invoiceLoyaltyPoints(tx *gorm.DB, program models.LoyaltyProgram, invoice models.Invoice) (int, error) {
+	var customer models.Customer
+	if err := tx.First(&customer, "id = ?", invoice.CustomerID).Error; err != nil {
+		return 0, err
+	}
+
+	if invoice.Items == nil {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Find(&invoice.Items).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	var pointsPaid float64
+	if err := tx.Model(&models.InvoicePayment{}).
+		Where("invoice_id = ? AND method = 'points'", invoice.ID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&pointsPaid).Error; err != nil {
+		return 0, err
+	}
+
+	return loyaltyPointsFor(program, customer, invoice, pointsPaid), nil
+}
+
+// invoiceEarnedPoints is what an invoice has earned so far: its earn entry plus
+// the adjustments made when it was edited
+func invoiceEarnedPoints(tx *gorm.DB, invoiceID uuid.UUID) (int, error) {
+	var held int
+	err := tx.Model(&models.LoyaltyTransaction{}).
+		Where("invoice_id = ? AND type IN ('earn', 'adjust')", invoiceID).
+		Select("COALESCE(SUM(points), 0)").
+		Scan(&held).Error
+	return held, err
+}
+
+// applyLoyaltyRedemption pays part of the invoice with the customer's points
+func applyLoyaltyRedemption(tx *gorm.DB, invoice *models.Invoice, userID uuid.UUID, points int) error {
+	program, err := loadLoyaltyProgram(tx, invoice.SalonID)
+	if err != nil {
+		return err
+	}
+	if !program.IsEnabled {
+		return &invoiceInputError{"Loyalty points are not enabled for this salon"}
+	}
+	if points < program.MinRedeemPoints {
+		return &invoiceInputError{"At least " + strconv.Itoa(program.MinRedeemPoints) + " points must be redeemed"}
+	}
+
+	value := math.Round(float64(points)*program.PointValue*100) / 100
+	if value > invoice.Total-invoice.PaidAmount+0.005 {
+		return &invoiceInputError{"Points value exceeds the amount due"}
+	}
+
+	_, err = services.DebitLoyaltyPoints(tx, models.LoyaltyTransaction{
+		SalonID:         invoice.SalonID,
+		CustomerID:      invoice.CustomerID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: &userID,
+		Type:            "redeem",
+		Points:          points,
+		Note:            "Redeemed on " + invoice.InvoiceNumber,
+	})
+	if errors.Is(err, services.ErrInsufficientPoints) {
+		return &invoiceInputError{"Customer does not have enough loyalty points"}
+	}
+	if err != nil {
+		return err
+	}
+
+	return recordTenderPayment(tx, invoice, models.InvoicePayment{
+		CreatedByUserID: userID,
+		Method:          "points",
+		Amount:          value,
+		Reference:       strconv.Itoa(points) + " points",
+	})
+}
+
+// reverseLoyaltyActivity takes back points earned on an invoice being deleted,
+// net of edits (as far as the balance allows), and returns points redeemed on it
+func reverseLoyaltyActivity(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	var entries []models.LoyaltyTransaction
+	if err := tx.Where("invoice_id = ? AND type IN ('earn', 'redeem')", invoice.ID).
+		Find(&entries).Error; err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	held, err := invoiceEarnedPoints(tx, invoice.ID)
+	if err != nil {
+		return err
+	}
+
+	program, err := loadLoyaltyProgram(tx, invoice.SalonID)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		reversal := models.LoyaltyTransaction{
+			SalonID:         invoice.SalonID,
+			CustomerID:      invoice.CustomerID,
+			InvoiceID:       &invoice.ID,
+			CreatedByUserID: &userID,
+			Type:            "adjust",
+			Note:            "Reversed: invoice " + invoice.InvoiceNumber + " deleted",
+		}
+
+		if entry.Type == "redeem" {
+			reversal.Points = -entry.Points
+			reversal.ExpiresAt = services.LoyaltyExpiry(program, time.Now())
+			if _, err := services.CreditLoyaltyPoints(tx, reversal); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var customer models.Customer
+		if err := tx.Select("loyalty_points").First(&customer, "id = ?", invoice.CustomerID).Error; err != nil {
+			return err
+		}
+		reversal.Points = held
+		if reversal.Points > customer.LoyaltyPoints {
+			reversal.Points = customer.LoyaltyPoints
+		}
+		if reversal.Points <= 0 {
+			continue
+		}
+		if _, err := services.DebitLoyaltyPoints(tx, reversal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
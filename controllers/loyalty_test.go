@@ -0,0 +1,66 @@
+// controllers/loyalty_test.go
+package controllers
+
+import (
+	"salonpro-backend/models"
+	"testing"
+	"time"
+)
+
+func TestLoyaltyPointsFor(t *testing.T) {
+	may := time.Date(2026, 5, 14, 12, 0, 0, 0, time.UTC)
+	mayBirthday := time.Date(1990, 5, 2, 0, 0, 0, 0, time.UTC)
+	juneBirthday := time.Date(1990, 6, 2, 0, 0, 0, 0, time.UTC)
+
+	program := models.LoyaltyProgram{
+		SpendPerPoint:       100,
+		CategoryMultipliers: models.FloatMap{"Colour": 2, "Retail": 0.5},
+		BirthdayMultiplier:  2,
+	}
+	service := func(category string, price float64) models.InvoiceItem {
+		return models.InvoiceItem{ItemType: "service", Category: category, TotalPrice: price}
+	}
+	invoice := func(discount, tax float64, items ...models.InvoiceItem) models.Invoice {
+		var subtotal float64
+		for _, item := range items {
+			subtotal += item.TotalPrice
+		}
+		return models.Invoice{
+			InvoiceDate: may,
+			Subtotal:    subtotal,
+			Discount:    discount,
+			Total:       subtotal - discount + tax,
+			Items:       items,
+		}
+	}
+
+	tests := []struct {
+		name       string
+		program    models.LoyaltyProgram
+		birthday   *time.Time
+		invoice    models.Invoice
+		pointsPaid float64
+		want       int
+	}{
+		{"one point per 100", program, nil, invoice(0, 0, service("Hair", 1050)), 0, 10},
+		{"tax earns nothing", program, nil, invoice(0, 180, service("Hair", 1000)), 0, 10},
+		{"category multiplier", program, nil, invoice(0, 0, service("Colour", 1000), service("Retail", 400)), 0, 22},
+		{"discount shared across lines", program, nil, invoice(500, 0, service("Hair", 1000), service("Colour", 1000)), 0, 22},
+		{"gift card sales earn nothing", program, nil, invoice(0, 0,
+			models.InvoiceItem{ItemType: "gift_card", TotalPrice: 2000}, service("Hair", 500)), 0, 5},
+		{"part paid with points", program, nil, invoice(0, 0, service("Hair", 1000)), 250, 7},
+		{"birthday month", program, &mayBirthday, invoice(0, 0, service("Hair", 1000)), 0, 20},
+		{"not birthday month", program, &juneBirthday, invoice(0, 0, service("Hair", 1000)), 0, 10},
+		{"paid entirely with points", program, nil, invoice(0, 0, service("Hair", 1000)), 1000, 0},
+		{"nothing to earn on", program, nil, invoice(0, 0), 0, 0},
+		{"no earning rate", models.LoyaltyProgram{}, nil, invoice(0, 0, service("Hair", 1000)), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer := models.Customer{Birthday: tt.birthday}
+			if got := loyaltyPointsFor(tt.program, customer, tt.invoice, tt.pointsPaid); got != tt.want {
+				t.Errorf("loyaltyPointsFor() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,608 @@
+// controllers/membership.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateMembershipPlanInput defines the expected JSON structure for creating a membership plan
+type CreateMembershipPlanInput struct {
+	Name                 string   `json:"name" binding:"required"`
+	Description          string   `json:"description"`
+	Fee                  float64  `json:"fee" binding:"required,min=0"`
+	DurationMonths       int      `json:"durationMonths" binding:"required,min=1"`
+	DiscountPercent      float64  `json:"discountPercent" binding:"min=0,max=100"`
+	FreeServicesPerMonth int      `json:"freeServicesPerMonth" binding:"min=0"`
+	FreeServiceIDs       []string `json:"freeServiceIds"`
+	PriorityBooking      bool     `json:"priorityBooking"`
+}
+
+// UpdateMembershipPlanInput defines the expected JSON structure for updating a membership plan.
+// Changes apply to current members from their next invoice.
+type UpdateMembershipPlanInput struct {
+	Name                 *string   `json:"name"`
+	Description          *string   `json:"description"`
+	Fee                  *float64  `json:"fee" binding:"omitempty,min=0"`
+	DurationMonths       *int      `json:"durationMonths" binding:"omitempty,min=1"`
+	DiscountPercent      *float64  `json:"discountPercent" binding:"omitempty,min=0,max=100"`
+	FreeServicesPerMonth *int      `json:"freeServicesPerMonth" binding:"omitempty,min=0"`
+	FreeServiceIDs       *[]string `json:"freeServiceIds"`
+	PriorityBooking      *bool     `json:"priorityBooking"`
+	IsActive             *bool     `json:"isActive"`
+}
+
+// SellMembershipInput defines the expected JSON structure for selling a plan to a customer
+type SellMembershipInput struct {
+	CustomerID    uuid.UUID  `json:"customerId" binding:"required"`
+	PlanID        uuid.UUID  `json:"planId" binding:"required"`
+	StartDate     *time.Time `json:"startDate"`
+	Tax           float64    `json:"tax" binding:"min=0"`
+	PaymentMethod string     `json:"paymentMethod" binding:"required"`
+}
+
+// RenewMembershipInput defines the expected JSON structure for renewing a membership
+type RenewMembershipInput struct {
+	Tax           float64 `json:"tax" binding:"min=0"`
+	PaymentMethod string  `json:"paymentMethod" binding:"required"`
+}
+
+// CreateMembershipPlan creates a new membership plan for the salon
+func CreateMembershipPlan(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input CreateMembershipPlanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	plan := models.MembershipPlan{
+		ID:                   uuid.New(),
+		SalonID:              salonUUID,
+		Name:                 input.Name,
+		Description:          input.Description,
+		Fee:                  input.Fee,
+		DurationMonths:       input.DurationMonths,
+		DiscountPercent:      input.DiscountPercent,
+		FreeServicesPerMonth: input.FreeServicesPerMonth,
+		FreeServiceIDs:       models.StringList(input.FreeServiceIDs),
+		PriorityBooking:      input.PriorityBooking,
+		IsActive:             true,
+	}
+
+	if err := config.DB.Create(&plan).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create membership plan")
+		return
+	}
+
+	c.JSON(http.StatusCreated, plan)
+}
+
+// GetMembershipPlans retrieves all membership plans for the salon
+func GetMembershipPlans(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var plans []models.MembershipPlan
+	if err := config.DB.Where("salon_id = ?", salonUUID).Order("fee").Find(&plans).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve membership plans")
+		return
+	}
+
+	c.JSON(http.StatusOK, plans)
+}
+
+// UpdateMembershipPlan updates an existing membership plan
+func UpdateMembershipPlan(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	planUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid plan ID format")
+		return
+	}
+
+	var input UpdateMembershipPlanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var plan models.MembershipPlan
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, planUUID).
+		First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Membership plan not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Update fields if provided
+	if input.Name != nil {
+		plan.Name = *input.Name
+	}
+	if input.Description != nil {
+		plan.Description = *input.Description
+	}
+	if input.Fee != nil {
+		plan.Fee = *input.Fee
+	}
+	if input.DurationMonths != nil {
+		plan.DurationMonths = *input.DurationMonths
+	}
+	if input.DiscountPercent != nil {
+		plan.DiscountPercent = *input.DiscountPercent
+	}
+	if input.FreeServicesPerMonth != nil {
+		plan.FreeServicesPerMonth = *input.FreeServicesPerMonth
+	}
+	if input.FreeServiceIDs != nil {
+		plan.FreeServiceIDs = models.StringList(*input.FreeServiceIDs)
+	}
+	if input.PriorityBooking != nil {
+		plan.PriorityBooking = *input.PriorityBooking
+	}
+	if input.IsActive != nil {
+		plan.IsActive = *input.IsActive
+	}
+
+	if err := config.DB.Save(&plan).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update membership plan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// SellMembership sells a plan to a customer and bills the fee on its own invoice
+func SellMembership(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input SellMembershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var plan models.MembershipPlan
+	if err := config.DB.Where("salon_id = ? AND id = ? AND is_active = true", salonUUID, input.PlanID).
+		First(&plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Membership plan not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	now := time.Now()
+	startDate := utils.BeginningOfDay(now)
+	if input.StartDate != nil {
+		startDate = utils.BeginningOfDay(*input.StartDate)
+	}
+
+	// Only one membership may cover any given day
+	var overlapping int64
+	if err := config.DB.Model(&models.CustomerMembership{}).
+		Where("customer_id = ? AND status = 'active' AND end_date > ?", customer.ID, startDate).
+		Count(&overlapping).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if overlapping > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Customer already has an active membership; renew it instead")
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	membership, invoice, err := createMembershipSale(tx, customer, plan, uuid.Must(uuid.Parse(userID.(string))),
+		startDate, input.Tax, input.PaymentMethod, nil)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to sell membership")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"membership": membership,
+		"invoice":    invoice,
+	})
+}
+
+// RenewMembership sells the same plan again, starting when the current period ends
+func RenewMembership(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	membershipUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid membership ID format")
+		return
+	}
+
+	var input RenewMembershipInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var current models.CustomerMembership
+	if err := config.DB.Preload("Plan").
+		Where("salon_id = ? AND id = ?", salonUUID, membershipUUID).
+		First(&current).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Membership not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if current.Status != "active" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Cancelled memberships cannot be renewed")
+		return
+	}
+	if !current.Plan.IsActive {
+		utils.RespondWithError(c, http.StatusBadRequest, "Membership plan is no longer offered")
+		return
+	}
+
+	var renewals int64
+	config.DB.Model(&models.CustomerMembership{}).
+		Where("renewed_from_id = ? AND status = 'active'", current.ID).
+		Count(&renewals)
+	if renewals > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Membership has already been renewed")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, current.CustomerID).
+		First(&customer).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Customer not found")
+		return
+	}
+
+	// Renewing early extends from the current end date; a lapsed membership restarts today
+	startDate := current.EndDate
+	if today := utils.BeginningOfDay(time.Now()); startDate.Before(today) {
+		startDate = today
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	membership, invoice, err := createMembershipSale(tx, customer, current.Plan, uuid.Must(uuid.Parse(userID.(string))),
+		startDate, input.Tax, input.PaymentMethod, &current.ID)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to renew membership")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"membership": membership,
+		"invoice":    invoice,
+	})
+}
+
+// CancelMembership stops a membership's benefits immediately
+func CancelMembership(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	membershipUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid membership ID format")
+		return
+	}
+
+	now := time.Now()
+	result := config.DB.Model(&models.CustomerMembership{}).
+		Where("salon_id = ? AND id = ? AND status = 'active'", salonUUID, membershipUUID).
+		Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": now,
+		})
+
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to cancel membership")
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Active membership not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Membership cancelled successfully"})
+}
+
+// GetCustomerMemberships lists a customer's memberships, newest first, with the current one highlighted
+func GetCustomerMemberships(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var memberships []models.CustomerMembership
+	if err := config.DB.Preload("Plan").
+		Where("salon_id = ? AND customer_id = ?", salonUUID, customerUUID).
+		Order("start_date DESC").
+		Find(&memberships).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve memberships")
+		return
+	}
+
+	var current *models.CustomerMembership
+	freeServicesLeft := 0
+	now := time.Now()
+	for i := range memberships {
+		if memberships[i].IsCurrent(now) {
+			current = &memberships[i]
+			used, err := countFreeServicesUsed(config.DB, current.ID, now)
+			if err != nil {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+				return
+			}
+			freeServicesLeft = current.Plan.FreeServicesPerMonth - used
+			if freeServicesLeft < 0 {
+				freeServicesLeft = 0
+			}
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"current":          current,
+		"isPriority":       current != nil && current.Plan.PriorityBooking,
+		"freeServicesLeft": freeServicesLeft,
+		"memberships":      memberships,
+	})
+}
+
+// createMembershipSale creates the membership, its invoice and payment inside tx
+func createMembershipSale(tx *gorm.DB, customer models.Customer, plan models.MembershipPlan, userID uuid.UUID,
+	startDate time.Time, tax float64, paymentMethod string, renewedFrom *uuid.UUID) (models.CustomerMembership, models.Invoice, error) {
+
+	membershipID := uuid.New()
+
+	description := "Membership: " + plan.Name
+	if renewedFrom != nil {
+		description = "Membership renewal: " + plan.Name
+	}
+
+	invoice, err := createSaleInvoice(tx, customer, userID, models.InvoiceItem{
+		ItemType:     "membership",
+		MembershipID: &membershipID,
+		ServiceName:  description,
+		Quantity:     1,
+		UnitPrice:    plan.Fee,
+		TotalPrice:   plan.Fee,
+	}, tax, paymentMethod)
+	if err != nil {
+		return models.CustomerMembership{}, invoice, err
+	}
+
+	membership := models.CustomerMembership{
+		ID:              membershipID,
+		SalonID:         customer.SalonID,
+		CustomerID:      customer.ID,
+		PlanID:          plan.ID,
+		SoldInvoiceID:   invoice.ID,
+		CreatedByUserID: userID,
+		RenewedFromID:   renewedFrom,
+		StartDate:       startDate,
+		EndDate:         startDate.AddDate(0, plan.DurationMonths, 0),
+		PricePaid:       plan.Fee,
+		Status:          "active",
+		Plan:            plan,
+	}
+
+	err = tx.Omit("Plan").Create(&membership).Error
+	return membership, invoice, err
+}
+
+// applyMembershipBenefits prices service lines for a customer with a current
+// membership: free services are used first, up to the monthly allowance, then
+// the plan discount applies to the remaining service lines
+func applyMembershipBenefits(db *gorm.DB, customerID uuid.UUID, items []models.InvoiceItem, now time.Time) error {
+	var membership models.CustomerMembership
+	err := db.Preload("Plan").
+		Where("customer_id = ? AND status = 'active' AND start_date <= ? AND end_date > ?", customerID, now, now).
+		Order("start_date").
+		First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	plan := membership.Plan
+	freeLeft := 0
+	if plan.FreeServicesPerMonth > 0 {
+		used, err := countFreeServicesUsed(db, membership.ID, now)
+		if err != nil {
+			return err
+		}
+		freeLeft = plan.FreeServicesPerMonth - used
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.ItemType != "service" || item.ServiceID == nil || item.BenefitType != "" {
+			continue
+		}
+
+		eligibleForFree := len(plan.FreeServiceIDs) == 0 || plan.FreeServiceIDs.Contains(item.ServiceID.String())
+		if freeLeft > 0 && eligibleForFree {
+			// Each unit counts against the allowance; any units beyond it get the discount
+			freeUnits := int(math.Min(float64(freeLeft), float64(item.Quantity)))
+			freeLeft -= freeUnits
+
+			gross := item.UnitPrice * float64(item.Quantity)
+			discount := item.UnitPrice * float64(freeUnits)
+			discount += item.UnitPrice * float64(item.Quantity-freeUnits) * plan.DiscountPercent / 100
+
+			item.DiscountAmount = discount
+			item.TotalPrice = gross - discount
+			item.BenefitType = "member_free"
+			item.MembershipID = &membership.ID
+			continue
+		}
+
+		if plan.DiscountPercent > 0 {
+			gross := item.UnitPrice * float64(item.Quantity)
+			item.DiscountAmount = gross * plan.DiscountPercent / 100
+			item.TotalPrice = gross - item.DiscountAmount
+			item.BenefitType = "member_discount"
+			item.MembershipID = &membership.ID
+		}
+	}
+
+	return nil
+}
+
+// countFreeServicesUsed counts free service units taken on a membership this calendar month
+func countFreeServicesUsed(db *gorm.DB, membershipID uuid.UUID, now time.Time) (int, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	var used int
+	err := db.Raw(`
+		SELECT COALESCE(SUM(LEAST(ii.quantity, ROUND(ii.discount_amount / NULLIF(ii.unit_price, 0)))), 0)
+		FROM invoice_items ii
+		INNER JOIN invoices i ON i.id = ii.invoice_id
+		WHERE ii.membership_id = ?
+		  AND ii.benefit_type = 'member_free'
+		  AND i.invoice_date >= ?
+		  AND i.deleted_at IS NULL
+	`, membershipID, monthStart).Scan(&used).Error
+	return used, err
+}
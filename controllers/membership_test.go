@@ -0,0 +1,177 @@
+// controllers/membership_test.go
+package controllers
+
+import (
+	"math"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCustomerMembershipIsCurrent(t *testing.T) {
+	start := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 3, 0)
+
+	tests := []struct {
+		name   string
+		status string
+		at     time.Time
+		want   bool
+	}{
+		{"before start", "active", start.Add(-time.Second), false},
+		{"on start", "active", start, true},
+		{"during", "active", start.AddDate(0, 1, 0), true},
+		{"on end", "active", end, false},
+		{"cancelled", "cancelled", start.AddDate(0, 1, 0), false},
+	}
+	for _, tt := range tests {
+		m := models.CustomerMembership{Status: tt.status, StartDate: start, EndDate: end}
+		if got := m.IsCurrent(tt.at); got != tt.want {
+			t.Errorf("%s: IsCurrent() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyMembershipBenefits(t *testing.T) {
+	now := time.Date(2026, 5, 14, 12, 0, 0, 0, time.UTC)
+	haircut := uuid.New()
+	colour := uuid.New()
+
+	type line struct {
+		service  uuid.UUID
+		itemType string
+		quantity int
+		price    float64
+	}
+	type want struct {
+		total   float64
+		benefit string
+	}
+
+	tests := []struct {
+		name      string
+		plan      models.MembershipPlan
+		usedFree  int // free haircuts already taken this month
+		cancelled bool
+		lines     []line
+		want      []want
+	}{
+		{"discount on services only",
+			models.MembershipPlan{DiscountPercent: 10}, 0, false,
+			[]line{{haircut, "service", 1, 800}, {uuid.Nil, "product", 1, 500}},
+			[]want{{720, "member_discount"}, {500, ""}}},
+		{"free service then discount",
+			models.MembershipPlan{DiscountPercent: 10, FreeServicesPerMonth: 1}, 0, false,
+			[]line{{haircut, "service", 1, 800}, {colour, "service", 1, 2000}},
+			[]want{{0, "member_free"}, {1800, "member_discount"}}},
+		{"free units beyond the allowance get the discount",
+			models.MembershipPlan{DiscountPercent: 20, FreeServicesPerMonth: 2}, 0, false,
+			[]line{{haircut, "service", 3, 500}},
+			[]want{{400, "member_free"}}},
+		{"allowance used up this month",
+			models.MembershipPlan{DiscountPercent: 10, FreeServicesPerMonth: 1}, 1, false,
+			[]line{{haircut, "service", 1, 800}},
+			[]want{{720, "member_discount"}}},
+		{"free only on listed services",
+			models.MembershipPlan{FreeServicesPerMonth: 1, FreeServiceIDs: models.StringList{colour.String()}}, 0, false,
+			[]line{{haircut, "service", 1, 800}, {colour, "service", 1, 2000}},
+			[]want{{800, ""}, {0, "member_free"}}},
+		{"cancelled membership",
+			models.MembershipPlan{DiscountPercent: 10}, 0, true,
+			[]line{{haircut, "service", 1, 800}},
+			[]want{{800, ""}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t,
+				&models.Invoice{},
+				&models.InvoiceItem{},
+				&models.MembershipPlan{},
+				&models.CustomerMembership{},
+			)
+
+			plan := tt.plan
+			plan.ID = uuid.New()
+			plan.SalonID = uuid.New()
+			plan.Name = "Gold"
+			plan.Fee = 5000
+			plan.DurationMonths = 12
+			if err := db.Create(&plan).Error; err != nil {
+				t.Fatalf("Failed to create plan: %v", err)
+			}
+
+			membership := models.CustomerMembership{
+				ID:              uuid.New(),
+				SalonID:         plan.SalonID,
+				CustomerID:      uuid.New(),
+				PlanID:          plan.ID,
+				SoldInvoiceID:   uuid.New(),
+				CreatedByUserID: uuid.New(),
+				StartDate:       now.AddDate(0, -1, 0),
+				EndDate:         now.AddDate(0, 11, 0),
+				PricePaid:       plan.Fee,
+				Status:          "active",
+			}
+			if tt.cancelled {
+				membership.Status = "cancelled"
+			}
+			if err := db.Omit("Plan").Create(&membership).Error; err != nil {
+				t.Fatalf("Failed to create membership: %v", err)
+			}
+
+			if tt.usedFree > 0 {
+				earlier := models.Invoice{
+					ID:              uuid.New(),
+					SalonID:         plan.SalonID,
+					CreatedByUserID: membership.CreatedByUserID,
+					InvoiceNumber:   "INV-" + uuid.NewString()[:8],
+					CustomerID:      membership.CustomerID,
+					InvoiceDate:     now.AddDate(0, 0, -2),
+					Items: []models.InvoiceItem{{
+						ID:             uuid.New(),
+						ItemType:       "service",
+						ServiceID:      &haircut,
+						ServiceName:    "Haircut",
+						Quantity:       tt.usedFree,
+						UnitPrice:      800,
+						DiscountAmount: 800 * float64(tt.usedFree),
+						BenefitType:    "member_free",
+						MembershipID:   &membership.ID,
+					}},
+				}
+				if err := db.Create(&earlier).Error; err != nil {
+					t.Fatalf("Failed to create earlier invoice: %v", err)
+				}
+			}
+
+			items := make([]models.InvoiceItem, len(tt.lines))
+			for i, l := range tt.lines {
+				items[i] = models.InvoiceItem{
+					ItemType:   l.itemType,
+					Quantity:   l.quantity,
+					UnitPrice:  l.price,
+					TotalPrice: l.price * float64(l.quantity),
+				}
+				if l.service != uuid.Nil {
+					service := l.service
+					items[i].ServiceID = &service
+				}
+			}
+
+			if err := applyMembershipBenefits(db, membership.CustomerID, items, now); err != nil {
+				t.Fatalf("applyMembershipBenefits() error = %v", err)
+			}
+			for i, w := range tt.want {
+				got := items[i]
+				if math.Abs(got.TotalPrice-w.total) > 0.001 || got.BenefitType != w.benefit {
+					t.Errorf("line %d = %v %q, want %v %q", i, got.TotalPrice, got.BenefitType, w.total, w.benefit)
+				}
+				if w.benefit != "" && (got.MembershipID == nil || *got.MembershipID != membership.ID) {
+					t.Errorf("line %d is not linked to the membership", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,642 @@
+// controllers/package.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ServicePackageItemInput is one service and its session count in a package
+type ServicePackageItemInput struct {
+	ServiceID uuid.UUID `json:"serviceId" binding:"required"`
+	Quantity  int       `json:"quantity" binding:"required,min=1"`
+}
+
+// CreateServicePackageInput defines the expected JSON structure for creating a package
+type CreateServicePackageInput struct {
+	Name         string                    `json:"name" binding:"required"`
+	Description  string                    `json:"description"`
+	Price        float64                   `json:"price" binding:"required,min=0"`
+	ValidityDays int                       `json:"validityDays" binding:"min=0"`
+	Items        []ServicePackageItemInput `json:"items" binding:"required,min=1,dive"`
+}
+
+// UpdateServicePackageInput defines the expected JSON structure for updating a package.
+// Packages already sold keep the sessions and expiry they were sold with.
+type UpdateServicePackageInput struct {
+	Name         *string                    `json:"name"`
+	Description  *string                    `json:"description"`
+	Price        *float64                   `json:"price" binding:"omitempty,min=0"`
+	ValidityDays *int                       `json:"validityDays" binding:"omitempty,min=0"`
+	Items        *[]ServicePackageItemInput `json:"items" binding:"omitempty,min=1,dive"`
+	IsActive     *bool                      `json:"isActive"`
+}
+
+// SellPackageInput defines the expected JSON structure for selling a package to a customer
+type SellPackageInput struct {
+	CustomerID    uuid.UUID `json:"customerId" binding:"required"`
+	PackageID     uuid.UUID `json:"packageId" binding:"required"`
+	Tax           float64   `json:"tax" binding:"min=0"`
+	PaymentMethod string    `json:"paymentMethod" binding:"required"`
+}
+
+// ExtendCustomerPackageInput sets a new expiry for a customer's package; null removes it
+type ExtendCustomerPackageInput struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// CreateServicePackage creates a new prepaid package for the salon
+func CreateServicePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input CreateServicePackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	pkg := models.ServicePackage{
+		ID:           uuid.New(),
+		SalonID:      salonUUID,
+		Name:         input.Name,
+		Description:  input.Description,
+		Price:        input.Price,
+		ValidityDays: input.ValidityDays,
+		IsActive:     true,
+	}
+
+	items, err := buildServicePackageItems(config.DB, salonUUID, pkg.ID, input.Items)
+	if err != nil {
+		respondInvoiceInputError(c, err)
+		return
+	}
+	pkg.Items = items
+
+	if err := config.DB.Create(&pkg).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create package")
+		return
+	}
+
+	c.JSON(http.StatusCreated, pkg)
+}
+
+// GetServicePackages retrieves all packages for the salon
+func GetServicePackages(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var packages []models.ServicePackage
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ?", salonUUID).
+		Order("name").
+		Find(&packages).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve packages")
+		return
+	}
+
+	c.JSON(http.StatusOK, packages)
+}
+
+// GetServicePackage retrieves a specific package by ID
+func GetServicePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	var pkg models.ServicePackage
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ? AND id = ?", salonUUID, packageUUID).
+		First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, pkg)
+}
+
+// UpdateServicePackage updates an existing package, replacing its items if given
+func UpdateServicePackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	var input UpdateServicePackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	var pkg models.ServicePackage
+	if err := tx.Where("salon_id = ? AND id = ?", salonUUID, packageUUID).
+		First(&pkg).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Update fields if provided
+	if input.Name != nil {
+		pkg.Name = *input.Name
+	}
+	if input.Description != nil {
+		pkg.Description = *input.Description
+	}
+	if input.Price != nil {
+		pkg.Price = *input.Price
+	}
+	if input.ValidityDays != nil {
+		pkg.ValidityDays = *input.ValidityDays
+	}
+	if input.IsActive != nil {
+		pkg.IsActive = *input.IsActive
+	}
+
+	if input.Items != nil {
+		items, err := buildServicePackageItems(tx, salonUUID, pkg.ID, *input.Items)
+		if err != nil {
+			tx.Rollback()
+			respondInvoiceInputError(c, err)
+			return
+		}
+
+		if err := tx.Where("package_id = ?", pkg.ID).Delete(&models.ServicePackageItem{}).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to clear existing items")
+			return
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			tx.Rollback()
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save package items")
+			return
+		}
+	}
+
+	if err := tx.Omit("Items").Save(&pkg).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update package")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	config.DB.Preload("Items").First(&pkg, "id = ?", pkg.ID)
+	c.JSON(http.StatusOK, pkg)
+}
+
+// SellPackage sells a package to a customer, billing it on its own invoice
+func SellPackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input SellPackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, input.CustomerID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var pkg models.ServicePackage
+	if err := config.DB.Preload("Items").
+		Where("salon_id = ? AND id = ? AND is_active = true", salonUUID, input.PackageID).
+		First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusBadRequest, "Package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	sessions, err := buildPackageSessions(config.DB, salonUUID, pkg)
+	if err != nil {
+		respondInvoiceInputError(c, err)
+		return
+	}
+
+	now := time.Now()
+	customerPackage := models.CustomerPackage{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		CustomerID:      customer.ID,
+		PackageID:       pkg.ID,
+		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		Name:            pkg.Name,
+		PricePaid:       pkg.Price,
+		PurchasedAt:     now,
+		Status:          "active",
+	}
+	if pkg.ValidityDays > 0 {
+		expiresAt := utils.BeginningOfDay(now).AddDate(0, 0, pkg.ValidityDays+1)
+		customerPackage.ExpiresAt = &expiresAt
+	}
+	for i := range sessions {
+		sessions[i].CustomerPackageID = customerPackage.ID
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	invoice, err := createSaleInvoice(tx, customer, customerPackage.CreatedByUserID, models.InvoiceItem{
+		ItemType:    "package",
+		PackageID:   &customerPackage.ID,
+		ServiceName: "Package: " + pkg.Name,
+		Quantity:    1,
+		UnitPrice:   pkg.Price,
+		TotalPrice:  pkg.Price,
+	}, input.Tax, input.PaymentMethod)
+	if err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to sell package")
+		return
+	}
+
+	customerPackage.SoldInvoiceID = invoice.ID
+	customerPackage.Sessions = sessions
+	if err := tx.Create(&customerPackage).Error; err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to sell package")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"package": customerPackage,
+		"invoice": invoice,
+	})
+}
+
+// GetCustomerPackages lists a customer's packages with remaining sessions
+func GetCustomerPackages(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var packages []models.CustomerPackage
+	if err := config.DB.Preload("Sessions").
+		Where("salon_id = ? AND customer_id = ?", salonUUID, customerUUID).
+		Order("purchased_at DESC").
+		Find(&packages).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve packages")
+		return
+	}
+
+	type sessionView struct {
+		ServiceID   uuid.UUID `json:"serviceId"`
+		ServiceName string    `json:"serviceName"`
+		Total       int       `json:"total"`
+		Used        int       `json:"used"`
+		Remaining   int       `json:"remaining"`
+	}
+	type packageView struct {
+		models.CustomerPackage
+		Usable   bool          `json:"usable"`
+		Sessions []sessionView `json:"Sessions"`
+	}
+
+	now := time.Now()
+	views := make([]packageView, 0, len(packages))
+	for _, p := range packages {
+		view := packageView{CustomerPackage: p, Sessions: []sessionView{}}
+		remaining := 0
+		for _, s := range p.Sessions {
+			view.Sessions = append(view.Sessions, sessionView{
+				ServiceID:   s.ServiceID,
+				ServiceName: s.ServiceName,
+				Total:       s.Total,
+				Used:        s.Used,
+				Remaining:   s.Remaining(),
+			})
+			remaining += s.Remaining()
+		}
+		view.Usable = p.Status == "active" && !p.IsExpired(now) && remaining > 0
+		views = append(views, view)
+	}
+
+	c.JSON(http.StatusOK, views)
+}
+
+// ExtendCustomerPackage changes the expiry of a customer's package
+func ExtendCustomerPackage(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	packageUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid package ID format")
+		return
+	}
+
+	var input ExtendCustomerPackageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customerPackage models.CustomerPackage
+	if err := config.DB.Where("salon_id = ? AND id = ? AND status = 'active'", salonUUID, packageUUID).
+		First(&customerPackage).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Active package not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if err := config.DB.Model(&customerPackage).Update("expires_at", input.ExpiresAt).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update package expiry")
+		return
+	}
+	customerPackage.ExpiresAt = input.ExpiresAt
+
+	c.JSON(http.StatusOK, customerPackage)
+}
+
+// buildServicePackageItems checks each service belongs to the salon and builds the package lines
+func buildServicePackageItems(db *gorm.DB, salonID, packageID uuid.UUID, inputs []ServicePackageItemInput) ([]models.ServicePackageItem, error) {
+	items := make([]models.ServicePackageItem, 0, len(inputs))
+	for _, input := range inputs {
+		var count int64
+		if err := db.Model(&models.Service{}).
+			Where("salon_id = ? AND id = ?", salonID, input.ServiceID).
+			Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, &invoiceInputError{"Service not found: " + input.ServiceID.String()}
+		}
+
+		items = append(items, models.ServicePackageItem{
+			ID:        uuid.New(),
+			PackageID: packageID,
+			ServiceID: input.ServiceID,
+			Quantity:  input.Quantity,
+		})
+	}
+	return items, nil
+}
+
+// buildPackageSessions turns a package's items into session balances, splitting
+// the package price across sessions in proportion to each service's list price
+func buildPackageSessions(db *gorm.DB, salonID uuid.UUID, pkg models.ServicePackage) ([]models.CustomerPackageSession, error) {
+	sessions := make([]models.CustomerPackageSession, 0, len(pkg.Items))
+	prices := make([]float64, 0, len(pkg.Items))
+	var listValue float64
+	totalSessions := 0
+
+	for _, item := range pkg.Items {
+		var service models.Service
+		if err := db.Where("salon_id = ? AND id = ?", salonID, item.ServiceID).
+			First(&service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &invoiceInputError{"Package contains a service that no longer exists"}
+			}
+			return nil, err
+		}
+
+		sessions = append(sessions, models.CustomerPackageSession{
+			ID:          uuid.New(),
+			ServiceID:   service.ID,
+			ServiceName: service.Name,
+			Total:       item.Quantity,
+		})
+		prices = append(prices, service.Price)
+		listValue += service.Price * float64(item.Quantity)
+		totalSessions += item.Quantity
+	}
+
+	for i := range sessions {
+		share := 1.0 / float64(totalSessions)
+		if listValue > 0 {
+			share = prices[i] / listValue
+		}
+		sessions[i].UnitValue = math.Round(pkg.Price*share*100) / 100
+	}
+	return sessions, nil
+}
+
+// applyPackageSessions prices service lines redeemed against a customer's
+// package at zero, after checking the package can cover them
+func applyPackageSessions(db *gorm.DB, customerID uuid.UUID, items []models.InvoiceItem, now time.Time) error {
+	for i := range items {
+		item := &items[i]
+		if item.PackageID == nil {
+			continue
+		}
+
+		var customerPackage models.CustomerPackage
+		if err := db.Where("id = ? AND customer_id = ?", *item.PackageID, customerID).
+			First(&customerPackage).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &invoiceInputError{"Package not found for this customer"}
+			}
+			return err
+		}
+		if customerPackage.Status != "active" {
+			return &invoiceInputError{"Package " + customerPackage.Name + " is no longer active"}
+		}
+		if customerPackage.IsExpired(now) {
+			return &invoiceInputError{"Package " + customerPackage.Name + " has expired"}
+		}
+
+		var count int64
+		if err := db.Model(&models.CustomerPackageSession{}).
+			Where("customer_package_id = ? AND service_id = ?", customerPackage.ID, *item.ServiceID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return &invoiceInputError{"Package " + customerPackage.Name + " does not include " + item.ServiceName}
+		}
+
+		item.DiscountAmount = item.UnitPrice * float64(item.Quantity)
+		item.TotalPrice = 0
+		item.BenefitType = "package_session"
+	}
+	return nil
+}
+
+// adjustPackageSessions moves package sessions for every line redeemed against
+// a package; direction is -1 to use sessions and +1 to give them back
+func adjustPackageSessions(tx *gorm.DB, items []models.InvoiceItem, direction int) error {
+	for _, item := range items {
+		if item.BenefitType != "package_session" || item.PackageID == nil || item.ServiceID == nil {
+			continue
+		}
+
+		query := tx.Model(&models.CustomerPackageSession{}).
+			Where("customer_package_id = ? AND service_id = ?", *item.PackageID, *item.ServiceID)
+		if direction < 0 {
+			query = query.Where("used + ? <= total", item.Quantity)
+		}
+
+		result := query.Update("used", gorm.Expr("used - ?", direction*item.Quantity))
+		if result.Error != nil {
+			return result.Error
+		}
+		if direction < 0 && result.RowsAffected == 0 {
+			return &invoiceInputError{"Not enough package sessions left for " + item.ServiceName}
+		}
+	}
+	return nil
+}
+
+// reversePackageSale cancels packages sold on the invoice; it fails if any of
+// their sessions have already been used
+func reversePackageSale(tx *gorm.DB, invoice models.Invoice) error {
+	var packages []models.CustomerPackage
+	if err := tx.Preload("Sessions").
+		Where("sold_invoice_id = ? AND status = 'active'", invoice.ID).
+		Find(&packages).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, p := range packages {
+		for _, s := range p.Sessions {
+			if s.Used > 0 {
+				return &invoiceInputError{"Package " + p.Name + " sold on this invoice has already been used"}
+			}
+		}
+
+		if err := tx.Model(&p).Updates(map[string]interface{}{
+			"status":       "cancelled",
+			"cancelled_at": now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// controllers/package_test.go
+package controllers
+
+import (
+	"errors"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildPackageSessions(t *testing.T) {
+	db := testDB(t, &models.Service{})
+	salonID := uuid.New()
+
+	haircut := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Haircut", Price: 500}
+	spa := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Hair spa", Price: 1500}
+	free := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Consultation", Price: 0}
+	for _, service := range []*models.Service{&haircut, &spa, &free} {
+		if err := db.Create(service).Error; err != nil {
+			t.Fatalf("Failed to create service: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		price float64
+		items []models.ServicePackageItem
+		want  []float64 // unit value per session line
+	}{
+		// List value 4*500 + 2*1500 = 5000, sold for 4000
+		{"split by list price", 4000, []models.ServicePackageItem{
+			{ServiceID: haircut.ID, Quantity: 4},
+			{ServiceID: spa.ID, Quantity: 2},
+		}, []float64{400, 1200}},
+		{"free services only split evenly", 900, []models.ServicePackageItem{
+			{ServiceID: free.ID, Quantity: 3},
+		}, []float64{300}},
+		{"rounded to paise", 1000, []models.ServicePackageItem{
+			{ServiceID: haircut.ID, Quantity: 3},
+		}, []float64{333.33}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pkg := models.ServicePackage{Price: tt.price, Items: tt.items}
+			sessions, err := buildPackageSessions(db, salonID, pkg)
+			if err != nil {
+				t.Fatalf("buildPackageSessions() error = %v", err)
+			}
+			if len(sessions) != len(tt.want) {
+				t.Fatalf("got %d sessions, want %d", len(sessions), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if sessions[i].UnitValue != want || sessions[i].Total != tt.items[i].Quantity {
+					t.Errorf("session %d = %d at %v, want %d at %v",
+						i, sessions[i].Total, sessions[i].UnitValue, tt.items[i].Quantity, want)
+				}
+			}
+		})
+	}
+
+	pkg := models.ServicePackage{Price: 100, Items: []models.ServicePackageItem{{ServiceID: uuid.New(), Quantity: 1}}}
+	if _, err := buildPackageSessions(db, salonID, pkg); err == nil {
+		t.Error("buildPackageSessions() accepted a service that does not exist")
+	}
+}
+
+func TestApplyPackageSessions(t *testing.T) {
+	now := time.Date(2026, 5, 14, 12, 0, 0, 0, time.UTC)
+	expired := now.AddDate(0, 0, -1)
+	haircut := uuid.New()
+	colour := uuid.New()
+
+	tests := []struct {
+		name     string
+		status   string
+		expires  *time.Time
+		used     int
+		service  uuid.UUID
+		quantity int
+		other    bool // the package belongs to someone else
+		wantErr  string
+	}{
+		{"session used", "active", nil, 0, haircut, 1, false, ""},
+		{"last sessions used", "active", nil, 3, haircut, 2, false, ""},
+		{"not enough sessions", "active", nil, 4, haircut, 2, false, "Not enough package sessions left for Haircut"},
+		{"service not in package", "active", nil, 0, colour, 1, false, "Package Hair care does not include Haircut"},
+		{"cancelled package", "cancelled", nil, 0, haircut, 1, false, "Package Hair care is no longer active"},
+		{"expired package", "active", &expired, 0, haircut, 1, false, "Package Hair care has expired"},
+		{"another customer's package", "active", nil, 0, haircut, 1, true, "Package not found for this customer"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, &models.CustomerPackage{}, &models.CustomerPackageSession{})
+
+			pkg := models.CustomerPackage{
+				ID:              uuid.New(),
+				SalonID:         uuid.New(),
+				CustomerID:      uuid.New(),
+				PackageID:       uuid.New(),
+				SoldInvoiceID:   uuid.New(),
+				CreatedByUserID: uuid.New(),
+				Name:            "Hair care",
+				PricePaid:       2000,
+				PurchasedAt:     now.AddDate(0, -1, 0),
+				ExpiresAt:       tt.expires,
+				Status:          tt.status,
+				Sessions: []models.CustomerPackageSession{{
+					ID:          uuid.New(),
+					ServiceID:   haircut,
+					ServiceName: "Haircut",
+					Total:       5,
+					Used:        tt.used,
+					UnitValue:   400,
+				}},
+			}
+			if err := db.Create(&pkg).Error; err != nil {
+				t.Fatalf("Failed to create package: %v", err)
+			}
+
+			customerID := pkg.CustomerID
+			if tt.other {
+				customerID = uuid.New()
+			}
+			service := tt.service
+			items := []models.InvoiceItem{{
+				ItemType:    "service",
+				ServiceID:   &service,
+				ServiceName: "Haircut",
+				Quantity:    tt.quantity,
+				UnitPrice:   500,
+				TotalPrice:  500 * float64(tt.quantity),
+				PackageID:   &pkg.ID,
+			}}
+
+			err := applyPackageSessions(db, customerID, items, now)
+			if err == nil {
+				err = adjustPackageSessions(db, items, -1)
+			}
+			if tt.wantErr != "" {
+				var inputErr *invoiceInputError
+				if !errors.As(err, &inputErr) || inputErr.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			if items[0].TotalPrice != 0 || items[0].DiscountAmount != 500*float64(tt.quantity) || items[0].BenefitType != "package_session" {
+				t.Errorf("line = %+v, want priced at zero as a package session", items[0])
+			}
+			var session models.CustomerPackageSession
+			db.First(&session, "customer_package_id = ?", pkg.ID)
+			if session.Used != tt.used+tt.quantity {
+				t.Errorf("used = %d, want %d", session.Used, tt.used+tt.quantity)
+			}
+
+			// Giving the sessions back, as when the invoice is edited or deleted
+			if err := adjustPackageSessions(db, items, 1); err != nil {
+				t.Fatalf("adjustPackageSessions(+1) error = %v", err)
+			}
+			db.First(&session, "customer_package_id = ?", pkg.ID)
+			if session.Used != tt.used {
+				t.Errorf("used after return = %d, want %d", session.Used, tt.used)
+			}
+		})
+	}
+}
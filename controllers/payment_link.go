@@ -0,0 +1,554 @@
+// controllers/payment_link.go
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentGatewayController takes online payments for invoices and reconciles
+// them from provider webhooks
+type PaymentGatewayController struct {
+	Gateway services.PaymentGateway
+}
+
+// CreatePaymentLinkInput defaults to the full amount due and a 72 hour expiry
+type CreatePaymentLinkInput struct {
+	Amount         float64 `json:"amount" binding:"omitempty,gt=0"`
+	ExpiresInHours int     `json:"expiresInHours" binding:"omitempty,min=1,max=720"`
+}
+
+// RefundGatewayPaymentInput defaults to refunding everything not yet refunded
+type RefundGatewayPaymentInput struct {
+	Amount float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason string  `json:"reason"`
+}
+
+// CreatePaymentLink asks the provider for a payment page for the invoice balance
+func (pc *PaymentGatewayController) CreatePaymentLink(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var input CreatePaymentLinkInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+			return
+		}
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	due := math.Round((invoice.Total-invoice.PaidAmount)*100) / 100
+	if due <= 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invoice has no amount due")
+		return
+	}
+	amount := due
+	if input.Amount > 0 {
+		if input.Amount > due+0.005 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Amount exceeds the amount due")
+			return
+		}
+		amount = math.Round(input.Amount*100) / 100
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to load salon")
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.First(&customer, "id = ?", invoice.CustomerID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to load customer")
+		return
+	}
+
+	hours := input.ExpiresInHours
+	if hours == 0 {
+		hours = 72
+	}
+	expiresAt := time.Now().Add(time.Duration(hours) * time.Hour)
+	currency := salon.MoneyFormat().CurrencyCode
+
+	gatewayLink, err := pc.Gateway.CreatePaymentLink(services.PaymentLinkRequest{
+		Amount:        amount,
+		Currency:      currency,
+		Description:   fmt.Sprintf("%s invoice %s", salon.Name, invoice.InvoiceNumber),
+		ReferenceID:   invoice.InvoiceNumber,
+		CustomerName:  customer.Name,
+		CustomerPhone: customer.Phone,
+		CustomerEmail: customer.Email,
+		ExpiresAt:     &expiresAt,
+		CallbackURL:   os.Getenv("PAYMENT_CALLBACK_URL"),
+	})
+	if err != nil {
+		respondGatewayError(c, "Failed to create payment link", err)
+		return
+	}
+
+	link := models.PaymentLink{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		InvoiceID:       invoice.ID,
+		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		Provider:        pc.Gateway.Name(),
+		ProviderLinkID:  gatewayLink.ID,
+		URL:             gatewayLink.URL,
+		Amount:          amount,
+		Currency:        currency,
+		Status:          "created",
+		ExpiresAt:       gatewayLink.ExpiresAt,
+	}
+	if err := config.DB.Create(&link).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to save payment link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// GetPaymentLinks lists the payment links sent for an invoice with their payments
+func (pc *PaymentGatewayController) GetPaymentLinks(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var links []models.PaymentLink
+	if err := config.DB.Preload("Payments").
+		Where("salon_id = ? AND invoice_id = ?", salonUUID, invoiceUUID).
+		Order("created_at DESC").
+		Find(&links).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch payment links")
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+// RefreshPaymentLink looks the link up with the provider and posts any captured
+// payments a missed webhook did not deliver
+func (pc *PaymentGatewayController) RefreshPaymentLink(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	linkUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid payment link ID format")
+		return
+	}
+
+	var link models.PaymentLink
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, linkUUID).
+		First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Payment link not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if link.Provider != pc.Gateway.Name() {
+		utils.RespondWithError(c, http.StatusBadRequest, "Payment link belongs to provider "+link.Provider)
+		return
+	}
+
+	gatewayLink, err := pc.Gateway.GetPaymentLink(link.ProviderLinkID)
+	if err != nil {
+		respondGatewayError(c, "Failed to fetch payment link", err)
+		return
+	}
+
+	for _, payment := range gatewayLink.Payments {
+		if payment.Status != "captured" {
+			continue
+		}
+		if _, err := postGatewayPayment(config.DB, link, payment); err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to post payment")
+			return
+		}
+	}
+
+	if err := config.DB.Model(&link).Update("status", gatewayLink.Status).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update payment link")
+		return
+	}
+
+	if err := config.DB.Preload("Payments").First(&link, "id = ?", link.ID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch payment link")
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// RefundGatewayPayment refunds an online payment through the provider and takes
+// the amount off the invoice's paid amount
+func (pc *PaymentGatewayController) RefundGatewayPayment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	paymentUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid payment ID format")
+		return
+	}
+
+	var input RefundGatewayPaymentInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+			return
+		}
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can refund payments")
+		return
+	}
+
+	var payment models.GatewayPayment
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, paymentUUID).
+		First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Payment not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if payment.Provider != pc.Gateway.Name() {
+		utils.RespondWithError(c, http.StatusBadRequest, "Payment belongs to provider "+payment.Provider)
+		return
+	}
+
+	amount := math.Round(payment.RefundableAmount()*100) / 100
+	if input.Amount > 0 {
+		if input.Amount > amount+0.005 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Refund exceeds the refundable amount")
+			return
+		}
+		amount = math.Round(input.Amount*100) / 100
+	}
+	if amount <= 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "Payment has already been refunded")
+		return
+	}
+
+	// The provider wants the refund in the currency the link was created in
+	var link models.PaymentLink
+	if err := config.DB.Select("currency").First(&link, "id = ?", payment.PaymentLinkID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to load payment link")
+		return
+	}
+
+	refund, err := pc.Gateway.Refund(payment.ProviderPaymentID, amount, link.Currency)
+	if err != nil {
+		respondGatewayError(c, "Failed to refund payment", err)
+		return
+	}
+
+	// The money has left the provider, so the local record must follow
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&payment).
+			Update("refunded_amount", gorm.Expr("refunded_amount + ?", amount)).Error; err != nil {
+			return err
+		}
+
+		var invoice models.Invoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&invoice, "id = ?", payment.InvoiceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil // invoice was deleted; nothing to adjust
+			}
+			return err
+		}
+
+		reference := "Refund " + refund.ID
+		if input.Reason != "" {
+			reference += ": " + input.Reason
+		}
+		return recordTenderPayment(tx, &invoice, models.InvoicePayment{
+			CreatedByUserID: currentUser.ID,
+			Method:          "online",
+			Amount:          -amount,
+			Reference:       reference,
+		})
+	})
+	if err != nil {
+		log.Printf("Refund %s issued for payment %s but not recorded: %v", refund.ID, payment.ProviderPaymentID, err)
+		utils.RespondWithError(c, http.StatusInternalServerError, "Refund issued but failed to record it")
+		return
+	}
+
+	c.JSON(http.StatusCreated, refund)
+}
+
+// HandlePaymentWebhook receives provider callbacks. It is not behind auth, so
+// the signature is the only proof the request came from the provider.
+func (pc *PaymentGatewayController) HandlePaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Failed to read body")
+		return
+	}
+
+	event, err := pc.Gateway.ParseWebhook(body, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhookSignature) {
+			utils.RespondWithError(c, http.StatusUnauthorized, "Invalid signature")
+		} else if errors.Is(err, services.ErrPaymentGatewayDisabled) {
+			utils.RespondWithError(c, http.StatusServiceUnavailable, err.Error())
+		} else {
+			utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	provider := pc.Gateway.Name()
+	if event.ID != "" {
+		var count int64
+		config.DB.Model(&models.PaymentWebhookEvent{}).
+			Where("provider = ? AND event_id = ?", provider, event.ID).
+			Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+			return
+		}
+	}
+
+	var link models.PaymentLink
+	if err := config.DB.Where("provider = ? AND provider_link_id = ?", provider, event.LinkID).
+		First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Not one of ours (e.g. created from the provider dashboard)
+			c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	switch event.Type {
+	case services.GatewayEventPaymentCaptured:
+		if event.Payment == nil || event.Payment.ID == "" {
+			utils.RespondWithError(c, http.StatusBadRequest, "Event has no payment")
+			return
+		}
+		if _, err := postGatewayPayment(config.DB, link, *event.Payment); err != nil {
+			// A non-2xx response makes the provider retry later
+			log.Printf("Failed to post %s payment %s: %v", provider, event.Payment.ID, err)
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to post payment")
+			return
+		}
+	case services.GatewayEventLinkExpired, services.GatewayEventLinkCancelled:
+		status := "expired"
+		if event.Type == services.GatewayEventLinkCancelled {
+			status = "cancelled"
+		}
+		if err := config.DB.Model(&models.PaymentLink{}).
+			Where("id = ? AND status IN ('created', 'partially_paid')", link.ID).
+			Update("status", status).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update payment link")
+			return
+		}
+	}
+
+	if event.ID != "" {
+		if err := config.DB.Create(&models.PaymentWebhookEvent{
+			ID:       uuid.New(),
+			Provider: provider,
+			EventID:  event.ID,
+			Type:     event.Type,
+		}).Error; err != nil {
+			log.Printf("Failed to record %s webhook event %s: %v", provider, event.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "processed"})
+}
+
+// postGatewayPayment records a captured provider payment against the link's
+// invoice, once per provider payment ID, and reports whether it was new
+func postGatewayPayment(db *gorm.DB, link models.PaymentLink, captured services.ProviderPayment) (bool, error) {
+	posted := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the invoice so a webhook and a manual refresh cannot both post
+		var invoice models.Invoice
+		invoiceErr := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&invoice, "id = ?", link.InvoiceID).Error
+		if invoiceErr != nil && !errors.Is(invoiceErr, gorm.ErrRecordNotFound) {
+			return invoiceErr
+		}
+
+		var count int64
+		if err := tx.Model(&models.GatewayPayment{}).
+			Where("provider = ? AND provider_payment_id = ?", link.Provider, captured.ID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		// Re-read the link for an up to date paid amount
+		if err := tx.First(&link, "id = ?", link.ID).Error; err != nil {
+			return err
+		}
+
+		amount := math.Round(captured.Amount*100) / 100
+		payment := models.GatewayPayment{
+			ID:                uuid.New(),
+			SalonID:           link.SalonID,
+			InvoiceID:         link.InvoiceID,
+			PaymentLinkID:     link.ID,
+			Provider:          link.Provider,
+			ProviderPaymentID: captured.ID,
+			Method:            captured.Method,
+			Amount:            amount,
+		}
+
+		if invoiceErr == nil {
+			// Posted under the generic 'online' method so provider methods such as
+			// 'wallet' are never mistaken for the customer wallet
+			invoicePaymentID := uuid.New()
+			if err := recordTenderPayment(tx, &invoice, models.InvoicePayment{
+				ID:              invoicePaymentID,
+				CreatedByUserID: link.CreatedByUserID,
+				Method:          "online",
+				Amount:          amount,
+				Reference:       fmt.Sprintf("%s %s (%s)", link.Provider, captured.ID, captured.Method),
+			}); err != nil {
+				return err
+			}
+			payment.InvoicePaymentID = &invoicePaymentID
+
+			if err := awardLoyaltyPoints(tx, invoice, link.CreatedByUserID); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("%s payment %s arrived for deleted invoice %s", link.Provider, captured.ID, link.InvoiceID)
+		}
+
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+
+		status := "partially_paid"
+		if link.AmountPaid+amount+0.005 >= link.Amount {
+			status = "paid"
+		}
+		if err := tx.Model(&models.PaymentLink{}).Where("id = ?", link.ID).Updates(map[string]interface{}{
+			"amount_paid": gorm.Expr("amount_paid + ?", amount),
+			"status":      status,
+		}).Error; err != nil {
+			return err
+		}
+
+		posted = true
+		return nil
+	})
+	return posted, err
+}
+
+// respondGatewayError reports a failed provider call, or that no provider is configured
+func respondGatewayError(c *gin.Context, message string, err error) {
+	if errors.Is(err, services.ErrPaymentGatewayDisabled) {
+		utils.RespondWithError(c, http.StatusServiceUnavailable, "Online payments are not configured")
+		return
+	}
+	utils.RespondWithError(c, http.StatusBadGateway, message+": "+err.Error())
+}
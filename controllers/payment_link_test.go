@@ -0,0 +1,199 @@
+// controllers/payment_link_test.go
+package controllers
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// paymentTestDB returns a test database with the payment tables
+func paymentTestDB(t *testing.T) *gorm.DB {
+	return testDB(t,
+		&models.Invoice{},
+		&models.InvoiceItem{},
+		&models.InvoicePayment{},
+		&models.PaymentLink{},
+		&models.GatewayPayment{},
+		&models.PaymentWebhookEvent{},
+		&models.LoyaltyProgram{},
+		&models.LoyaltyTransaction{},
+	)
+}
+
+// createTestPaymentLink stores an unpaid invoice and a fake gateway link for it
+func createTestPaymentLink(t *testing.T, db *gorm.DB, gateway *services.FakeGateway, total float64) models.PaymentLink {
+	t.Helper()
+
+	invoice := models.Invoice{
+		ID:              uuid.New(),
+		SalonID:         uuid.New(),
+		CreatedByUserID: uuid.New(),
+		InvoiceNumber:   "INV-" + uuid.NewString()[:8],
+		CustomerID:      uuid.New(),
+		Subtotal:        total,
+		Total:           total,
+		PaymentStatus:   "unpaid",
+	}
+	if err := db.Create(&invoice).Error; err != nil {
+		t.Fatalf("Failed to create invoice: %v", err)
+	}
+
+	gatewayLink, err := gateway.CreatePaymentLink(services.PaymentLinkRequest{Amount: total, Currency: "INR"})
+	if err != nil {
+		t.Fatalf("CreatePaymentLink() error = %v", err)
+	}
+	link := models.PaymentLink{
+		ID:              uuid.New(),
+		SalonID:         invoice.SalonID,
+		InvoiceID:       invoice.ID,
+		CreatedByUserID: invoice.CreatedByUserID,
+		Provider:        gateway.Name(),
+		ProviderLinkID:  gatewayLink.ID,
+		URL:             gatewayLink.URL,
+		Amount:          total,
+		Currency:        "INR",
+		Status:          "created",
+	}
+	if err := db.Create(&link).Error; err != nil {
+		t.Fatalf("Failed to create payment link: %v", err)
+	}
+	return link
+}
+
+// assertPostedOnce checks the invoice and link reflect exactly one payment of amount
+func assertPostedOnce(t *testing.T, db *gorm.DB, link models.PaymentLink, amount float64) {
+	t.Helper()
+
+	var gatewayPayments, invoicePayments int64
+	db.Model(&models.GatewayPayment{}).Where("payment_link_id = ?", link.ID).Count(&gatewayPayments)
+	db.Model(&models.InvoicePayment{}).Where("invoice_id = ?", link.InvoiceID).Count(&invoicePayments)
+	if gatewayPayments != 1 || invoicePayments != 1 {
+		t.Errorf("got %d gateway and %d invoice payments, want 1 each", gatewayPayments, invoicePayments)
+	}
+
+	var invoice models.Invoice
+	if err := db.First(&invoice, "id = ?", link.InvoiceID).Error; err != nil {
+		t.Fatalf("Failed to reload invoice: %v", err)
+	}
+	if invoice.PaidAmount != amount {
+		t.Errorf("invoice paid amount = %v, want %v", invoice.PaidAmount, amount)
+	}
+
+	if err := db.First(&link, "id = ?", link.ID).Error; err != nil {
+		t.Fatalf("Failed to reload payment link: %v", err)
+	}
+	if link.AmountPaid != amount {
+		t.Errorf("link amount paid = %v, want %v", link.AmountPaid, amount)
+	}
+}
+
+func TestPostGatewayPaymentDuplicatePaymentID(t *testing.T) {
+	db := paymentTestDB(t)
+	gateway := services.NewFakeGateway("whsec")
+	link := createTestPaymentLink(t, db, gateway, 500)
+
+	captured := services.ProviderPayment{ID: "pay_1", Amount: 200, Method: "upi", Status: "captured"}
+	posted, err := postGatewayPayment(db, link, captured)
+	if err != nil || !posted {
+		t.Fatalf("first postGatewayPayment() = %v, %v; want true, nil", posted, err)
+	}
+
+	// The same payment again, e.g. from a manual refresh after the webhook
+	posted, err = postGatewayPayment(db, link, captured)
+	if err != nil || posted {
+		t.Fatalf("second postGatewayPayment() = %v, %v; want false, nil", posted, err)
+	}
+
+	assertPostedOnce(t, db, link, 200)
+
+	var invoice models.Invoice
+	db.First(&invoice, "id = ?", link.InvoiceID)
+	if invoice.PaymentStatus != "partial" || invoice.PaymentMethod != "online" {
+		t.Errorf("invoice status = %s, method = %s; want partial, online", invoice.PaymentStatus, invoice.PaymentMethod)
+	}
+}
+
+func TestHandlePaymentWebhookDuplicates(t *testing.T) {
+	db := paymentTestDB(t)
+	gin.SetMode(gin.TestMode)
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() { config.DB = previous })
+
+	gateway := services.NewFakeGateway("whsec")
+	controller := PaymentGatewayController{Gateway: gateway}
+	link := createTestPaymentLink(t, db, gateway, 500)
+
+	body, signature, err := gateway.Pay(link.ProviderLinkID, 500, "card")
+	if err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+
+	deliver := func(body []byte, signature string) (int, string) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/webhooks/payments", bytes.NewReader(body))
+		c.Request.Header.Set("X-Fake-Signature", signature)
+		controller.HandlePaymentWebhook(c)
+
+		var response struct {
+			Status string `json:"status"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Status
+	}
+
+	if code, status := deliver(body, signature); code != http.StatusOK || status != "processed" {
+		t.Fatalf("first delivery = %d %q, want 200 processed", code, status)
+	}
+
+	// The provider redelivers the same event
+	if code, status := deliver(body, signature); code != http.StatusOK || status != "duplicate" {
+		t.Fatalf("redelivery = %d %q, want 200 duplicate", code, status)
+	}
+
+	// A different event for a payment that was already posted
+	var event services.GatewayEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("Failed to decode webhook body: %v", err)
+	}
+	event.ID = "evt_other"
+	other, _ := json.Marshal(event)
+	otherSignature := signTestWebhook(other, "whsec")
+	if code, status := deliver(other, otherSignature); code != http.StatusOK || status != "processed" {
+		t.Fatalf("second event = %d %q, want 200 processed", code, status)
+	}
+
+	if code, _ := deliver(body, "00"); code != http.StatusUnauthorized {
+		t.Errorf("bad signature = %d, want 401", code)
+	}
+
+	assertPostedOnce(t, db, link, 500)
+
+	var events int64
+	db.Model(&models.PaymentWebhookEvent{}).Count(&events)
+	if events != 2 {
+		t.Errorf("recorded %d webhook events, want 2", events)
+	}
+}
+
+// signTestWebhook returns the hex HMAC-SHA256 the fake gateway expects
+func signTestWebhook(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,300 @@
+// controllers/product.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateProductInput defines the expected JSON structure for creating a retail product
+type CreateProductInput struct {
+	Name          string  `json:"name" binding:"required"`
+	SKU           string  `json:"sku" binding:"required"`
+	Brand         string  `json:"brand"`
+	Description   string  `json:"description"`
+	Category      string  `json:"category"`
+	Price         float64 `json:"price" binding:"required,min=0"`
+	TaxRate       float64 `json:"taxRate" binding:"min=0,max=100"`
+	StockQuantity int     `json:"stockQuantity" binding:"min=0"`
+}
+
+// UpdateProductInput defines the expected JSON structure for updating a retail product
+type UpdateProductInput struct {
+	Name          *string  `json:"name"`
+	SKU           *string  `json:"sku"`
+	Brand         *string  `json:"brand"`
+	Description   *string  `json:"description"`
+	Category      *string  `json:"category"`
+	Price         *float64 `json:"price" binding:"omitempty,min=0"`
+	TaxRate       *float64 `json:"taxRate" binding:"omitempty,min=0,max=100"`
+	StockQuantity *int     `json:"stockQuantity" binding:"omitempty,min=0"`
+	IsActive      *bool    `json:"isActive"`
+}
+
+// CreateProduct creates a new retail product for the salon
+func CreateProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input CreateProductInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	sku := strings.TrimSpace(input.SKU)
+
+	// Check if SKU already exists for this salon
+	var existingProduct models.Product
+	if err := config.DB.Where("salon_id = ? AND sku = ?", salonUUID, sku).
+		First(&existingProduct).Error; err == nil {
+		utils.RespondWithError(c, http.StatusConflict, "Product with this SKU already exists")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+
+	// Create new product
+	product := models.Product{
+		ID:            uuid.New(),
+		SalonID:       salonUUID,
+		Name:          input.Name,
+		SKU:           sku,
+		Brand:         input.Brand,
+		Description:   input.Description,
+		Category:      input.Category,
+		Price:         input.Price,
+		TaxRate:       input.TaxRate,
+		StockQuantity: input.StockQuantity,
+		IsActive:      true,
+	}
+
+	if err := config.DB.Create(&product).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create product")
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+// GetProducts retrieves all retail products for the salon
+func GetProducts(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var products []models.Product
+	if err := config.DB.Where("salon_id = ?", salonUUID).Order("name").Find(&products).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve products")
+		return
+	}
+
+	c.JSON(http.StatusOK, products)
+}
+
+// GetProduct retrieves a specific retail product by ID
+func GetProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// UpdateProduct updates an existing retail product
+func UpdateProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	var input UpdateProductInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Retrieve existing product
+	var product models.Product
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).
+		First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Update fields if provided
+	if input.Name != nil {
+		product.Name = *input.Name
+	}
+	if input.SKU != nil {
+		sku := strings.TrimSpace(*input.SKU)
+
+		// Check if SKU is being changed to one used by another product
+		if sku != product.SKU {
+			var existingProduct models.Product
+			if err := config.DB.Where("salon_id = ? AND sku = ?", salonUUID, sku).
+				First(&existingProduct).Error; err == nil {
+				utils.RespondWithError(c, http.StatusConflict, "Another product with this SKU already exists")
+				return
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+				return
+			}
+		}
+		product.SKU = sku
+	}
+	if input.Brand != nil {
+		product.Brand = *input.Brand
+	}
+	if input.Description != nil {
+		product.Description = *input.Description
+	}
+	if input.Category != nil {
+		product.Category = *input.Category
+	}
+	if input.Price != nil {
+		product.Price = *input.Price
+	}
+	if input.TaxRate != nil {
+		product.TaxRate = *input.TaxRate
+	}
+	if input.StockQuantity != nil {
+		product.StockQuantity = *input.StockQuantity
+	}
+	if input.IsActive != nil {
+		product.IsActive = *input.IsActive
+	}
+
+	if err := config.DB.Save(&product).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update product")
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+// DeleteProduct deletes a retail product
+func DeleteProduct(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	productUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid product ID format")
+		return
+	}
+
+	// A product that has been sold stays for the sales history and is deactivated instead
+	var sales int64
+	if err := config.DB.Model(&models.InvoiceItem{}).Where("product_id = ?", productUUID).Count(&sales).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if sales > 0 {
+		result := config.DB.Model(&models.Product{}).
+			Where("salon_id = ? AND id = ?", salonUUID, productUUID).
+			Update("is_active", false)
+		if result.Error != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to deactivate product")
+			return
+		}
+		if result.RowsAffected == 0 {
+			utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Product has sales history and was deactivated instead of deleted"})
+		return
+	}
+
+	result := config.DB.Where("salon_id = ? AND id = ?", salonUUID, productUUID).
+		Delete(&models.Product{})
+
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete product")
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusNotFound, "Product not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+}
@@ -5,6 +5,7 @@ import (
 	"salonpro-backend/config"
 	"salonpro-backend/models"
 	"salonpro-backend/utils"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -53,13 +54,17 @@ func GetProfile(c *gin.Context) {
 	}
 
 	// Extract messages
-	var birthdayMessage, anniversaryMessage string
+	var birthdayMessage, anniversaryMessage, membershipExpiryMessage, duesMessage string
 	for _, tmpl := range reminderTemplates {
 		switch tmpl.Type {
 		case "birthday":
 			birthdayMessage = tmpl.Message
 		case "anniversary":
 			anniversaryMessage = tmpl.Message
+		case "membership_expiry":
+			membershipExpiryMessage = tmpl.Message
+		case "dues":
+			duesMessage = tmpl.Message
 		}
 	}
 
@@ -73,14 +78,24 @@ func GetProfile(c *gin.Context) {
 			"workingHours": salon.WorkingHours,
 		},
 		"messageTemplates": gin.H{
-			"birthday":    birthdayMessage,
-			"anniversary": anniversaryMessage,
+			"birthday":         birthdayMessage,
+			"anniversary":      anniversaryMessage,
+			"membershipExpiry": membershipExpiryMessage,
+			"dues":             duesMessage,
+		},
+		"regionalSettings": salonRegionalSettings(salon),
+		"upiSettings": gin.H{
+			"upiId":     salon.UPIID,
+			"payeeName": salon.UPIPayeeName,
 		},
 		"notifications": gin.H{
-			"birthdayReminders":     salon.BirthdayReminders,
-			"anniversaryReminders":  salon.AnniversaryReminders,
-			"whatsAppNotifications": salon.WhatsAppNotifications,
-			"smsNotifications":      salon.SMSNotifications,
+			"birthdayReminders":        salon.BirthdayReminders,
+			"anniversaryReminders":     salon.AnniversaryReminders,
+			"whatsAppNotifications":    salon.WhatsAppNotifications,
+			"smsNotifications":         salon.SMSNotifications,
+			"duesReminders":            salon.DuesReminders,
+			"duesReminderIntervalDays": salon.DuesReminderIntervalDays,
+			"marketingRequiresOptIn":   salon.MarketingRequiresOptIn,
 		},
 	})
 }
@@ -189,6 +204,9 @@ func UpdateWorkingHours(c *gin.Context) {
 type UpdateTemplatesInput struct {
 	BirthdayMessage    string `json:"birthday" form:"birthday" binding:"omitempty"`
 	AnniversaryMessage string `json:"anniversary" form:"anniversary" binding:"omitempty"`
+	// Salons created before these types have no row for them, so they are only touched when sent
+	MembershipExpiryMessage string `json:"membershipExpiry" form:"membershipExpiry" binding:"omitempty"`
+	DuesMessage             string `json:"dues" form:"dues" binding:"omitempty"`
 }
 
 func UpdateReminderTemplates(c *gin.Context) {
@@ -226,6 +244,36 @@ func UpdateReminderTemplates(c *gin.Context) {
 		}
 	}
 
+	optional := []struct {
+		Type    string
+		Message string
+	}{
+		{"membership_expiry", input.MembershipExpiryMessage},
+		{"dues", input.DuesMessage},
+	}
+
+	for _, u := range optional {
+		if u.Message == "" {
+			continue
+		}
+		result := config.DB.Model(&models.ReminderTemplate{}).
+			Where("salon_id = ? AND type = ?", salonUUID, u.Type).
+			Update("message", u.Message)
+		if result.Error == nil && result.RowsAffected == 0 {
+			result = config.DB.Create(&models.ReminderTemplate{
+				ID:       uuid.New(),
+				SalonID:  salonUUID,
+				Type:     u.Type,
+				Message:  u.Message,
+				IsActive: true,
+			})
+		}
+		if result.Error != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update "+u.Type+" template")
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Templates updated successfully"})
 }
 
@@ -234,6 +282,11 @@ type UpdateNotificationsInput struct {
 	AnniversaryReminders  bool `json:"anniversaryReminders"`
 	WhatsAppNotifications bool `json:"whatsAppNotifications"`
 	SMSNotifications      bool `json:"smsNotifications"`
+
+	// Optional so older clients that do not send them leave the settings alone
+	DuesReminders            *bool `json:"duesReminders"`
+	DuesReminderIntervalDays *int  `json:"duesReminderIntervalDays" binding:"omitempty,min=1"`
+	MarketingRequiresOptIn   *bool `json:"marketingRequiresOptIn"`
 }
 
 func UpdateNotifications(c *gin.Context) {
@@ -254,17 +307,136 @@ func UpdateNotifications(c *gin.Context) {
 		return
 	}
 
+	updates := map[string]interface{}{
+		"birthday_reminders":      input.BirthdayReminders,
+		"anniversary_reminders":   input.AnniversaryReminders,
+		"whats_app_notifications": input.WhatsAppNotifications,
+		"sms_notifications":       input.SMSNotifications,
+	}
+	if input.DuesReminders != nil {
+		updates["dues_reminders"] = *input.DuesReminders
+	}
+	if input.DuesReminderIntervalDays != nil {
+		updates["dues_reminder_interval_days"] = *input.DuesReminderIntervalDays
+	}
+	if input.MarketingRequiresOptIn != nil {
+		updates["marketing_requires_opt_in"] = *input.MarketingRequiresOptIn
+	}
+
 	if err := config.DB.Model(&models.Salon{}).
 		Where("id = ?", salonUUID).
-		Updates(map[string]interface{}{
-			"birthday_reminders":      input.BirthdayReminders,
-			"anniversary_reminders":   input.AnniversaryReminders,
-			"whats_app_notifications": input.WhatsAppNotifications,
-			"sms_notifications":       input.SMSNotifications,
-		}).Error; err != nil {
+		Updates(updates).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update notifications")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Notification settings updated successfully"})
 }
+
+type UpdateRegionalSettingsInput struct {
+	CurrencyCode  string `json:"currencyCode" binding:"required,len=3"`
+	DecimalPlaces *int   `json:"decimalPlaces" binding:"omitempty,min=0,max=3"`
+	Locale        string `json:"locale" binding:"required"`
+}
+
+func UpdateRegionalSettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var input UpdateRegionalSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	currencyCode := strings.ToUpper(input.CurrencyCode)
+	if !utils.ValidateCurrencyCode(currencyCode) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Unknown currency code")
+		return
+	}
+	if !utils.ValidateLocale(input.Locale) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid locale")
+		return
+	}
+
+	decimalPlaces := utils.CurrencyDecimalPlaces(currencyCode)
+	if input.DecimalPlaces != nil {
+		decimalPlaces = *input.DecimalPlaces
+	}
+
+	if err := config.DB.Model(&models.Salon{}).
+		Where("id = ?", salonUUID).
+		Updates(map[string]interface{}{
+			"currency_code":  currencyCode,
+			"decimal_places": decimalPlaces,
+			"locale":         input.Locale,
+		}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update regional settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Regional settings updated successfully"})
+}
+
+// UpdateUPISettingsInput sets the UPI ID printed on invoice QR codes; an empty
+// upiId turns UPI payments off
+type UpdateUPISettingsInput struct {
+	UPIID     string `json:"upiId"`
+	PayeeName string `json:"payeeName" binding:"max=100"`
+}
+
+func UpdateUPISettings(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found")
+		return
+	}
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid salon ID")
+		return
+	}
+
+	var input UpdateUPISettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	upiID := strings.TrimSpace(input.UPIID)
+	if upiID != "" && !utils.ValidateUPIID(upiID) {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid UPI ID, expected name@bank")
+		return
+	}
+
+	if err := config.DB.Model(&models.Salon{}).
+		Where("id = ?", salonUUID).
+		Updates(map[string]interface{}{
+			"upi_id":         upiID,
+			"upi_payee_name": strings.TrimSpace(input.PayeeName),
+		}).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update UPI settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "UPI settings updated successfully"})
+}
+
+// salonRegionalSettings is the currency and locale block returned to the frontend
+func salonRegionalSettings(salon models.Salon) gin.H {
+	format := salon.MoneyFormat()
+	return gin.H{
+		"currencyCode":   format.CurrencyCode,
+		"currencySymbol": format.Symbol(),
+		"decimalPlaces":  format.DecimalPlaces,
+		"locale":         format.Locale,
+	}
+}
@@ -0,0 +1,348 @@
+// controllers/receivable.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordInvoicePaymentInput defines a payment collected against an existing invoice
+type RecordInvoicePaymentInput struct {
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Method    string  `json:"method" binding:"required"`
+	Reference string  `json:"reference"`
+}
+
+// CustomerReceivable is the amount a customer owes, split by invoice age
+type CustomerReceivable struct {
+	CustomerID        uuid.UUID `json:"customerId"`
+	CustomerName      string    `json:"customerName"`
+	CustomerPhone     string    `json:"customerPhone"`
+	InvoiceCount      int       `json:"invoiceCount"`
+	Outstanding       float64   `json:"outstanding"`
+	Days0To30         float64   `json:"days0To30"`
+	Days31To60        float64   `json:"days31To60"`
+	Days61Plus        float64   `json:"days61Plus"`
+	OldestInvoiceDate time.Time `json:"oldestInvoiceDate"`
+}
+
+// StatementLine is one charge or payment on a customer's statement of account
+type StatementLine struct {
+	Date          time.Time  `json:"date"`
+	Type          string     `json:"type"` // 'invoice' or 'payment'
+	InvoiceID     uuid.UUID  `json:"invoiceId"`
+	InvoiceNumber string     `json:"invoiceNumber"`
+	Description   string     `json:"description"`
+	Debit         float64    `json:"debit"`
+	Credit        float64    `json:"credit"`
+	Balance       float64    `json:"balance"`
+	PaymentID     *uuid.UUID `json:"paymentId,omitempty"`
+}
+
+// GetReceivables lists what each customer owes on unpaid and part-paid invoices,
+// with aging buckets of 0-30, 31-60 and 61+ days from the invoice date
+func GetReceivables(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	today := utils.BeginningOfDay(time.Now())
+	cutoff30 := today.AddDate(0, 0, -30)
+	cutoff60 := today.AddDate(0, 0, -60)
+
+	var receivables []CustomerReceivable
+	if err := config.DB.Raw(`
+		SELECT c.id as customer_id,
+			   c.name as customer_name,
+			   c.phone as customer_phone,
+			   COUNT(i.id) as invoice_count,
+			   SUM(i.total - i.paid_amount) as outstanding,
+			   COALESCE(SUM(i.total - i.paid_amount) FILTER (WHERE i.invoice_date >= ?), 0) as days0_to30,
+			   COALESCE(SUM(i.total - i.paid_amount) FILTER (WHERE i.invoice_date < ? AND i.invoice_date >= ?), 0) as days31_to60,
+			   COALESCE(SUM(i.total - i.paid_amount) FILTER (WHERE i.invoice_date < ?), 0) as days61_plus,
+			   MIN(i.invoice_date) as oldest_invoice_date
+		FROM invoices i
+		INNER JOIN customers c ON c.id = i.customer_id
+		WHERE i.salon_id = ?
+		  AND i.payment_status IN ('unpaid', 'partial')
+		  AND i.total - i.paid_amount > 0.005
+		  AND i.deleted_at IS NULL
+		GROUP BY c.id, c.name, c.phone
+		ORDER BY outstanding DESC
+	`, cutoff30, cutoff30, cutoff60, cutoff60, salonUUID).Scan(&receivables).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve receivables")
+		return
+	}
+
+	var total, days0To30, days31To60, days61Plus float64
+	for _, r := range receivables {
+		total += r.Outstanding
+		days0To30 += r.Days0To30
+		days31To60 += r.Days31To60
+		days61Plus += r.Days61Plus
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"totalOutstanding": total,
+		"aging": gin.H{
+			"days0To30":  days0To30,
+			"days31To60": days31To60,
+			"days61Plus": days61Plus,
+		},
+		"customers": receivables,
+	})
+}
+
+// GetCustomerStatement returns a statement of account of invoices and payments
+// between ?from= and ?to= (YYYY-MM-DD, default the last 90 days) with running balance
+func GetCustomerStatement(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	now := time.Now()
+	end := utils.BeginningOfDay(now).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -90)
+	if from := c.Query("from"); from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, now.Location()); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.ParseInLocation("2006-01-02", to, now.Location())
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		end = toDate.AddDate(0, 0, 1)
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	// Balance brought forward: everything invoiced before the period less everything paid
+	var opening struct {
+		Invoiced float64
+		Paid     float64
+	}
+	if err := config.DB.Raw(`
+		SELECT
+			(SELECT COALESCE(SUM(total), 0) FROM invoices
+			 WHERE customer_id = ? AND invoice_date < ? AND deleted_at IS NULL) as invoiced,
+			(SELECT COALESCE(SUM(p.amount), 0) FROM invoice_payments p
+			 INNER JOIN invoices i ON i.id = p.invoice_id
+			 WHERE i.customer_id = ? AND p.paid_at < ? AND i.deleted_at IS NULL) as paid
+	`, customer.ID, start, customer.ID, start).Scan(&opening).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build statement")
+		return
+	}
+
+	var invoices []models.Invoice
+	if err := config.DB.Where("customer_id = ? AND invoice_date >= ? AND invoice_date < ?", customer.ID, start, end).
+		Find(&invoices).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build statement")
+		return
+	}
+
+	var payments []struct {
+		models.InvoicePayment
+		InvoiceNumber string
+	}
+	if err := config.DB.Raw(`
+		SELECT p.*, i.invoice_number
+		FROM invoice_payments p
+		INNER JOIN invoices i ON i.id = p.invoice_id
+		WHERE i.customer_id = ? AND p.paid_at >= ? AND p.paid_at < ? AND i.deleted_at IS NULL
+	`, customer.ID, start, end).Scan(&payments).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build statement")
+		return
+	}
+
+	lines := make([]StatementLine, 0, len(invoices)+len(payments))
+	for _, inv := range invoices {
+		lines = append(lines, StatementLine{
+			Date:          inv.InvoiceDate,
+			Type:          "invoice",
+			InvoiceID:     inv.ID,
+			InvoiceNumber: inv.InvoiceNumber,
+			Description:   "Invoice " + inv.InvoiceNumber,
+			Debit:         inv.Total,
+		})
+	}
+	for _, p := range payments {
+		paymentID := p.ID
+		line := StatementLine{
+			Date:          p.PaidAt,
+			Type:          "payment",
+			InvoiceID:     p.InvoiceID,
+			InvoiceNumber: p.InvoiceNumber,
+			Description:   "Payment (" + p.Method + ") for " + p.InvoiceNumber,
+			PaymentID:     &paymentID,
+		}
+		if p.Amount >= 0 {
+			line.Credit = p.Amount
+		} else {
+			line.Debit = -p.Amount
+		}
+		lines = append(lines, line)
+	}
+
+	// Charges before payments made at the same moment
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].Date.Equal(lines[j].Date) {
+			return lines[i].Type == "invoice" && lines[j].Type != "invoice"
+		}
+		return lines[i].Date.Before(lines[j].Date)
+	})
+
+	openingBalance := opening.Invoiced - opening.Paid
+	balance := openingBalance
+	var debits, credits float64
+	for i := range lines {
+		balance += lines[i].Debit - lines[i].Credit
+		lines[i].Balance = math.Round(balance*100) / 100
+		debits += lines[i].Debit
+		credits += lines[i].Credit
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerId":     customer.ID,
+		"customerName":   customer.Name,
+		"from":           start.Format("2006-01-02"),
+		"to":             end.AddDate(0, 0, -1).Format("2006-01-02"),
+		"openingBalance": openingBalance,
+		"debits":         debits,
+		"credits":        credits,
+		"closingBalance": balance,
+		"lines":          lines,
+	})
+}
+
+// RecordInvoicePayment collects a payment against an invoice's outstanding balance
+func RecordInvoicePayment(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return
+	}
+
+	var input RecordInvoicePaymentInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// Start transaction
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Lock the invoice so a gateway payment posted at the same time cannot slip
+	// past the amount due check
+	var invoice models.Invoice
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if input.Amount > invoice.Total-invoice.PaidAmount+0.005 {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusBadRequest, "Payment exceeds the amount due")
+		return
+	}
+
+	userUUID := uuid.Must(uuid.Parse(userID.(string)))
+	if err := recordTenderPayment(tx, &invoice, models.InvoicePayment{
+		CreatedByUserID: userUUID,
+		Method:          input.Method,
+		Amount:          math.Round(input.Amount*100) / 100,
+		Reference:       input.Reference,
+	}); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record payment")
+		return
+	}
+
+	if err := awardLoyaltyPoints(tx, invoice, userUUID); err != nil {
+		tx.Rollback()
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to award loyalty points")
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Transaction commit failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invoice)
+}
@@ -0,0 +1,517 @@
+// controllers/register.go
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OpenRegisterSessionInput defines the expected JSON structure for opening the cash drawer
+type OpenRegisterSessionInput struct {
+	OpeningFloat float64 `json:"openingFloat" binding:"min=0"`
+	Notes        string  `json:"notes"`
+}
+
+// CashMovementInput defines a pay-in or pay-out during a session
+type CashMovementInput struct {
+	Type   string  `json:"type" binding:"required,oneof=pay_in pay_out"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+// CloseRegisterSessionInput defines the expected JSON structure for closing the cash drawer
+type CloseRegisterSessionInput struct {
+	CountedCash *float64 `json:"countedCash" binding:"required,min=0"`
+	Notes       string   `json:"notes"`
+}
+
+// ZReport summarises everything that went through the drawer during a session
+type ZReport struct {
+	SessionID    uuid.UUID  `json:"sessionId"`
+	OpenedAt     time.Time  `json:"openedAt"`
+	ClosedAt     *time.Time `json:"closedAt"`
+	OpeningFloat float64    `json:"openingFloat"`
+
+	PaymentsByMethod     map[string]float64 `json:"paymentsByMethod"`
+	WalletTopUpsByMethod map[string]float64 `json:"walletTopUpsByMethod"`
+	RefundsByMethod      map[string]float64 `json:"refundsByMethod"`
+	PayIns               float64            `json:"payIns"`
+	PayOuts              float64            `json:"payOuts"`
+
+	InvoiceCount     int     `json:"invoiceCount"`
+	InvoiceDiscounts float64 `json:"invoiceDiscounts"` // manual and coupon discounts
+	LineDiscounts    float64 `json:"lineDiscounts"`    // member and package benefits
+	VoidCount        int     `json:"voidCount"`
+	VoidTotal        float64 `json:"voidTotal"`
+
+	ExpectedCash float64  `json:"expectedCash"`
+	CountedCash  *float64 `json:"countedCash"`
+	Variance     *float64 `json:"variance"`
+}
+
+// OpenRegisterSession opens the salon's cash drawer with an opening float
+func OpenRegisterSession(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var input OpenRegisterSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	// The salon has one drawer, so only one session can be open at a time
+	var open int64
+	if err := config.DB.Model(&models.RegisterSession{}).
+		Where("salon_id = ? AND status = 'open'", salonUUID).
+		Count(&open).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if open > 0 {
+		utils.RespondWithError(c, http.StatusConflict, "A register session is already open")
+		return
+	}
+
+	session := models.RegisterSession{
+		ID:             uuid.New(),
+		SalonID:        salonUUID,
+		OpenedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		Status:         "open",
+		OpeningFloat:   input.OpeningFloat,
+		OpenedAt:       time.Now(),
+		Notes:          input.Notes,
+	}
+
+	if err := config.DB.Create(&session).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to open register session")
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// GetRegisterSessions lists the salon's register sessions, newest first
+func GetRegisterSessions(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var sessions []models.RegisterSession
+	if err := config.DB.Omit("ZReport").
+		Where("salon_id = ?", salonUUID).
+		Order("opened_at DESC").
+		Limit(100).
+		Find(&sessions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to retrieve register sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetCurrentRegisterSession returns the open session with a running report
+func GetCurrentRegisterSession(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var session models.RegisterSession
+	if err := config.DB.Preload("Movements").
+		Where("salon_id = ? AND status = 'open'", salonUUID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "No register session is open")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	report, err := buildZReport(config.DB, session, time.Now())
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build register report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"report":  report,
+	})
+}
+
+// GetRegisterSession returns a session with its Z report, or a running report if still open
+func GetRegisterSession(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	sessionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid session ID format")
+		return
+	}
+
+	var session models.RegisterSession
+	if err := config.DB.Preload("Movements").
+		Where("salon_id = ? AND id = ?", salonUUID, sessionUUID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Register session not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	if session.Status == "closed" {
+		c.JSON(http.StatusOK, gin.H{
+			"session": session,
+			"report":  session.ZReport,
+		})
+		return
+	}
+
+	report, err := buildZReport(config.DB, session, time.Now())
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build register report")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"report":  report,
+	})
+}
+
+// AddCashMovement records petty cash paid into or out of the open drawer
+func AddCashMovement(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	sessionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid session ID format")
+		return
+	}
+
+	var input CashMovementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var session models.RegisterSession
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, sessionUUID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Register session not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if session.Status != "open" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Register session is closed")
+		return
+	}
+
+	movement := models.CashMovement{
+		ID:              uuid.New(),
+		SalonID:         salonUUID,
+		SessionID:       session.ID,
+		CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+		Type:            input.Type,
+		Amount:          input.Amount,
+		Reason:          input.Reason,
+	}
+
+	if err := config.DB.Create(&movement).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record cash movement")
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// CloseRegisterSession closes the drawer with the counted cash and produces the Z report
+func CloseRegisterSession(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	sessionUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid session ID format")
+		return
+	}
+
+	var input CloseRegisterSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var session models.RegisterSession
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, sessionUUID).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Register session not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+	if session.Status != "open" {
+		utils.RespondWithError(c, http.StatusBadRequest, "Register session is already closed")
+		return
+	}
+
+	closedAt := time.Now()
+	report, err := buildZReport(config.DB, session, closedAt)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build Z report")
+		return
+	}
+
+	variance := math.Round((*input.CountedCash-report.ExpectedCash)*100) / 100
+	report.ClosedAt = &closedAt
+	report.CountedCash = input.CountedCash
+	report.Variance = &variance
+
+	snapshot, err := zReportSnapshot(report)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build Z report")
+		return
+	}
+
+	closedBy := uuid.Must(uuid.Parse(userID.(string)))
+	notes := session.Notes
+	if input.Notes != "" {
+		if notes != "" {
+			notes += "\n"
+		}
+		notes += input.Notes
+	}
+
+	// Guard on status so two people closing at once cannot both succeed
+	result := config.DB.Model(&models.RegisterSession{}).
+		Where("id = ? AND status = 'open'", session.ID).
+		Updates(map[string]interface{}{
+			"status":            "closed",
+			"closed_by_user_id": closedBy,
+			"closed_at":         closedAt,
+			"expected_cash":     report.ExpectedCash,
+			"counted_cash":      *input.CountedCash,
+			"variance":          variance,
+			"z_report":          snapshot,
+			"notes":             notes,
+		})
+	if result.Error != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to close register session")
+		return
+	}
+	if result.RowsAffected == 0 {
+		utils.RespondWithError(c, http.StatusConflict, "Register session was closed by someone else")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// buildZReport totals payments, top-ups, cash movements, discounts and voids
+// between the session opening and until
+func buildZReport(db *gorm.DB, session models.RegisterSession, until time.Time) (ZReport, error) {
+	report := ZReport{
+		SessionID:            session.ID,
+		OpenedAt:             session.OpenedAt,
+		OpeningFloat:         session.OpeningFloat,
+		PaymentsByMethod:     map[string]float64{},
+		WalletTopUpsByMethod: map[string]float64{},
+		RefundsByMethod:      map[string]float64{},
+	}
+	from := session.OpenedAt
+
+	type methodTotal struct {
+		Method string
+		Total  float64
+	}
+
+	var payments []methodTotal
+	if err := db.Raw(`
+		SELECT method, SUM(amount) as total
+		FROM invoice_payments
+		WHERE salon_id = ? AND paid_at >= ? AND paid_at < ?
+		GROUP BY method
+	`, session.SalonID, from, until).Scan(&payments).Error; err != nil {
+		return report, err
+	}
+	for _, p := range payments {
+		report.PaymentsByMethod[p.Method] += p.Total
+	}
+
+	var topUps []methodTotal
+	if err := db.Raw(`
+		SELECT payment_method as method, SUM(amount) as total
+		FROM wallet_transactions
+		WHERE salon_id = ? AND type = 'topup' AND created_at >= ? AND created_at < ?
+		GROUP BY payment_method
+	`, session.SalonID, from, until).Scan(&topUps).Error; err != nil {
+		return report, err
+	}
+	for _, t := range topUps {
+		report.WalletTopUpsByMethod[t.Method] += t.Total
+	}
+
+	var movements struct {
+		PayIns  float64
+		PayOuts float64
+	}
+	if err := db.Raw(`
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE type = 'pay_in'), 0) as pay_ins,
+			COALESCE(SUM(amount) FILTER (WHERE type = 'pay_out'), 0) as pay_outs
+		FROM cash_movements
+		WHERE session_id = ?
+	`, session.ID).Scan(&movements).Error; err != nil {
+		return report, err
+	}
+	report.PayIns = movements.PayIns
+	report.PayOuts = movements.PayOuts
+
+	var invoices struct {
+		InvoiceCount     int
+		InvoiceDiscounts float64
+		LineDiscounts    float64
+	}
+	if err := db.Raw(`
+		SELECT
+			COUNT(*) as invoice_count,
+			COALESCE(SUM(i.discount), 0) as invoice_discounts,
+			COALESCE(SUM((SELECT SUM(ii.discount_amount) FROM invoice_items ii WHERE ii.invoice_id = i.id)), 0) as line_discounts
+		FROM invoices i
+		WHERE i.salon_id = ? AND i.invoice_date >= ? AND i.invoice_date < ? AND i.deleted_at IS NULL
+	`, session.SalonID, from, until).Scan(&invoices).Error; err != nil {
+		return report, err
+	}
+	report.InvoiceCount = invoices.InvoiceCount
+	report.InvoiceDiscounts = invoices.InvoiceDiscounts
+	report.LineDiscounts = invoices.LineDiscounts
+
+	// Payments on voided invoices were deleted with them: ones taken during this
+	// session still count as takings, and every voided payment is handed back now
+	var voids []models.InvoiceVoid
+	if err := db.Where("salon_id = ? AND voided_at >= ? AND voided_at < ?", session.SalonID, from, until).
+		Find(&voids).Error; err != nil {
+		return report, err
+	}
+	for _, void := range voids {
+		report.VoidCount++
+		report.VoidTotal += void.Total
+		for _, p := range void.Payments {
+			if !p.PaidAt.Before(from) {
+				report.PaymentsByMethod[p.Method] += p.Amount
+			}
+			report.RefundsByMethod[p.Method] += p.Amount
+		}
+	}
+
+	expected := report.OpeningFloat +
+		report.PaymentsByMethod["cash"] +
+		report.WalletTopUpsByMethod["cash"] +
+		report.PayIns -
+		report.PayOuts -
+		report.RefundsByMethod["cash"]
+	report.ExpectedCash = math.Round(expected*100) / 100
+
+	return report, nil
+}
+
+// zReportSnapshot converts the report into the JSONB stored on the session
+func zReportSnapshot(report ZReport) (models.JSONB, error) {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot models.JSONB
+	err = json.Unmarshal(data, &snapshot)
+	return snapshot, err
+}
@@ -0,0 +1,124 @@
+// controllers/register_test.go
+package controllers
+
+import (
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBuildZReport(t *testing.T) {
+	opened := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	closed := opened.Add(10 * time.Hour)
+	before := opened.Add(-24 * time.Hour)
+	during := opened.Add(2 * time.Hour)
+
+	tests := []struct {
+		name      string
+		payments  []models.InvoicePayment
+		topUps    []models.WalletTransaction
+		movements []models.CashMovement
+		voids     []models.InvoiceVoid
+		want      float64 // expected cash
+	}{
+		{
+			name: "opening float only",
+			want: 2000,
+		},
+		{
+			name: "cash takings and top-ups",
+			payments: []models.InvoicePayment{
+				{Method: "cash", Amount: 1200, PaidAt: during},
+				{Method: "card", Amount: 800, PaidAt: during},
+				{Method: "cash", Amount: 500, PaidAt: before}, // yesterday's session
+			},
+			topUps: []models.WalletTransaction{
+				{Type: "topup", PaymentMethod: "cash", Amount: 300, CreatedAt: during},
+				{Type: "topup", PaymentMethod: "upi", Amount: 700, CreatedAt: during},
+			},
+			want: 3500,
+		},
+		{
+			name: "pay-ins and pay-outs",
+			payments: []models.InvoicePayment{
+				{Method: "cash", Amount: 1000, PaidAt: during},
+			},
+			movements: []models.CashMovement{
+				{Type: "pay_in", Amount: 500, Reason: "Change"},
+				{Type: "pay_out", Amount: 250.5, Reason: "Milk"},
+			},
+			want: 3249.5,
+		},
+		{
+			name: "void of a payment taken this session nets out",
+			voids: []models.InvoiceVoid{
+				{Total: 600, VoidedAt: during, Payments: models.VoidedPaymentList{{Method: "cash", Amount: 600, PaidAt: during}}},
+			},
+			want: 2000,
+		},
+		{
+			name: "void of an earlier payment is handed back",
+			voids: []models.InvoiceVoid{
+				{Total: 600, VoidedAt: during, Payments: models.VoidedPaymentList{{Method: "cash", Amount: 400, PaidAt: before}}},
+			},
+			want: 1600,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, &models.Invoice{}, &models.InvoiceItem{}, &models.InvoicePayment{},
+				&models.WalletTransaction{}, &models.RegisterSession{}, &models.CashMovement{}, &models.InvoiceVoid{})
+			userID := uuid.New()
+			session := models.RegisterSession{
+				ID:             uuid.New(),
+				SalonID:        uuid.New(),
+				OpenedByUserID: userID,
+				Status:         "open",
+				OpeningFloat:   2000,
+				OpenedAt:       opened,
+			}
+			if err := db.Create(&session).Error; err != nil {
+				t.Fatalf("Failed to create session: %v", err)
+			}
+
+			for _, p := range tt.payments {
+				p.ID, p.SalonID, p.InvoiceID, p.CreatedByUserID = uuid.New(), session.SalonID, uuid.New(), userID
+				if err := db.Create(&p).Error; err != nil {
+					t.Fatalf("Failed to create payment: %v", err)
+				}
+			}
+			for _, w := range tt.topUps {
+				w.ID, w.SalonID, w.CustomerID, w.CreatedByUserID = uuid.New(), session.SalonID, uuid.New(), userID
+				if err := db.Create(&w).Error; err != nil {
+					t.Fatalf("Failed to create top-up: %v", err)
+				}
+			}
+			for _, m := range tt.movements {
+				m.ID, m.SalonID, m.SessionID, m.CreatedByUserID = uuid.New(), session.SalonID, session.ID, userID
+				if err := db.Create(&m).Error; err != nil {
+					t.Fatalf("Failed to create cash movement: %v", err)
+				}
+			}
+			for _, v := range tt.voids {
+				v.ID, v.SalonID, v.InvoiceID, v.CustomerID, v.VoidedByUserID = uuid.New(), session.SalonID, uuid.New(), uuid.New(), userID
+				v.InvoiceNumber = "INV-" + v.InvoiceID.String()[:8]
+				if err := db.Create(&v).Error; err != nil {
+					t.Fatalf("Failed to create void: %v", err)
+				}
+			}
+
+			report, err := buildZReport(db, session, closed)
+			if err != nil {
+				t.Fatalf("buildZReport() error = %v", err)
+			}
+			if report.ExpectedCash != tt.want {
+				t.Errorf("expected cash = %v, want %v", report.ExpectedCash, tt.want)
+			}
+			if report.VoidCount != len(tt.voids) {
+				t.Errorf("void count = %d, want %d", report.VoidCount, len(tt.voids))
+			}
+		})
+	}
+}
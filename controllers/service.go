@@ -20,6 +20,9 @@ type CreateServiceInput struct {
 	Price       float64 `json:"price" binding:"required,min=0"`
 	Duration    int     `json:"duration" binding:"min=0"` // in minutes
 	Category    string  `json:"category"`
+
+	RequiresPatchTest  bool `json:"requiresPatchTest"`
+	PatchTestValidDays int  `json:"patchTestValidDays" binding:"min=0"`
 }
 
 // UpdateServiceInput defines the expected JSON structure for updating a service
@@ -30,6 +33,9 @@ type UpdateServiceInput struct {
 	Duration    *int     `json:"duration"`
 	Category    *string  `json:"category"`
 	IsActive    *bool    `json:"isActive"`
+
+	RequiresPatchTest  *bool `json:"requiresPatchTest"`
+	PatchTestValidDays *int  `json:"patchTestValidDays" binding:"omitempty,min=0"`
 }
 
 // CreateService creates a new service for the salon
@@ -62,6 +68,9 @@ func CreateService(c *gin.Context) {
 		Duration:    input.Duration,
 		Category:    input.Category,
 		IsActive:    true,
+
+		RequiresPatchTest:  input.RequiresPatchTest,
+		PatchTestValidDays: input.PatchTestValidDays,
 	}
 
 	if err := config.DB.Create(&service).Error; err != nil {
@@ -188,6 +197,12 @@ func UpdateService(c *gin.Context) {
 	if input.IsActive != nil {
 		service.IsActive = *input.IsActive
 	}
+	if input.RequiresPatchTest != nil {
+		service.RequiresPatchTest = *input.RequiresPatchTest
+	}
+	if input.PatchTestValidDays != nil {
+		service.PatchTestValidDays = *input.PatchTestValidDays
+	}
 
 	if err := config.DB.Save(&service).Error; err != nil {
 		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update service")
@@ -0,0 +1,792 @@
+// controllers/service_record.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ServiceRecordInput defines the expected JSON structure for recording a visit's
+// technical notes. InvoiceItemID links the record to a service line on one of
+// the customer's invoices; its service and date are used when not given.
+type ServiceRecordInput struct {
+	InvoiceItemID     *uuid.UUID `json:"invoiceItemId"`
+	ServiceID         *uuid.UUID `json:"serviceId"`
+	PerformedAt       *time.Time `json:"performedAt"`
+	Formula           string     `json:"formula"`
+	Developer         string     `json:"developer"`
+	ProcessingMinutes int        `json:"processingMinutes" binding:"min=0"`
+	Result            string     `json:"result"`
+	Notes             string     `json:"notes"`
+}
+
+// UpdateServiceRecordInput defines the expected JSON structure for updating a service record
+type UpdateServiceRecordInput struct {
+	PerformedAt       *time.Time `json:"performedAt"`
+	Formula           *string    `json:"formula"`
+	Developer         *string    `json:"developer"`
+	ProcessingMinutes *int       `json:"processingMinutes" binding:"omitempty,min=0"`
+	Result            *string    `json:"result"`
+	Notes             *string    `json:"notes"`
+}
+
+// AllergyInput defines the expected JSON structure for recording an allergy
+type AllergyInput struct {
+	Allergen string `json:"allergen" binding:"required"`
+	Severity string `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	Reaction string `json:"reaction"`
+	Notes    string `json:"notes"`
+}
+
+// UpdateAllergyInput defines the expected JSON structure for updating an allergy
+type UpdateAllergyInput struct {
+	Allergen *string `json:"allergen"`
+	Severity *string `json:"severity" binding:"omitempty,oneof=mild moderate severe"`
+	Reaction *string `json:"reaction"`
+	Notes    *string `json:"notes"`
+}
+
+// PatchTestInput defines the expected JSON structure for recording a patch test.
+// ExpiresAt defaults to the service's validity period, or DefaultPatchTestValidDays.
+type PatchTestInput struct {
+	ServiceID *uuid.UUID `json:"serviceId"`
+	Product   string     `json:"product"`
+	TestedAt  *time.Time `json:"testedAt"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Result    string     `json:"result" binding:"omitempty,oneof=pending passed failed"`
+	Notes     string     `json:"notes"`
+}
+
+// UpdatePatchTestInput records a patch test's result once it has been read
+type UpdatePatchTestInput struct {
+	Result    *string    `json:"result" binding:"omitempty,oneof=pending passed failed"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	Notes     *string    `json:"notes"`
+}
+
+// GetServiceRecords lists the customer's service records, newest first.
+// ?serviceId= narrows them to one service.
+func GetServiceRecords(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID)
+	if serviceID := c.Query("serviceId"); serviceID != "" {
+		serviceUUID, err := uuid.Parse(serviceID)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid service ID format")
+			return
+		}
+		query = query.Where("service_id = ?", serviceUUID)
+	}
+
+	var records []models.ServiceRecord
+	if err := query.Order("performed_at DESC").Find(&records).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch service records")
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+// CreateServiceRecord records the technical notes from one of the customer's visits
+func CreateServiceRecord(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var input ServiceRecordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	record := models.ServiceRecord{
+		ID:                uuid.New(),
+		SalonID:           salonUUID,
+		CustomerID:        customer.ID,
+		ServiceID:         input.ServiceID,
+		RecordedByUserID:  userUUID,
+		PerformedAt:       time.Now(),
+		Formula:           input.Formula,
+		Developer:         input.Developer,
+		ProcessingMinutes: input.ProcessingMinutes,
+		Result:            input.Result,
+		Notes:             input.Notes,
+	}
+
+	if input.InvoiceItemID != nil {
+		var line struct {
+			ServiceID   *uuid.UUID
+			InvoiceDate time.Time
+		}
+		result := config.DB.Table("invoice_items ii").
+			Select("ii.service_id, i.invoice_date").
+			Joins("JOIN invoices i ON i.id = ii.invoice_id").
+			Where("ii.id = ? AND ii.item_type = 'service' AND i.salon_id = ? AND i.customer_id = ? AND i.deleted_at IS NULL",
+				*input.InvoiceItemID, salonUUID, customer.ID).
+			Limit(1).
+			Scan(&line)
+		if result.Error != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if result.RowsAffected == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invoice item not found among this customer's services")
+			return
+		}
+		if input.ServiceID != nil && line.ServiceID != nil && *input.ServiceID != *line.ServiceID {
+			utils.RespondWithError(c, http.StatusBadRequest, "Service does not match the invoice item")
+			return
+		}
+		record.InvoiceItemID = input.InvoiceItemID
+		record.ServiceID = line.ServiceID
+		record.PerformedAt = line.InvoiceDate
+	} else if input.ServiceID != nil {
+		var count int64
+		if err := config.DB.Model(&models.Service{}).Where("salon_id = ? AND id = ?", salonUUID, *input.ServiceID).Count(&count).Error; err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			return
+		}
+		if count == 0 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Service not found")
+			return
+		}
+	}
+	if input.PerformedAt != nil {
+		record.PerformedAt = *input.PerformedAt
+	}
+
+	if err := config.DB.Create(&record).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to create service record")
+		return
+	}
+
+	c.JSON(http.StatusCreated, record)
+}
+
+// UpdateServiceRecord corrects a service record's notes
+func UpdateServiceRecord(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var record models.ServiceRecord
+	if !loadCustomerRecord(c, salonUUID, "recordId", "Service record", &record) {
+		return
+	}
+
+	var input UpdateServiceRecordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.PerformedAt != nil {
+		record.PerformedAt = *input.PerformedAt
+	}
+	if input.Formula != nil {
+		record.Formula = *input.Formula
+	}
+	if input.Developer != nil {
+		record.Developer = *input.Developer
+	}
+	if input.ProcessingMinutes != nil {
+		record.ProcessingMinutes = *input.ProcessingMinutes
+	}
+	if input.Result != nil {
+		record.Result = *input.Result
+	}
+	if input.Notes != nil {
+		record.Notes = *input.Notes
+	}
+
+	if err := config.DB.Save(&record).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update service record")
+		return
+	}
+
+	c.JSON(http.StatusOK, record)
+}
+
+// DeleteServiceRecord removes a service record. Owners and managers only.
+func DeleteServiceRecord(c *gin.Context) {
+	deleteCustomerRecord(c, "recordId", "Service record", &models.ServiceRecord{})
+}
+
+// GetCustomerAllergies lists the customer's recorded allergies
+func GetCustomerAllergies(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var allergies []models.CustomerAllergy
+	if err := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID).
+		Order("created_at").
+		Find(&allergies).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch allergies")
+		return
+	}
+
+	c.JSON(http.StatusOK, allergies)
+}
+
+// CreateCustomerAllergy records an allergy or sensitivity for the customer
+func CreateCustomerAllergy(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var input AllergyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	allergy := models.CustomerAllergy{
+		ID:               uuid.New(),
+		SalonID:          salonUUID,
+		CustomerID:       customer.ID,
+		RecordedByUserID: userUUID,
+		Allergen:         strings.TrimSpace(input.Allergen),
+		Severity:         input.Severity,
+		Reaction:         input.Reaction,
+		Notes:            input.Notes,
+	}
+	if allergy.Severity == "" {
+		allergy.Severity = "mild"
+	}
+
+	if err := config.DB.Create(&allergy).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record allergy")
+		return
+	}
+
+	c.JSON(http.StatusCreated, allergy)
+}
+
+// UpdateCustomerAllergy updates a recorded allergy
+func UpdateCustomerAllergy(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var allergy models.CustomerAllergy
+	if !loadCustomerRecord(c, salonUUID, "allergyId", "Allergy", &allergy) {
+		return
+	}
+
+	var input UpdateAllergyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.Allergen != nil {
+		if strings.TrimSpace(*input.Allergen) == "" {
+			utils.RespondWithError(c, http.StatusBadRequest, "Allergen cannot be empty")
+			return
+		}
+		allergy.Allergen = strings.TrimSpace(*input.Allergen)
+	}
+	if input.Severity != nil {
+		allergy.Severity = *input.Severity
+	}
+	if input.Reaction != nil {
+		allergy.Reaction = *input.Reaction
+	}
+	if input.Notes != nil {
+		allergy.Notes = *input.Notes
+	}
+
+	if err := config.DB.Save(&allergy).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update allergy")
+		return
+	}
+
+	c.JSON(http.StatusOK, allergy)
+}
+
+// DeleteCustomerAllergy removes an allergy recorded in error. Owners and managers only.
+func DeleteCustomerAllergy(c *gin.Context) {
+	deleteCustomerRecord(c, "allergyId", "Allergy", &models.CustomerAllergy{})
+}
+
+// GetPatchTests lists the customer's patch tests, newest first
+func GetPatchTests(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var tests []models.PatchTest
+	if err := config.DB.Where("salon_id = ? AND customer_id = ?", salonUUID, customer.ID).
+		Order("tested_at DESC").
+		Find(&tests).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to fetch patch tests")
+		return
+	}
+
+	c.JSON(http.StatusOK, tests)
+}
+
+// CreatePatchTest records a patch test given to the customer. Tests are
+// usually recorded as pending and updated once the result has been read.
+func CreatePatchTest(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	userUUID, err := uuid.Parse(userID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid user ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var input PatchTestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	validDays := models.DefaultPatchTestValidDays
+	if input.ServiceID != nil {
+		var service models.Service
+		if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, *input.ServiceID).First(&service).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.RespondWithError(c, http.StatusBadRequest, "Service not found")
+			} else {
+				utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+			}
+			return
+		}
+		if service.PatchTestValidDays > 0 {
+			validDays = service.PatchTestValidDays
+		}
+	}
+
+	test := models.PatchTest{
+		ID:                uuid.New(),
+		SalonID:           salonUUID,
+		CustomerID:        customer.ID,
+		ServiceID:         input.ServiceID,
+		PerformedByUserID: userUUID,
+		Product:           input.Product,
+		TestedAt:          time.Now(),
+		Result:            input.Result,
+		Notes:             input.Notes,
+	}
+	if input.TestedAt != nil {
+		test.TestedAt = *input.TestedAt
+	}
+	test.ExpiresAt = test.TestedAt.AddDate(0, 0, validDays)
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(test.TestedAt) {
+			utils.RespondWithError(c, http.StatusBadRequest, "expiresAt must be after testedAt")
+			return
+		}
+		test.ExpiresAt = *input.ExpiresAt
+	}
+	if test.Result == "" {
+		test.Result = "pending"
+	}
+
+	if err := config.DB.Create(&test).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to record patch test")
+		return
+	}
+
+	c.JSON(http.StatusCreated, test)
+}
+
+// UpdatePatchTest records a patch test's result or changes when it expires
+func UpdatePatchTest(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var test models.PatchTest
+	if !loadCustomerRecord(c, salonUUID, "testId", "Patch test", &test) {
+		return
+	}
+
+	var input UpdatePatchTestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	if input.Result != nil {
+		test.Result = *input.Result
+	}
+	if input.ExpiresAt != nil {
+		if !input.ExpiresAt.After(test.TestedAt) {
+			utils.RespondWithError(c, http.StatusBadRequest, "expiresAt must be after testedAt")
+			return
+		}
+		test.ExpiresAt = *input.ExpiresAt
+	}
+	if input.Notes != nil {
+		test.Notes = *input.Notes
+	}
+
+	if err := config.DB.Save(&test).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to update patch test")
+		return
+	}
+
+	c.JSON(http.StatusOK, test)
+}
+
+// CheckPatchTests warns about any of ?serviceIds= (comma-separated) that need a
+// patch test the customer does not have, so staff see it while building an invoice
+func CheckPatchTests(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customer, ok := loadSalonCustomer(c, salonUUID)
+	if !ok {
+		return
+	}
+
+	var serviceIDs []uuid.UUID
+	for _, raw := range strings.Split(c.Query("serviceIds"), ",") {
+		if raw = strings.TrimSpace(raw); raw == "" {
+			continue
+		}
+		serviceUUID, err := uuid.Parse(raw)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid service ID format: "+raw)
+			return
+		}
+		serviceIDs = append(serviceIDs, serviceUUID)
+	}
+	if len(serviceIDs) == 0 {
+		utils.RespondWithError(c, http.StatusBadRequest, "serviceIds is required")
+		return
+	}
+
+	warnings, err := patchTestWarnings(config.DB, salonUUID, customer.ID, serviceIDs, time.Now())
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to check patch tests")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"warnings": warnings})
+}
+
+// patchTestWarnings checks each service that requires a patch test against the
+// customer's tests. A service is clear if a passed test covering it is still
+// valid at the given time; otherwise the latest covering test gives the reason.
+func patchTestWarnings(db *gorm.DB, salonID, customerID uuid.UUID, serviceIDs []uuid.UUID, at time.Time) ([]models.PatchTestWarning, error) {
+	warnings := []models.PatchTestWarning{}
+	if len(serviceIDs) == 0 {
+		return warnings, nil
+	}
+
+	var flagged []models.Service
+	if err := db.Where("salon_id = ? AND id IN ? AND requires_patch_test", salonID, serviceIDs).
+		Order("name").
+		Find(&flagged).Error; err != nil {
+		return nil, err
+	}
+	if len(flagged) == 0 {
+		return warnings, nil
+	}
+
+	var tests []models.PatchTest
+	if err := db.Where("salon_id = ? AND customer_id = ?", salonID, customerID).
+		Order("tested_at DESC").
+		Find(&tests).Error; err != nil {
+		return nil, err
+	}
+
+	for _, service := range flagged {
+		var latest *models.PatchTest
+		valid := false
+		for i, test := range tests {
+			if test.ServiceID != nil && *test.ServiceID != service.ID {
+				continue
+			}
+			if latest == nil {
+				latest = &tests[i]
+			}
+			if test.Result == "passed" && !test.TestedAt.After(at) && test.ExpiresAt.After(at) {
+				valid = true
+				break
+			}
+		}
+		if valid {
+			continue
+		}
+
+		warning := models.PatchTestWarning{ServiceID: service.ID, ServiceName: service.Name, LastTest: latest}
+		switch {
+		case latest == nil:
+			warning.Reason = "missing"
+			warning.Message = service.Name + " needs a patch test and none is on record"
+		case latest.Result == "failed":
+			warning.Reason = "failed"
+			warning.Message = "The customer's last patch test for " + service.Name + " failed"
+		case latest.Result == "pending":
+			warning.Reason = "pending"
+			warning.Message = "The patch test for " + service.Name + " is waiting for its result"
+		default:
+			warning.Reason = "expired"
+			warning.Message = "The patch test for " + service.Name + " expired on " + latest.ExpiresAt.Format("2006-01-02")
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings, nil
+}
+
+// invoicePatchTestWarnings runs the patch test check over an invoice's service lines
+func invoicePatchTestWarnings(db *gorm.DB, invoice models.Invoice) ([]models.PatchTestWarning, error) {
+	var serviceIDs []uuid.UUID
+	for _, item := range invoice.Items {
+		if item.ItemType == "service" && item.ServiceID != nil {
+			serviceIDs = append(serviceIDs, *item.ServiceID)
+		}
+	}
+	return patchTestWarnings(db, invoice.SalonID, invoice.CustomerID, serviceIDs, invoice.InvoiceDate)
+}
+
+// loadSalonCustomer loads the customer named by the :id parameter
+func loadSalonCustomer(c *gin.Context, salonID uuid.UUID) (models.Customer, bool) {
+	var customer models.Customer
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return customer, false
+	}
+
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonID, customerUUID).First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return customer, false
+	}
+	return customer, true
+}
+
+// loadCustomerRecord loads a service record, allergy or patch test named by the
+// param, checking it belongs to the customer in the :id parameter
+func loadCustomerRecord(c *gin.Context, salonID uuid.UUID, param, label string, dest interface{}) bool {
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return false
+	}
+	recordUUID, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid "+strings.ToLower(label)+" ID format")
+		return false
+	}
+
+	if err := config.DB.Where("salon_id = ? AND customer_id = ? AND id = ?", salonID, customerUUID, recordUUID).
+		First(dest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, label+" not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return false
+	}
+	return true
+}
+
+// deleteCustomerRecord deletes a service record or allergy for owners and managers
+func deleteCustomerRecord(c *gin.Context, param, label string, model interface{}) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	// Check if current user is owner or manager
+	var currentUser models.User
+	if err := config.DB.First(&currentUser, "id = ?", userID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User not found")
+		return
+	}
+
+	if currentUser.Role != string(RoleOwner) && currentUser.Role != string(RoleManager) {
+		utils.RespondWithError(c, http.StatusForbidden, "Only owners and managers can delete client records")
+		return
+	}
+
+	if !loadCustomerRecord(c, salonUUID, param, label, model) {
+		return
+	}
+
+	if err := config.DB.Delete(model).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to delete "+strings.ToLower(label))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": label + " deleted successfully"})
+}
@@ -0,0 +1,85 @@
+// controllers/service_record_test.go
+package controllers
+
+import (
+	"reflect"
+	"salonpro-backend/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPatchTestWarnings(t *testing.T) {
+	at := time.Date(2026, 3, 14, 11, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	salonID, customerID := uuid.New(), uuid.New()
+	colour := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Colour", Price: 2000, RequiresPatchTest: true}
+	highlights := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Highlights", Price: 3500, RequiresPatchTest: true}
+	haircut := models.Service{ID: uuid.New(), SalonID: salonID, Name: "Haircut", Price: 500}
+
+	patchTest := func(service *models.Service, result string, testedAgo, validFor time.Duration) models.PatchTest {
+		test := models.PatchTest{Result: result, TestedAt: at.Add(-testedAgo), ExpiresAt: at.Add(-testedAgo + validFor)}
+		if service != nil {
+			test.ServiceID = &service.ID
+		}
+		return test
+	}
+
+	tests := []struct {
+		name     string
+		services []uuid.UUID
+		tests    []models.PatchTest
+		want     []string // service:reason, in service name order
+	}{
+		{"no flagged services", []uuid.UUID{haircut.ID}, nil, nil},
+		{"nothing on record", []uuid.UUID{colour.ID, haircut.ID}, nil, []string{"Colour:missing"}},
+		{"passed test for the service", []uuid.UUID{colour.ID},
+			[]models.PatchTest{patchTest(&colour, "passed", 2*day, 180*day)}, nil},
+		{"passed test covering every service", []uuid.UUID{colour.ID, highlights.ID},
+			[]models.PatchTest{patchTest(nil, "passed", 2*day, 180*day)}, nil},
+		{"test for another service", []uuid.UUID{colour.ID, highlights.ID},
+			[]models.PatchTest{patchTest(&highlights, "passed", 2*day, 180*day)}, []string{"Colour:missing"}},
+		{"expired", []uuid.UUID{colour.ID},
+			[]models.PatchTest{patchTest(&colour, "passed", 200*day, 180*day)}, []string{"Colour:expired"}},
+		{"failed", []uuid.UUID{colour.ID},
+			[]models.PatchTest{patchTest(&colour, "failed", day, 180*day)}, []string{"Colour:failed"}},
+		{"waiting for the result", []uuid.UUID{colour.ID},
+			[]models.PatchTest{patchTest(&colour, "pending", time.Hour, 180*day)}, []string{"Colour:pending"}},
+		{"older pass still valid after a later pending test", []uuid.UUID{colour.ID},
+			[]models.PatchTest{
+				patchTest(&colour, "passed", 30*day, 180*day),
+				patchTest(&colour, "pending", time.Hour, 180*day),
+			}, nil},
+		{"both flagged services", []uuid.UUID{highlights.ID, colour.ID, haircut.ID},
+			[]models.PatchTest{patchTest(&colour, "failed", day, 180*day)}, []string{"Colour:failed", "Highlights:missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, &models.Service{}, &models.PatchTest{})
+			for _, service := range []models.Service{colour, highlights, haircut} {
+				if err := db.Create(&service).Error; err != nil {
+					t.Fatalf("Failed to create service: %v", err)
+				}
+			}
+			for _, test := range tt.tests {
+				test.ID, test.SalonID, test.CustomerID, test.PerformedByUserID = uuid.New(), salonID, customerID, uuid.New()
+				if err := db.Create(&test).Error; err != nil {
+					t.Fatalf("Failed to create patch test: %v", err)
+				}
+			}
+
+			warnings, err := patchTestWarnings(db, salonID, customerID, tt.services, at)
+			if err != nil {
+				t.Fatalf("patchTestWarnings() error = %v", err)
+			}
+			var got []string
+			for _, warning := range warnings {
+				got = append(got, warning.ServiceName+":"+warning.Reason)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("patchTestWarnings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// controllers/upi.go
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/services"
+	"salonpro-backend/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetInvoiceUPI returns the UPI intent for an invoice's outstanding balance
+// along with an SVG QR code of it
+func GetInvoiceUPI(c *gin.Context) {
+	upi, ok := loadInvoiceUPI(c)
+	if !ok {
+		return
+	}
+
+	qr, err := utils.EncodeQR(upi.URI)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate QR code")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"upi":   upi,
+		"qrSvg": qr.SVG(),
+	})
+}
+
+// GetInvoiceUPIQR returns the QR code image, as ?format=png (default, with
+// ?scale= pixels per module) or ?format=svg
+func GetInvoiceUPIQR(c *gin.Context) {
+	upi, ok := loadInvoiceUPI(c)
+	if !ok {
+		return
+	}
+
+	qr, err := utils.EncodeQR(upi.URI)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate QR code")
+		return
+	}
+
+	switch c.DefaultQuery("format", "png") {
+	case "svg":
+		c.Data(http.StatusOK, "image/svg+xml", []byte(qr.SVG()))
+	case "png":
+		scale, err := strconv.Atoi(c.DefaultQuery("scale", "8"))
+		if err != nil || scale < 1 || scale > 40 {
+			utils.RespondWithError(c, http.StatusBadRequest, "Scale must be between 1 and 40")
+			return
+		}
+		data, err := qr.PNG(scale)
+		if err != nil {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Failed to generate QR code")
+			return
+		}
+		c.Data(http.StatusOK, "image/png", data)
+	default:
+		utils.RespondWithError(c, http.StatusBadRequest, "Format must be png or svg")
+	}
+}
+
+// loadInvoiceUPI builds the UPI intent for the invoice in the URL, writing the
+// error response itself when it cannot
+func loadInvoiceUPI(c *gin.Context) (services.UPIPayment, bool) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return services.UPIPayment{}, false
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return services.UPIPayment{}, false
+	}
+
+	invoiceUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid invoice ID format")
+		return services.UPIPayment{}, false
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, invoiceUUID).
+		First(&invoice).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Invoice not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return services.UPIPayment{}, false
+	}
+
+	var salon models.Salon
+	if err := config.DB.First(&salon, "id = ?", salonUUID).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Salon not found")
+		return services.UPIPayment{}, false
+	}
+
+	upi, err := services.BuildUPIPayment(salon, invoice)
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, err.Error())
+		return services.UPIPayment{}, false
+	}
+	return upi, true
+}
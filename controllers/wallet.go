@@ -0,0 +1,314 @@
+// controllers/wallet.go
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"salonpro-backend/config"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TopUpWalletInput defines the expected JSON structure for adding money to a wallet
+type TopUpWalletInput struct {
+	Amount        float64 `json:"amount" binding:"required,gt=0"`
+	PaymentMethod string  `json:"paymentMethod" binding:"required"`
+	Reference     string  `json:"reference"`
+	Note          string  `json:"note"`
+}
+
+// WalletLiability summarises money customers hold on account with the salon
+type WalletLiability struct {
+	CustomersWithBalance int     `json:"customersWithBalance"`
+	OutstandingBalance   float64 `json:"outstandingBalance"`
+	TotalToppedUp        float64 `json:"totalToppedUp"`
+	TotalSpent           float64 `json:"totalSpent"`
+}
+
+// TopUpWallet adds money to a customer's wallet and issues a receipt
+func TopUpWallet(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	userID, exists := c.Get("userId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "User ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	var input TopUpWalletInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid input: "+err.Error())
+		return
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var transaction models.WalletTransaction
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = creditWallet(tx, models.WalletTransaction{
+			SalonID:         salonUUID,
+			CustomerID:      customer.ID,
+			CreatedByUserID: uuid.Must(uuid.Parse(userID.(string))),
+			Type:            "topup",
+			Amount:          math.Round(input.Amount*100) / 100,
+			ReceiptNumber:   "RCP-" + time.Now().Format("20060102") + "-" + utils.GenerateRandomString(6),
+			PaymentMethod:   input.PaymentMethod,
+			Reference:       input.Reference,
+			Note:            input.Note,
+		})
+		return err
+	})
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to top up wallet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, transaction)
+}
+
+// GetWalletStatement returns a customer's wallet balance and the movements between
+// ?from= and ?to= (YYYY-MM-DD, default the last 90 days) with the opening balance
+func GetWalletStatement(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	customerUUID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusBadRequest, "Invalid customer ID format")
+		return
+	}
+
+	now := time.Now()
+	end := utils.BeginningOfDay(now).AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -90)
+	if from := c.Query("from"); from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, now.Location()); err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid from date, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		toDate, err := time.ParseInLocation("2006-01-02", to, now.Location())
+		if err != nil {
+			utils.RespondWithError(c, http.StatusBadRequest, "Invalid to date, expected YYYY-MM-DD")
+			return
+		}
+		end = toDate.AddDate(0, 0, 1)
+	}
+
+	var customer models.Customer
+	if err := config.DB.Where("salon_id = ? AND id = ?", salonUUID, customerUUID).
+		First(&customer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.RespondWithError(c, http.StatusNotFound, "Customer not found")
+		} else {
+			utils.RespondWithError(c, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	var openingBalance float64
+	if err := config.DB.Model(&models.WalletTransaction{}).
+		Where("customer_id = ? AND created_at < ?", customer.ID, start).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&openingBalance).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build wallet statement")
+		return
+	}
+
+	var transactions []models.WalletTransaction
+	if err := config.DB.Where("customer_id = ? AND created_at >= ? AND created_at < ?", customer.ID, start, end).
+		Order("created_at").
+		Find(&transactions).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to build wallet statement")
+		return
+	}
+
+	var credits, debits float64
+	for _, t := range transactions {
+		if t.Amount > 0 {
+			credits += t.Amount
+		} else {
+			debits -= t.Amount
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"customerId":     customer.ID,
+		"customerName":   customer.Name,
+		"from":           start.Format("2006-01-02"),
+		"to":             end.AddDate(0, 0, -1).Format("2006-01-02"),
+		"openingBalance": openingBalance,
+		"credits":        credits,
+		"debits":         debits,
+		"closingBalance": openingBalance + credits - debits,
+		"balance":        customer.WalletBalance,
+		"transactions":   transactions,
+	})
+}
+
+// GetWalletLiability reports the money customers currently hold on account
+func GetWalletLiability(c *gin.Context) {
+	salonID, exists := c.Get("salonId")
+	if !exists {
+		utils.RespondWithError(c, http.StatusUnauthorized, "Salon ID not found in context")
+		return
+	}
+
+	salonUUID, err := uuid.Parse(salonID.(string))
+	if err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Invalid salon ID format")
+		return
+	}
+
+	var liability WalletLiability
+	if err := config.DB.Raw(`
+		SELECT
+			COUNT(*) FILTER (WHERE wallet_balance > 0) as customers_with_balance,
+			COALESCE(SUM(wallet_balance), 0) as outstanding_balance
+		FROM customers
+		WHERE salon_id = ?
+	`, salonUUID).Scan(&liability).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate wallet liability")
+		return
+	}
+
+	if err := config.DB.Raw(`
+		SELECT
+			COALESCE(SUM(amount) FILTER (WHERE type = 'topup'), 0) as total_topped_up,
+			COALESCE(-SUM(amount) FILTER (WHERE type IN ('debit', 'refund')), 0) as total_spent
+		FROM wallet_transactions
+		WHERE salon_id = ?
+	`, salonUUID).Scan(&liability).Error; err != nil {
+		utils.RespondWithError(c, http.StatusInternalServerError, "Failed to calculate wallet liability")
+		return
+	}
+
+	c.JSON(http.StatusOK, liability)
+}
+
+// creditWallet adds entry.Amount (> 0) to the customer's wallet and records the movement
+func creditWallet(tx *gorm.DB, entry models.WalletTransaction) (models.WalletTransaction, error) {
+	// RETURNING gives the balance this update produced, whatever else runs meanwhile
+	var customer models.Customer
+	result := tx.Model(&customer).Clauses(clause.Returning{Columns: []clause.Column{{Name: "wallet_balance"}}}).
+		Where("id = ?", entry.CustomerID).
+		Update("wallet_balance", gorm.Expr("wallet_balance + ?", entry.Amount))
+	if result.Error != nil {
+		return entry, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entry, gorm.ErrRecordNotFound
+	}
+
+	entry.ID = uuid.New()
+	entry.BalanceAfter = customer.WalletBalance
+	err := tx.Create(&entry).Error
+	return entry, err
+}
+
+// applyWalletPayment pays part of the invoice from the customer's wallet
+func applyWalletPayment(tx *gorm.DB, invoice *models.Invoice, userID uuid.UUID, amount float64) error {
+	amount = math.Round(amount*100) / 100
+	if amount > invoice.Total-invoice.PaidAmount+0.005 {
+		return &invoiceInputError{"Wallet amount exceeds the amount due"}
+	}
+
+	// Guarded decrement so concurrent payments cannot overdraw the wallet
+	var customer models.Customer
+	result := tx.Model(&customer).Clauses(clause.Returning{Columns: []clause.Column{{Name: "wallet_balance"}}}).
+		Where("id = ? AND wallet_balance >= ?", invoice.CustomerID, amount).
+		Update("wallet_balance", gorm.Expr("wallet_balance - ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return &invoiceInputError{"Wallet balance is insufficient"}
+	}
+
+	if err := tx.Create(&models.WalletTransaction{
+		ID:              uuid.New(),
+		SalonID:         invoice.SalonID,
+		CustomerID:      invoice.CustomerID,
+		InvoiceID:       &invoice.ID,
+		CreatedByUserID: userID,
+		Type:            "debit",
+		Amount:          -amount,
+		BalanceAfter:    customer.WalletBalance,
+		Reference:       invoice.InvoiceNumber,
+	}).Error; err != nil {
+		return err
+	}
+
+	return recordTenderPayment(tx, invoice, models.InvoicePayment{
+		CreatedByUserID: userID,
+		Method:          "wallet",
+		Amount:          amount,
+	})
+}
+
+// refundWalletPayments returns wallet money spent on an invoice being deleted
+func refundWalletPayments(tx *gorm.DB, invoice models.Invoice, userID uuid.UUID) error {
+	var payments []models.InvoicePayment
+	if err := tx.Where("invoice_id = ? AND method = 'wallet'", invoice.ID).Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		if _, err := creditWallet(tx, models.WalletTransaction{
+			SalonID:         invoice.SalonID,
+			CustomerID:      invoice.CustomerID,
+			InvoiceID:       &invoice.ID,
+			CreatedByUserID: userID,
+			Type:            "refund",
+			Amount:          payment.Amount,
+			Reference:       invoice.InvoiceNumber,
+			Note:            "Invoice deleted",
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+// controllers/wallet_test.go
+package controllers
+
+import (
+	"errors"
+	"salonpro-backend/models"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestApplyWalletPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     float64
+		paid        float64
+		amount      float64
+		wantErr     string
+		wantBalance float64
+		wantStatus  string
+	}{
+		{"part of the invoice", 1500, 0, 400, "", 1100, "partial"},
+		{"rest of the invoice", 1500, 600, 400, "", 1100, "paid"},
+		{"whole wallet", 400, 0, 400, "", 0, "partial"},
+		{"rounded to paise", 1000, 0, 99.999, "", 900, "partial"},
+		{"more than the balance", 300, 0, 400, "Wallet balance is insufficient", 300, "unpaid"},
+		{"more than the amount due", 1500, 800, 400, "Wallet amount exceeds the amount due", 1500, "unpaid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testDB(t, &models.Customer{}, &models.Invoice{}, &models.InvoicePayment{}, &models.WalletTransaction{})
+			userID := uuid.New()
+
+			customer := models.Customer{
+				ID:              uuid.New(),
+				SalonID:         uuid.New(),
+				CreatedByUserID: userID,
+				Name:            "Asha Rao",
+				Phone:           "9876543210",
+				WalletBalance:   tt.balance,
+			}
+			if err := db.Create(&customer).Error; err != nil {
+				t.Fatalf("Failed to create customer: %v", err)
+			}
+			invoice := models.Invoice{
+				ID:              uuid.New(),
+				SalonID:         customer.SalonID,
+				CreatedByUserID: userID,
+				InvoiceNumber:   "INV-" + uuid.NewString()[:8],
+				CustomerID:      customer.ID,
+				Subtotal:        1000,
+				Total:           1000,
+				PaymentStatus:   "unpaid",
+			}
+			if err := db.Create(&invoice).Error; err != nil {
+				t.Fatalf("Failed to create invoice: %v", err)
+			}
+			if tt.paid > 0 {
+				// Earlier cash payment, posted the way the invoice screens post it
+				if err := recordTenderPayment(db, &invoice, models.InvoicePayment{
+					CreatedByUserID: userID, Method: "cash", Amount: tt.paid,
+				}); err != nil {
+					t.Fatalf("Failed to record cash payment: %v", err)
+				}
+			}
+
+			err := applyWalletPayment(db, &invoice, userID, tt.amount)
+			if tt.wantErr != "" {
+				var inputErr *invoiceInputError
+				if !errors.As(err, &inputErr) || inputErr.Error() != tt.wantErr {
+					t.Fatalf("applyWalletPayment() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("applyWalletPayment() error = %v", err)
+			}
+
+			db.First(&customer, "id = ?", customer.ID)
+			if customer.WalletBalance != tt.wantBalance {
+				t.Errorf("wallet balance = %v, want %v", customer.WalletBalance, tt.wantBalance)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			if invoice.PaymentStatus != tt.wantStatus {
+				t.Errorf("invoice status = %s, want %s", invoice.PaymentStatus, tt.wantStatus)
+			}
+			var entry models.WalletTransaction
+			if err := db.First(&entry, "customer_id = ? AND type = 'debit'", customer.ID).Error; err != nil {
+				t.Fatalf("no wallet debit recorded: %v", err)
+			}
+			if entry.BalanceAfter != tt.wantBalance || entry.Amount != tt.wantBalance-tt.balance {
+				t.Errorf("debit = %v, balance after %v; want %v, %v", entry.Amount, entry.BalanceAfter, tt.wantBalance-tt.balance, tt.wantBalance)
+			}
+
+			// Deleting the invoice puts the money back
+			if err := refundWalletPayments(db, invoice, userID); err != nil {
+				t.Fatalf("refundWalletPayments() error = %v", err)
+			}
+			db.First(&customer, "id = ?", customer.ID)
+			if customer.WalletBalance != tt.balance {
+				t.Errorf("wallet balance after refund = %v, want %v", customer.WalletBalance, tt.balance)
+			}
+		})
+	}
+}
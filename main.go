@@ -4,7 +4,9 @@ import (
 	"log"
 	"os"
 	"salonpro-backend/config"
+	"salonpro-backend/models"
 	"salonpro-backend/routes"
+	"salonpro-backend/services"
 
 	"github.com/joho/godotenv"
 )
@@ -16,16 +18,118 @@ func init() {
 	}
 	config.ConnectDB()
 
-	// config.DB.AutoMigrate(
-	// 	&models.Salon{},
-	// 	&models.User{},
-	// 	&models.Customer{},
-	// 	&models.Service{},
-	// 	&models.Invoice{},
-	// 	&models.InvoiceItem{},
-	// 	&models.ReminderTemplate{},
-	// 	// &models.ReminderLog{},
-	// )
+	// Types, extensions and index changes the models depend on come first
+	runSchemaStatements(schemaPrerequisites)
+
+	if err := config.DB.AutoMigrate(
+		&models.Salon{},
+		&models.User{},
+		&models.Customer{},
+		&models.Service{},
+		&models.Invoice{},
+		&models.InvoiceItem{},
+		&models.ReminderTemplate{},
+		&models.ReminderLog{},
+		&models.InvoiceDelivery{},
+		&models.Product{},
+		&models.InvoicePayment{},
+		&models.GiftCard{},
+		&models.GiftCardTransaction{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.MembershipPlan{},
+		&models.CustomerMembership{},
+		&models.ServicePackage{},
+		&models.ServicePackageItem{},
+		&models.CustomerPackage{},
+		&models.CustomerPackageSession{},
+		&models.LoyaltyProgram{},
+		&models.LoyaltyTransaction{},
+		&models.WalletTransaction{},
+		&models.InvoiceVoid{},
+		&models.RegisterSession{},
+		&models.CashMovement{},
+		&models.PaymentLink{},
+		&models.GatewayPayment{},
+		&models.PaymentWebhookEvent{},
+		&models.InvoiceRevision{},
+		&models.CustomerImport{},
+		&models.CustomerExport{},
+		&models.CustomerMerge{},
+		&models.CustomerSegment{},
+		&models.SegmentMessage{},
+		&models.CustomerConsent{},
+		&models.MessageSuppression{},
+		&models.DataSubjectRequest{},
+		&models.CustomerChange{},
+		&models.ServiceRecord{},
+		&models.CustomerAllergy{},
+		&models.PatchTest{},
+		&models.Attachment{},
+	); err != nil {
+		log.Fatalf("Database migration failed: %v", err)
+	}
+
+	runSchemaStatements(schemaMigrations)
+}
+
+// runSchemaStatements runs raw DDL, stopping the server if any of it fails
+func runSchemaStatements(statements []string) {
+	for _, statement := range statements {
+		if err := config.DB.Exec(statement).Error; err != nil {
+			log.Fatalf("Database migration failed: %v\n%s", err, statement)
+		}
+	}
+}
+
+// schemaPrerequisites run before AutoMigrate. Each one is safe to run on every start.
+var schemaPrerequisites = []string{
+	`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`,
+	// The customer search's trigram indexes need pg_trgm
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+
+	`DO $$
+	BEGIN
+		CREATE TYPE payment_status AS ENUM ('unpaid', 'partial', 'paid');
+	EXCEPTION WHEN duplicate_object THEN NULL;
+	END $$`,
+	`DO $$
+	BEGIN
+		CREATE TYPE reminder_type AS ENUM ('birthday', 'anniversary', 'membership_expiry', 'dues');
+	EXCEPTION WHEN duplicate_object THEN NULL;
+	END $$`,
+	// Reminder types added after the reminder_type enum was first created
+	`ALTER TYPE reminder_type ADD VALUE IF NOT EXISTS 'membership_expiry'`,
+	`ALTER TYPE reminder_type ADD VALUE IF NOT EXISTS 'dues'`,
+
+	// Household members may share their primary's phone, so the salon/phone
+	// unique index only covers customers outside a household. The old index had
+	// no predicate and is dropped so AutoMigrate creates the partial one.
+	`DO $$
+	BEGIN
+		IF EXISTS (
+			SELECT 1 FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE c.relname = 'idx_salon_phone' AND c.relnamespace = current_schema()::regnamespace
+			  AND i.indpred IS NULL
+		) THEN
+			DROP INDEX idx_salon_phone;
+		END IF;
+	END $$`,
+}
+
+// schemaMigrations are schema changes AutoMigrate can't make, run after it.
+// Each one is safe to run on every start.
+var schemaMigrations = []string{
+	// The customer search compares the phone's digits only; a gorm index tag
+	// can't hold this expression
+	`CREATE INDEX IF NOT EXISTS idx_customers_phone_digits_trgm ON customers USING gin (regexp_replace(phone, '[^0-9]', '', 'g') gin_trgm_ops)`,
+
+	// Reports and benefit counts filter on deleted_at, which the models don't
+	// map so that deletes stay hard deletes
+	`ALTER TABLE invoices ADD COLUMN IF NOT EXISTS deleted_at timestamptz`,
+	`ALTER TABLE customers ADD COLUMN IF NOT EXISTS deleted_at timestamptz`,
+	`ALTER TABLE services ADD COLUMN IF NOT EXISTS deleted_at timestamptz`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz`,
 }
 
 func main() {
@@ -34,6 +138,16 @@ func main() {
 	if port == "" {
 		port = "8080"
 	}
+
+	// Daily birthday, anniversary, membership expiry and dues reminders send real
+	// messages through Twilio, so a deployment turns them on explicitly
+	if os.Getenv("REMINDERS_ENABLED") == "true" {
+		services.NewReminderService(config.DB).StartScheduler()
+	} else {
+		log.Println("Reminder scheduler not started; set REMINDERS_ENABLED=true to send daily reminders")
+	}
+	services.StartLoyaltyExpiryScheduler(config.DB)
+
 	r := routes.SetupRouter()
 	// printRoutes(r)
 	r.Run(":" + port)
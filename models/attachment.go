@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a photo or document uploaded to a customer, optionally tied to
+// the invoice for the visit it came from. The file lives in storage; only its
+// key is kept here.
+type Attachment struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID          uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID        *uuid.UUID `gorm:"type:uuid;index"` // the visit, if any
+	UploadedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+
+	Kind         string `gorm:"type:varchar(20);not null"` // 'before_photo', 'after_photo', 'photo' or 'document'
+	Filename     string `gorm:"not null"`                  // as uploaded
+	ContentType  string `gorm:"type:varchar(100);not null"`
+	SizeBytes    int64  `gorm:"not null"`
+	StorageKey   string `gorm:"not null"`
+	ThumbnailKey string // blank for documents and images we cannot decode
+	Width        int    // upright pixel size of images
+	Height       int
+	Caption      string
+
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
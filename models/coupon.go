@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Coupon is a promo code giving a percentage or flat discount under validation rules
+type Coupon struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_salon_coupon_code,priority:1"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	Code         string `gorm:"not null;uniqueIndex:idx_salon_coupon_code,priority:2"`
+	Description  string
+	DiscountType string  `gorm:"type:varchar(20);not null"` // 'percentage' or 'flat'
+	Value        float64 `gorm:"type:decimal(10,2);not null"`
+	MaxDiscount  float64 `gorm:"type:decimal(10,2);default:0.0"` // cap for percentage coupons, 0 = no cap
+
+	// Rules
+	MinSpend           float64    `gorm:"type:decimal(10,2);default:0.0"`
+	EligibleServiceIDs StringList `gorm:"type:jsonb;default:'[]'"` // empty = all services and products
+	EligibleCategories StringList `gorm:"type:jsonb;default:'[]'"`
+	ValidFrom          *time.Time
+	ValidUntil         *time.Time
+	UsageLimit         int    `gorm:"default:0"` // total redemptions, 0 = unlimited
+	PerCustomerLimit   int    `gorm:"default:0"` // 0 = unlimited
+	FirstVisitOnly     bool   `gorm:"default:false"`
+	Occasion           string `gorm:"type:varchar(20)"` // 'birthday' or 'anniversary': only valid in that month
+
+	UsageCount int       `gorm:"default:0"`
+	IsActive   bool      `gorm:"default:true"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// CouponRedemption links a coupon to the invoice it discounted
+type CouponRedemption struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	CouponID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	DiscountAmount float64   `gorm:"type:decimal(10,2);not null"`
+	RedeemedAt     time.Time `gorm:"autoCreateTime"`
+}
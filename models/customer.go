@@ -8,19 +8,30 @@ import (
 
 type Customer struct {
 	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_salon_phone,priority:1,where:household_primary_id IS NULL;index:idx_customers_salon_name,priority:1;index:idx_customers_salon_last_visit,priority:1;index:idx_customers_salon_total_spent,priority:1;index:idx_customers_salon_total_visits,priority:1"`
 	CreatedByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
 
-	Name        string `gorm:"not null"`
-	Phone       string `gorm:"not null;uniqueIndex:idx_salon_phone,priority:2"`
-	Email       string
+	// The trigram indexes serve the customer search's ILIKE '%term%' and need the pg_trgm
+	// extension, which main.go creates along with the index on the phone's digits
+	Name        string `gorm:"not null;index:idx_customers_salon_name,priority:2;index:idx_customers_name_trgm,type:gin,expression:name gin_trgm_ops"`
+	Phone       string `gorm:"not null;uniqueIndex:idx_salon_phone,priority:2,where:household_primary_id IS NULL"` // household members may share their primary's number
+	Email       string `gorm:"index:idx_customers_email_trgm,type:gin,expression:email gin_trgm_ops"`
 	Birthday    *time.Time
 	Anniversary *time.Time
 	Notes       string
-	TotalVisits int     `gorm:"default:0"`
-	TotalSpent  float64 `gorm:"type:decimal(10,2);default:0.0"`
-	LastVisit   *time.Time
-	IsActive    bool `gorm:"default:true"`
+	Gender      string     `gorm:"type:varchar(20)"`        // 'female', 'male', 'other' or blank
+	Tags        StringList `gorm:"type:jsonb;default:'[]'"` // free-form labels such as "VIP" or "bridal"
+	TotalVisits int        `gorm:"default:0;index:idx_customers_salon_total_visits,priority:2"`
+	TotalSpent  float64    `gorm:"type:decimal(10,2);default:0.0;index:idx_customers_salon_total_spent,priority:2"`
+	LastVisit   *time.Time `gorm:"index:idx_customers_salon_last_visit,priority:2"`
+	IsActive    bool       `gorm:"default:true"`
+
+	LoyaltyPoints int     `gorm:"default:0"`                      // current balance, kept in step with the loyalty ledger
+	WalletBalance float64 `gorm:"type:decimal(10,2);default:0.0"` // money on account, kept in step with the wallet ledger
+
+	// Set on household members to the customer who is the household's contact
+	HouseholdPrimaryID *uuid.UUID `gorm:"type:uuid;index"`
+	Relationship       string     `gorm:"type:varchar(20)"` // member's relation to the primary: 'spouse', 'partner', 'child', 'parent', 'sibling' or 'other'
 
 	Invoices []Invoice `gorm:"foreignKey:CustomerID"`
 }
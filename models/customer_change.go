@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerChange records one edit to a customer's profile, with each changed
+// field's old and new value
+type CustomerChange struct {
+	ID              uuid.UUID       `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID       `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID       `gorm:"type:uuid;index;not null"`
+	ChangedByUserID uuid.UUID       `gorm:"type:uuid;not null"`
+	Changes         FieldChangeList `gorm:"type:jsonb;default:'[]'"`
+	CreatedAt       time.Time       `gorm:"autoCreateTime"`
+}
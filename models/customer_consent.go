@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerConsent is one recorded grant or withdrawal of a customer's consent
+// to be messaged on a channel. Records are never edited; the latest per channel
+// and purpose is the customer's current choice.
+type CustomerConsent struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID          uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	Channel          string     `gorm:"type:varchar(20);not null"` // 'sms', 'whatsapp' or 'email'
+	Purpose          string     `gorm:"type:varchar(20);not null"` // 'marketing' or 'transactional'
+	Granted          bool       `gorm:"not null"`
+	Source           string     `gorm:"type:varchar(30);not null"` // how it was captured, e.g. 'in_store' or 'online_form'
+	CapturedByUserID *uuid.UUID `gorm:"type:uuid"`                 // nil when the customer gave it themselves
+	Note             string
+	CreatedAt        time.Time `gorm:"autoCreateTime;index"`
+}
+
+// MessageSuppression blocks a phone number or email address on a channel. It
+// is keyed by the address rather than the customer, so it outlives deletion,
+// merges and re-imports of the same person.
+type MessageSuppression struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_message_suppression"`
+	Channel         string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_message_suppression"`
+	Purpose         string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_message_suppression"`
+	Address         string     `gorm:"not null;uniqueIndex:idx_message_suppression"` // phone digits or lowercased email
+	Reason          string     `gorm:"type:varchar(30);not null"`                    // 'opt_out', 'complaint', 'bounce', 'manual' or 'erasure'
+	Source          string     `gorm:"type:varchar(30)"`
+	CreatedByUserID *uuid.UUID `gorm:"type:uuid"`
+	Note            string
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerExport logs each download of the customer base, since it carries
+// the salon's contact list out of the system
+type CustomerExport struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	Format    string    `gorm:"type:varchar(10);not null"` // 'csv' or 'vcard'
+	Filters   StringMap `gorm:"type:jsonb;default:'{}'"`   // query parameters the export was filtered by
+	RowCount  int       `gorm:"default:0"`
+	Completed bool      `gorm:"default:false"` // false if the download failed part way
+	IPAddress string
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
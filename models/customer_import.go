@@ -0,0 +1,68 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerImport tracks one spreadsheet import, run inline for small files and
+// in the background for large ones. A dry run validates every row without saving.
+type CustomerImport struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	Filename    string    `gorm:"not null"`
+	DryRun      bool      `gorm:"default:false"`
+	OnDuplicate string    `gorm:"type:varchar(10);default:'skip'"`   // 'skip' or 'update' customers whose phone already exists
+	Columns     StringMap `gorm:"type:jsonb;default:'{}'"`           // spreadsheet column -> customer field
+	Status      string    `gorm:"type:varchar(20);default:'queued'"` // 'queued', 'running', 'completed' or 'failed'
+
+	TotalRows     int `gorm:"default:0"`
+	ProcessedRows int `gorm:"default:0"`
+	CreatedCount  int `gorm:"default:0"`
+	UpdatedCount  int `gorm:"default:0"`
+	SkippedCount  int `gorm:"default:0"` // duplicates left alone
+	ErrorCount    int `gorm:"default:0"` // rows rejected by validation
+
+	Issues        ImportIssueList `gorm:"type:jsonb;default:'[]'"` // per-row problems, capped at MaxImportIssues
+	FailureReason string
+
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
+
+// MaxImportIssues caps how many row problems are kept on an import
+const MaxImportIssues = 1000
+
+// ImportIssue is a problem with one spreadsheet row, numbered as in the file
+type ImportIssue struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Value   string `json:"value,omitempty"`
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped,omitempty"` // duplicate skipped rather than an error
+}
+
+// ImportIssueList is stored as a JSONB array
+type ImportIssueList []ImportIssue
+
+func (l ImportIssueList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]ImportIssue{})
+	}
+	return json.Marshal([]ImportIssue(l))
+}
+
+func (l *ImportIssueList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
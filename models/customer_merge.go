@@ -0,0 +1,71 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerMerge audits the merge of a duplicate customer into a surviving one.
+// The duplicate is deleted, so its details are kept here as they were.
+type CustomerMerge struct {
+	ID               uuid.UUID        `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID          uuid.UUID        `gorm:"type:uuid;index;not null"`
+	SurvivorID       uuid.UUID        `gorm:"type:uuid;index;not null"`
+	MergedCustomerID uuid.UUID        `gorm:"type:uuid;index;not null"`
+	MergedByUserID   uuid.UUID        `gorm:"type:uuid;not null"`
+	MergedCustomer   CustomerSnapshot `gorm:"type:jsonb;not null"`
+	SurvivorBefore   CustomerSnapshot `gorm:"type:jsonb;not null"`
+	MovedRecords     IntMap           `gorm:"type:jsonb;default:'{}'"` // table -> rows moved to the survivor
+	Reason           string
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
+
+// CustomerSnapshot is a customer's details and running totals at a point in time
+type CustomerSnapshot struct {
+	Name          string     `json:"name"`
+	Phone         string     `json:"phone"`
+	Email         string     `json:"email"`
+	Birthday      *time.Time `json:"birthday"`
+	Anniversary   *time.Time `json:"anniversary"`
+	Notes         string     `json:"notes"`
+	IsActive      bool       `json:"isActive"`
+	TotalVisits   int        `json:"totalVisits"`
+	TotalSpent    float64    `json:"totalSpent"`
+	LastVisit     *time.Time `json:"lastVisit"`
+	LoyaltyPoints int        `json:"loyaltyPoints"`
+	WalletBalance float64    `json:"walletBalance"`
+}
+
+// SnapshotCustomer copies the details kept in a merge record
+func SnapshotCustomer(c Customer) CustomerSnapshot {
+	return CustomerSnapshot{
+		Name:          c.Name,
+		Phone:         c.Phone,
+		Email:         c.Email,
+		Birthday:      c.Birthday,
+		Anniversary:   c.Anniversary,
+		Notes:         c.Notes,
+		IsActive:      c.IsActive,
+		TotalVisits:   c.TotalVisits,
+		TotalSpent:    c.TotalSpent,
+		LastVisit:     c.LastVisit,
+		LoyaltyPoints: c.LoyaltyPoints,
+		WalletBalance: c.WalletBalance,
+	}
+}
+
+func (s CustomerSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *CustomerSnapshot) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, s)
+}
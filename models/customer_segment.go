@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomerSegment is a saved set of rules picking out customers. Membership is
+// worked out when the segment is used, so it stays current as customers change.
+type CustomerSegment struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+	Name            string    `gorm:"not null"`
+	Description     string
+	Rules           SegmentRules `gorm:"type:jsonb;not null"`
+	CreatedAt       time.Time    `gorm:"autoCreateTime"`
+	UpdatedAt       time.Time    `gorm:"autoUpdateTime"`
+}
+
+// SegmentRules are combined with AND; a list matches any of its values.
+// Unset rules don't filter.
+type SegmentRules struct {
+	TagsAny     []string `json:"tagsAny,omitempty"`     // has at least one of these tags
+	TagsAll     []string `json:"tagsAll,omitempty"`     // has every one of these tags
+	ExcludeTags []string `json:"excludeTags,omitempty"` // has none of these tags
+
+	MinTotalSpent *float64 `json:"minTotalSpent,omitempty"`
+	MaxTotalSpent *float64 `json:"maxTotalSpent,omitempty"`
+	MinVisits     *int     `json:"minVisits,omitempty"`
+	MaxVisits     *int     `json:"maxVisits,omitempty"`
+
+	LastVisitWithinDays *int `json:"lastVisitWithinDays,omitempty"` // visited in the last N days
+	NoVisitForDays      *int `json:"noVisitForDays,omitempty"`      // has visited, but not in the last N days
+
+	ServiceIDs        []uuid.UUID `json:"serviceIds,omitempty"`        // has had any of these services
+	ServiceWithinDays *int        `json:"serviceWithinDays,omitempty"` // ... in the last N days
+
+	BirthdayMonths []int    `json:"birthdayMonths,omitempty"` // 1-12
+	Genders        []string `json:"genders,omitempty"`
+}
+
+func (r SegmentRules) Value() (driver.Value, error) {
+	return json.Marshal(r)
+}
+
+func (r *SegmentRules) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, r)
+}
+
+// SegmentMessage is one message sent to every active customer in a segment.
+// Sending runs in the background; the counts show how far it has got.
+type SegmentMessage struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	SegmentID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+	Message         string    `gorm:"not null"`
+	Status          string    `gorm:"type:varchar(20);default:'queued'"` // 'queued', 'running', 'completed' or 'failed'
+
+	TotalRecipients int `gorm:"default:0"`
+	ProcessedCount  int `gorm:"default:0"`
+	SentCount       int `gorm:"default:0"`
+	FailedCount     int `gorm:"default:0"`
+	SkippedCount    int `gorm:"default:0"` // no channel the customer may be messaged on
+
+	FailureReason string
+	StartedAt     *time.Time
+	FinishedAt    *time.Time
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataSubjectResponseDays is how long the salon has to act on a request
+const DataSubjectResponseDays = 30
+
+// DataSubjectRequest tracks a customer's request under privacy law for a copy
+// of their data or for its erasure, from receipt until it is fulfilled or refused
+type DataSubjectRequest struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Type             string `gorm:"type:varchar(20);not null"`                   // 'export' or 'erasure'
+	Status           string `gorm:"type:varchar(20);not null;default:'pending'"` // 'pending', 'completed' or 'rejected'
+	RequestedVia     string `gorm:"type:varchar(20);not null"`                   // 'in_store', 'email', 'phone' or 'written'
+	SubjectReference string // masked phone number, so the request stays recognisable after erasure
+	Note             string
+	RejectionReason  string
+	Summary          IntMap `gorm:"type:jsonb;default:'{}'"` // records exported or anonymised, by kind
+
+	RequestedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+	FulfilledByUserID *uuid.UUID `gorm:"type:uuid"`
+	DueAt             time.Time  `gorm:"not null"`
+	CompletedAt       *time.Time
+	CreatedAt         time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GiftCard is a salon-issued stored-value card redeemable against invoices
+type GiftCard struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Code         string  `gorm:"uniqueIndex;not null"`
+	InitialValue float64 `gorm:"type:decimal(10,2);not null"`
+	Balance      float64 `gorm:"type:decimal(10,2);not null"`
+	ExpiresAt    *time.Time
+	Status       string `gorm:"type:varchar(20);not null;default:'active'"` // 'active' or 'void'
+
+	PurchaserCustomerID uuid.UUID  `gorm:"type:uuid;index;not null"`
+	RecipientCustomerID *uuid.UUID `gorm:"type:uuid;index"`
+	RecipientName       string
+	RecipientPhone      string
+	RecipientEmail      string
+	Message             string
+
+	SoldInvoiceID uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+
+	Transactions []GiftCardTransaction `gorm:"foreignKey:GiftCardID"`
+}
+
+// IsExpired reports whether the card is past its expiry date
+func (g GiftCard) IsExpired(now time.Time) bool {
+	return g.ExpiresAt != nil && now.After(*g.ExpiresAt)
+}
+
+// GiftCardTransaction is one movement on a gift card's balance
+type GiftCardTransaction struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	GiftCardID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID       *uuid.UUID `gorm:"type:uuid;index"`
+	CreatedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+	Type            string     `gorm:"type:varchar(20);not null"` // 'issue', 'redeem', 'refund' or 'void'
+	Amount          float64    `gorm:"type:decimal(10,2);not null"`
+	BalanceAfter    float64    `gorm:"type:decimal(10,2);not null"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime"`
+}
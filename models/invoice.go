@@ -25,15 +25,78 @@ type Invoice struct {
 	PaymentMethod string
 	Notes         string
 
-	Items []InvoiceItem `gorm:"foreignKey:InvoiceID"`
+	CouponID   *uuid.UUID `gorm:"type:uuid;index"` // coupon that set part of Discount
+	CouponCode string
+
+	Items    []InvoiceItem    `gorm:"foreignKey:InvoiceID"`
+	Payments []InvoicePayment `gorm:"foreignKey:InvoiceID"`
+
+	// Set when the invoice is created or its items change; not stored
+	PatchTestWarnings []PatchTestWarning `gorm:"-" json:",omitempty"`
 }
 
 type InvoiceItem struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
-	InvoiceID   uuid.UUID `gorm:"type:uuid;index;not null"`
-	ServiceID   uuid.UUID `gorm:"type:uuid;index;not null"`
-	ServiceName string    `gorm:"not null"`
-	Quantity    int       `gorm:"default:1"`
-	UnitPrice   float64   `gorm:"type:decimal(10,2);not null"`
-	TotalPrice  float64   `gorm:"type:decimal(10,2);not null"`
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	InvoiceID  uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ItemType   string     `gorm:"type:varchar(20);not null;default:'service'"` // 'service', 'product', 'gift_card', 'membership' or 'package'
+	ServiceID  *uuid.UUID `gorm:"type:uuid;index"`
+	ProductID  *uuid.UUID `gorm:"type:uuid;index"`
+	GiftCardID *uuid.UUID `gorm:"type:uuid;index"`
+	SKU        string
+
+	ServiceName string  `gorm:"not null"` // line description, kept under its original column name
+	Category    string  // service or product category at the time of sale
+	Quantity    int     `gorm:"default:1"`
+	UnitPrice   float64 `gorm:"type:decimal(10,2);not null"`
+	TotalPrice  float64 `gorm:"type:decimal(10,2);not null"`
+	TaxRate     float64 `gorm:"type:decimal(5,2);default:0.0"`  // product lines carry their own rate
+	TaxAmount   float64 `gorm:"type:decimal(10,2);default:0.0"` // tax charged on this line
+
+	// Automatic pricing benefits applied to this line
+	DiscountAmount float64    `gorm:"type:decimal(10,2);default:0.0"` // taken off UnitPrice * Quantity
+	BenefitType    string     `gorm:"type:varchar(30)"`               // 'member_discount', 'member_free' or 'package_session'
+	MembershipID   *uuid.UUID `gorm:"type:uuid;index"`                // membership sold or used on this line
+	PackageID      *uuid.UUID `gorm:"type:uuid;index"`                // customer package sold or used on this line
+}
+
+// TaxAmount is the invoice-level tax rate applied to service lines plus the
+// tax already charged on product lines at their own rates
+func (inv Invoice) TaxAmount() float64 {
+	var serviceSubtotal, productTax float64
+	for _, item := range inv.Items {
+		switch item.ItemType {
+		case "product":
+			productTax += item.TaxAmount
+		case "gift_card":
+			// stored value is not taxed at sale
+		default:
+			serviceSubtotal += item.TotalPrice
+		}
+	}
+	return serviceSubtotal*inv.Tax/100 + productTax
+}
+
+// InvoicePayment is one tender applied to an invoice (cash, card, gift card, ...)
+type InvoicePayment struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	InvoiceID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+	Method          string     `gorm:"type:varchar(30);not null"` // 'cash', 'card', 'upi', 'gift_card', ...
+	Amount          float64    `gorm:"type:decimal(10,2);not null"`
+	GiftCardID      *uuid.UUID `gorm:"type:uuid;index"`
+	Reference       string
+	PaidAt          time.Time `gorm:"autoCreateTime"`
+}
+
+// PaymentStatusFor derives 'paid', 'partial' or 'unpaid' from the amounts
+func PaymentStatusFor(total, paid float64) string {
+	switch {
+	case paid <= 0:
+		return "unpaid"
+	case paid+0.005 >= total:
+		return "paid"
+	default:
+		return "partial"
+	}
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceDelivery records each attempt to send an invoice or receipt to a customer
+type InvoiceDelivery struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	SentByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+	Channel      string    `gorm:"type:varchar(20);not null"` // 'email'
+	Recipient    string    `gorm:"not null"`
+	DocumentType string    `gorm:"type:varchar(20);not null"` // 'invoice' or 'receipt'
+	Status       string    `gorm:"type:varchar(20);not null"` // 'sent' or 'failed'
+	ErrorMessage string
+	AttemptedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRevisionImmutable is returned when code tries to change a stored revision
+var ErrRevisionImmutable = errors.New("invoice revisions cannot be changed")
+
+// InvoiceRevision is an immutable record of one invoice update: who made it,
+// which fields changed and the line items before and after
+type InvoiceRevision struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID      uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_invoice_revision"`
+	Revision       int       `gorm:"not null;uniqueIndex:idx_invoice_revision"` // 1 for the first edit
+	EditedByUserID uuid.UUID `gorm:"type:uuid;index;not null"`
+
+	Changes  FieldChangeList  `gorm:"type:jsonb;default:'[]'"`
+	OldItems RevisionItemList `gorm:"type:jsonb;default:'[]'"`
+	NewItems RevisionItemList `gorm:"type:jsonb;default:'[]'"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+func (r *InvoiceRevision) BeforeUpdate(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+func (r *InvoiceRevision) BeforeDelete(tx *gorm.DB) error {
+	return ErrRevisionImmutable
+}
+
+// FieldChange is one invoice field's value before and after an edit
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// FieldChangeList is stored as a JSONB array
+type FieldChangeList []FieldChange
+
+func (l FieldChangeList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]FieldChange{})
+	}
+	return json.Marshal([]FieldChange(l))
+}
+
+func (l *FieldChangeList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
+
+// RevisionItem is a line item as it stood at a revision
+type RevisionItem struct {
+	ItemType       string     `json:"itemType"`
+	ServiceID      *uuid.UUID `json:"serviceId,omitempty"`
+	ProductID      *uuid.UUID `json:"productId,omitempty"`
+	Name           string     `json:"name"`
+	Quantity       int        `json:"quantity"`
+	UnitPrice      float64    `json:"unitPrice"`
+	DiscountAmount float64    `json:"discountAmount"`
+	TotalPrice     float64    `json:"totalPrice"`
+	TaxAmount      float64    `json:"taxAmount"`
+	BenefitType    string     `json:"benefitType,omitempty"`
+}
+
+// Key identifies the same line across revisions, whatever its quantity or price
+func (i RevisionItem) Key() string {
+	switch {
+	case i.ServiceID != nil:
+		return i.ItemType + ":" + i.ServiceID.String()
+	case i.ProductID != nil:
+		return i.ItemType + ":" + i.ProductID.String()
+	default:
+		return i.ItemType + ":" + i.Name
+	}
+}
+
+// RevisionItemsFrom snapshots invoice items for a revision
+func RevisionItemsFrom(items []InvoiceItem) RevisionItemList {
+	list := make(RevisionItemList, 0, len(items))
+	for _, item := range items {
+		list = append(list, RevisionItem{
+			ItemType:       item.ItemType,
+			ServiceID:      item.ServiceID,
+			ProductID:      item.ProductID,
+			Name:           item.ServiceName,
+			Quantity:       item.Quantity,
+			UnitPrice:      item.UnitPrice,
+			DiscountAmount: item.DiscountAmount,
+			TotalPrice:     item.TotalPrice,
+			TaxAmount:      item.TaxAmount,
+			BenefitType:    item.BenefitType,
+		})
+	}
+	return list
+}
+
+// RevisionItemList is stored as a JSONB array
+type RevisionItemList []RevisionItem
+
+func (l RevisionItemList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]RevisionItem{})
+	}
+	return json.Marshal([]RevisionItem(l))
+}
+
+func (l *RevisionItemList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
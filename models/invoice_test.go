@@ -0,0 +1,72 @@
+// models/invoice_test.go
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInvoiceTaxAmount(t *testing.T) {
+	tests := []struct {
+		name  string
+		tax   float64
+		items []InvoiceItem
+		want  float64
+	}{
+		{"no items", 18, nil, 0},
+		{"services at the invoice rate", 18, []InvoiceItem{
+			{ItemType: "service", TotalPrice: 1000},
+			{ItemType: "service", TotalPrice: 500},
+		}, 270},
+		{"products at their own rate", 18, []InvoiceItem{
+			{ItemType: "product", TotalPrice: 400, TaxRate: 12, TaxAmount: 48},
+		}, 48},
+		{"mixed", 18, []InvoiceItem{
+			{ItemType: "service", TotalPrice: 1000},
+			{ItemType: "product", TotalPrice: 400, TaxRate: 12, TaxAmount: 48},
+		}, 228},
+		{"gift cards are not taxed", 18, []InvoiceItem{
+			{ItemType: "gift_card", TotalPrice: 2000},
+			{ItemType: "service", TotalPrice: 100},
+		}, 18},
+		{"memberships and packages at the invoice rate", 10, []InvoiceItem{
+			{ItemType: "membership", TotalPrice: 3000},
+			{ItemType: "package", TotalPrice: 2000},
+		}, 500},
+		{"no invoice rate", 0, []InvoiceItem{
+			{ItemType: "service", TotalPrice: 1000},
+			{ItemType: "product", TotalPrice: 100, TaxAmount: 5},
+		}, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := Invoice{Tax: tt.tax, Items: tt.items}
+			if got := inv.TaxAmount(); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("TaxAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaymentStatusFor(t *testing.T) {
+	tests := []struct {
+		name        string
+		total, paid float64
+		want        string
+	}{
+		{"nothing paid", 1000, 0, "unpaid"},
+		{"refunded below zero", 1000, -50, "unpaid"},
+		{"part paid", 1000, 400, "partial"},
+		{"paid in full", 1000, 1000, "paid"},
+		{"within half a paisa", 1000, 999.996, "paid"},
+		{"a paisa short", 1000, 999.99, "partial"},
+		{"overpaid", 1000, 1200, "paid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PaymentStatusFor(tt.total, tt.paid); got != tt.want {
+				t.Errorf("PaymentStatusFor(%v, %v) = %s, want %s", tt.total, tt.paid, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// InvoiceVoid keeps a record of a deleted invoice and the payments handed back
+type InvoiceVoid struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceNumber  string    `gorm:"not null"`
+	CustomerID     uuid.UUID `gorm:"type:uuid;index;not null"`
+	VoidedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	InvoiceDate time.Time
+	Total       float64           `gorm:"type:decimal(10,2);not null"`
+	Discount    float64           `gorm:"type:decimal(10,2);default:0.0"`
+	Payments    VoidedPaymentList `gorm:"type:jsonb;default:'[]'"` // refunded when the invoice was voided
+	Reason      string
+	VoidedAt    time.Time `gorm:"autoCreateTime;index"`
+}
+
+// VoidedPayment is a payment that was on an invoice when it was voided
+type VoidedPayment struct {
+	Method string    `json:"method"`
+	Amount float64   `json:"amount"`
+	PaidAt time.Time `json:"paidAt"`
+}
+
+// VoidedPaymentList is stored as a JSONB array
+type VoidedPaymentList []VoidedPayment
+
+func (l VoidedPaymentList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]VoidedPayment{})
+	}
+	return json.Marshal([]VoidedPayment(l))
+}
+
+func (l *VoidedPaymentList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoyaltyProgram holds a salon's rules for earning and redeeming points
+type LoyaltyProgram struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID   uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	IsEnabled bool      `gorm:"default:false"`
+
+	// Earning
+	SpendPerPoint       float64  `gorm:"type:decimal(10,2);not null;default:100"` // amount spent to earn one point
+	CategoryMultipliers FloatMap `gorm:"type:jsonb;default:'{}'"`                 // service/product category => multiplier
+	BirthdayMultiplier  float64  `gorm:"type:decimal(5,2);default:1"`             // during the customer's birthday month
+
+	// Redemption
+	PointValue      float64 `gorm:"type:decimal(10,4);not null;default:1"` // currency value of one point
+	MinRedeemPoints int     `gorm:"default:0"`
+	ExpiryMonths    int     `gorm:"default:0"` // months before earned points expire, 0 = never
+
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// LoyaltyTransaction is one entry in a customer's points ledger
+type LoyaltyTransaction struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID       *uuid.UUID `gorm:"type:uuid;index"`
+	CreatedByUserID *uuid.UUID `gorm:"type:uuid"`
+
+	Type         string `gorm:"type:varchar(20);not null"` // 'earn', 'redeem', 'expire' or 'adjust'
+	Points       int    `gorm:"not null"`                  // positive for credits, negative for debits
+	BalanceAfter int    `gorm:"not null"`
+	Note         string
+
+	// Credits are used oldest-expiring first; Remaining is what is left of this credit
+	Remaining int        `gorm:"default:0"`
+	ExpiresAt *time.Time `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MembershipPlan is a paid plan giving members automatic pricing benefits
+type MembershipPlan struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name           string    `gorm:"not null"`
+	Description    string
+	Fee            float64 `gorm:"type:decimal(10,2);not null"`
+	DurationMonths int     `gorm:"not null"`
+
+	// Benefits
+	DiscountPercent      float64    `gorm:"type:decimal(5,2);default:0.0"` // off service lines
+	FreeServicesPerMonth int        `gorm:"default:0"`
+	FreeServiceIDs       StringList `gorm:"type:jsonb;default:'[]'"` // services that can be taken free, empty = any
+	PriorityBooking      bool       `gorm:"default:false"`
+
+	IsActive  bool      `gorm:"default:true"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// CustomerMembership is a plan sold to a customer for a fixed period
+type CustomerMembership struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	PlanID          uuid.UUID  `gorm:"type:uuid;index;not null"`
+	SoldInvoiceID   uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+	RenewedFromID   *uuid.UUID `gorm:"type:uuid"`
+
+	StartDate   time.Time `gorm:"not null"`
+	EndDate     time.Time `gorm:"index;not null"`
+	PricePaid   float64   `gorm:"type:decimal(10,2);not null"`
+	Status      string    `gorm:"type:varchar(20);not null;default:'active'"` // 'active' or 'cancelled'
+	CancelledAt *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+
+	Plan MembershipPlan `gorm:"foreignKey:PlanID"`
+}
+
+// IsCurrent reports whether the membership gives benefits at the given time
+func (m CustomerMembership) IsCurrent(now time.Time) bool {
+	return m.Status == "active" && !now.Before(m.StartDate) && now.Before(m.EndDate)
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ServicePackage is a prepaid bundle of service sessions, e.g. "10 blow-dries"
+type ServicePackage struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name         string    `gorm:"not null"`
+	Description  string
+	Price        float64   `gorm:"type:decimal(10,2);not null"`
+	ValidityDays int       `gorm:"default:0"` // days from purchase the sessions can be used, 0 = no expiry
+	IsActive     bool      `gorm:"default:true"`
+	CreatedAt    time.Time `gorm:"autoCreateTime"`
+
+	Items []ServicePackageItem `gorm:"foreignKey:PackageID"`
+}
+
+// ServicePackageItem is one service and its session count within a package
+type ServicePackageItem struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	PackageID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceID uuid.UUID `gorm:"type:uuid;not null"`
+	Quantity  int       `gorm:"not null"`
+}
+
+// CustomerPackage is a package sold to a customer
+type CustomerPackage struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	PackageID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	SoldInvoiceID   uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	Name        string     `gorm:"not null"` // package name at the time of sale
+	PricePaid   float64    `gorm:"type:decimal(10,2);not null"`
+	PurchasedAt time.Time  `gorm:"not null"`
+	ExpiresAt   *time.Time `gorm:"index"`
+	Status      string     `gorm:"type:varchar(20);not null;default:'active'"` // 'active' or 'cancelled'
+	CancelledAt *time.Time
+
+	Sessions []CustomerPackageSession `gorm:"foreignKey:CustomerPackageID"`
+}
+
+// IsExpired reports whether the package's sessions can no longer be used
+func (p CustomerPackage) IsExpired(now time.Time) bool {
+	return p.ExpiresAt != nil && !now.Before(*p.ExpiresAt)
+}
+
+// CustomerPackageSession tracks the sessions of one service left on a customer's package
+type CustomerPackageSession struct {
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	CustomerPackageID uuid.UUID `gorm:"type:uuid;index;not null"`
+	ServiceID         uuid.UUID `gorm:"type:uuid;not null"`
+	ServiceName       string    `gorm:"not null"`
+	Total             int       `gorm:"not null"`
+	Used              int       `gorm:"default:0"`
+	UnitValue         float64   `gorm:"type:decimal(10,2);not null"` // share of the package price per session, for deferred revenue
+}
+
+// Remaining is the number of sessions still available
+func (s CustomerPackageSession) Remaining() int {
+	return s.Total - s.Used
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentLink is an online payment page sent to a customer for an invoice balance
+type PaymentLink struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	InvoiceID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	Provider       string `gorm:"type:varchar(20);not null;uniqueIndex:idx_provider_link"`
+	ProviderLinkID string `gorm:"not null;uniqueIndex:idx_provider_link"`
+	URL            string `gorm:"not null"`
+
+	Amount     float64 `gorm:"type:decimal(10,2);not null"`
+	Currency   string  `gorm:"type:varchar(3);not null"`
+	AmountPaid float64 `gorm:"type:decimal(10,2);default:0.0"`
+	Status     string  `gorm:"type:varchar(20);not null;default:'created'"` // 'created', 'partially_paid', 'paid', 'expired' or 'cancelled'
+	ExpiresAt  *time.Time
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+
+	Payments []GatewayPayment `gorm:"foreignKey:PaymentLinkID"`
+}
+
+// GatewayPayment is a payment captured by the provider. The unique provider
+// payment ID means a payment is posted to its invoice once, however it arrives.
+type GatewayPayment struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID           uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	PaymentLinkID     uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoicePaymentID  *uuid.UUID `gorm:"type:uuid"` // nil when the invoice was deleted before the money arrived
+	Provider          string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_provider_payment"`
+	ProviderPaymentID string     `gorm:"not null;uniqueIndex:idx_provider_payment"`
+	Method            string     `gorm:"type:varchar(30)"` // provider's method, e.g. 'upi' or 'card'
+	Amount            float64    `gorm:"type:decimal(10,2);not null"`
+	RefundedAmount    float64    `gorm:"type:decimal(10,2);default:0.0"`
+	CreatedAt         time.Time  `gorm:"autoCreateTime"`
+}
+
+// RefundableAmount is what can still be refunded through the provider
+func (p GatewayPayment) RefundableAmount() float64 {
+	return p.Amount - p.RefundedAmount
+}
+
+// PaymentWebhookEvent records each processed webhook so redeliveries are ignored
+type PaymentWebhookEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Provider   string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_provider_event"`
+	EventID    string    `gorm:"not null;uniqueIndex:idx_provider_event"`
+	Type       string    `gorm:"type:varchar(50)"`
+	ReceivedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// Product is a retail item (shampoo, serum, tools) sold over the counter
+type Product struct {
+	ID            uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID       uuid.UUID `gorm:"type:uuid;index;not null;uniqueIndex:idx_salon_sku,priority:1"`
+	Name          string    `gorm:"not null"`
+	SKU           string    `gorm:"not null;uniqueIndex:idx_salon_sku,priority:2"`
+	Brand         string
+	Description   string
+	Category      string  `gorm:"default:'General'"`
+	Price         float64 `gorm:"type:decimal(10,2);not null"`
+	TaxRate       float64 `gorm:"type:decimal(5,2);default:0.0"` // percentage
+	StockQuantity int     `gorm:"default:0"`
+	IsActive      bool    `gorm:"default:true"`
+
+	InvoiceItems []InvoiceItem `gorm:"foreignKey:ProductID"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegisterSession is one shift on the salon's cash drawer, from opening float to counted close
+type RegisterSession struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID        uuid.UUID  `gorm:"type:uuid;index;not null"`
+	OpenedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+	ClosedByUserID *uuid.UUID `gorm:"type:uuid"`
+
+	Status       string    `gorm:"type:varchar(20);not null;default:'open'"` // 'open' or 'closed'
+	OpeningFloat float64   `gorm:"type:decimal(10,2);not null"`
+	OpenedAt     time.Time `gorm:"not null"`
+	ClosedAt     *time.Time
+
+	// Set on close
+	ExpectedCash *float64 `gorm:"type:decimal(10,2)"`
+	CountedCash  *float64 `gorm:"type:decimal(10,2)"`
+	Variance     *float64 `gorm:"type:decimal(10,2)"` // counted minus expected
+	ZReport      JSONB    `gorm:"type:jsonb"`         // snapshot of the report at close
+	Notes        string
+
+	Movements []CashMovement `gorm:"foreignKey:SessionID"`
+}
+
+// CashMovement is cash put into or taken out of the drawer outside of sales
+type CashMovement struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID `gorm:"type:uuid;index;not null"`
+	SessionID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	CreatedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+	Type            string    `gorm:"type:varchar(20);not null"` // 'pay_in' or 'pay_out'
+	Amount          float64   `gorm:"type:decimal(10,2);not null"`
+	Reason          string    `gorm:"not null"`
+	CreatedAt       time.Time `gorm:"autoCreateTime"`
+}
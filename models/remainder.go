@@ -1,9 +1,14 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// ReminderTemplate holds a salon's message for one reminder type.
+// Types: 'birthday', 'anniversary', 'membership_expiry', 'dues' (reminder_type enum;
+// main.go adds the values that came later).
 type ReminderTemplate struct {
 	ID       uuid.UUID `gorm:"type:uuid;primary_key"`
 	SalonID  uuid.UUID `gorm:"type:uuid;index;not null"`
@@ -11,3 +16,18 @@ type ReminderTemplate struct {
 	Message  string    `gorm:"type:text;not null"`
 	IsActive bool      `gorm:"default:true"`
 }
+
+// ReminderLog records every message ReminderService attempts to send
+type ReminderLog struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID   uuid.UUID  `gorm:"type:uuid;index;not null"`
+	TemplateID   *uuid.UUID `gorm:"type:uuid"`
+	ReferenceID  *uuid.UUID `gorm:"type:uuid;index"` // record the reminder is about, e.g. a membership
+	Type         string     `gorm:"type:varchar(30);not null"`
+	Message      string     `gorm:"type:text;not null"`
+	Channel      string     `gorm:"type:varchar(20);not null"` // 'whatsapp' or 'sms'
+	Status       string     `gorm:"type:varchar(20);not null"` // 'sent' or 'failed'
+	ErrorMessage string
+	SentAt       time.Time
+}
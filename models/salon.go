@@ -1,6 +1,8 @@
 package models
 
 import (
+	"salonpro-backend/utils"
+
 	"github.com/google/uuid"
 )
 
@@ -14,9 +16,40 @@ type Salon struct {
 	WhatsAppNotifications bool  `gorm:"default:false"`
 	SMSNotifications      bool  `gorm:"default:false"`
 
+	DuesReminders            bool `gorm:"default:false"` // opt-in reminders for unpaid invoice balances
+	DuesReminderIntervalDays int  `gorm:"default:7"`     // days after the invoice, and between repeats
+
+	// Only send marketing to customers with recorded consent, rather than to
+	// everyone who hasn't opted out
+	MarketingRequiresOptIn bool `gorm:"default:false"`
+
+	CurrencyCode  string `gorm:"type:varchar(3);default:'INR'"`    // ISO 4217
+	DecimalPlaces int    `gorm:"default:2"`                        // minor units shown on documents
+	Locale        string `gorm:"type:varchar(20);default:'en-IN'"` // BCP 47, drives number and date formatting
+
+	UPIID        string `gorm:"type:varchar(100)"` // virtual payment address shown on invoice QR codes
+	UPIPayeeName string `gorm:"type:varchar(100)"` // name the customer's UPI app shows when paying
+
 	Users             []User             `gorm:"foreignKey:SalonID"`
 	Customers         []Customer         `gorm:"foreignKey:SalonID"`
 	Services          []Service          `gorm:"foreignKey:SalonID"`
 	Invoices          []Invoice          `gorm:"foreignKey:SalonID"`
 	ReminderTemplates []ReminderTemplate `gorm:"foreignKey:SalonID"`
 }
+
+// MoneyFormat returns the formatter for this salon's currency and locale settings
+func (s Salon) MoneyFormat() utils.MoneyFormat {
+	f := utils.MoneyFormat{
+		CurrencyCode:  s.CurrencyCode,
+		DecimalPlaces: s.DecimalPlaces,
+		Locale:        s.Locale,
+	}
+	if f.CurrencyCode == "" {
+		f.CurrencyCode = utils.DefaultCurrencyCode
+		f.DecimalPlaces = utils.DefaultDecimalPlaces
+	}
+	if f.Locale == "" {
+		f.Locale = utils.DefaultLocale
+	}
+	return f
+}
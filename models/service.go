@@ -14,5 +14,8 @@ type Service struct {
 	Category    string  `gorm:"default:'General'"`
 	IsActive    bool    `gorm:"default:true"`
 
+	RequiresPatchTest  bool `gorm:"default:false"` // e.g. colour services; checked when the service is invoiced
+	PatchTestValidDays int  `gorm:"default:0"`     // how long a test lasts for this service; 0 uses DefaultPatchTestValidDays
+
 	InvoiceItems []InvoiceItem `gorm:"foreignKey:ServiceID"`
 }
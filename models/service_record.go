@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPatchTestValidDays is how long a patch test lasts when neither the
+// test nor the service says otherwise
+const DefaultPatchTestValidDays = 180
+
+// ServiceRecord holds a stylist's technical notes from one visit, such as a
+// colour formula, so the next appointment can repeat or adjust it
+type ServiceRecord struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID          uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID       uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceItemID    *uuid.UUID `gorm:"type:uuid;index"` // the invoiced service this record describes, if any
+	ServiceID        *uuid.UUID `gorm:"type:uuid;index"`
+	RecordedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+
+	PerformedAt       time.Time `gorm:"index;not null"`
+	Formula           string    // shades and quantities, e.g. "6/0 30g + 6/3 10g"
+	Developer         string    // developer strength, e.g. "20 vol (6%)"
+	ProcessingMinutes int
+	Result            string // how it turned out and what to change next time
+	Notes             string
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// CustomerAllergy is a known allergy or sensitivity to bear in mind when
+// choosing products
+type CustomerAllergy struct {
+	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID          uuid.UUID `gorm:"type:uuid;index;not null"`
+	CustomerID       uuid.UUID `gorm:"type:uuid;index;not null"`
+	RecordedByUserID uuid.UUID `gorm:"type:uuid;not null"`
+
+	Allergen string `gorm:"not null"`                                 // e.g. "PPD" or "latex"
+	Severity string `gorm:"type:varchar(20);not null;default:'mild'"` // 'mild', 'moderate' or 'severe'
+	Reaction string
+	Notes    string
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// PatchTest is a skin test done before a service that needs one. A test with no
+// ServiceID covers every service that requires a patch test.
+type PatchTest struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID           uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID        uuid.UUID  `gorm:"type:uuid;index;not null"`
+	ServiceID         *uuid.UUID `gorm:"type:uuid;index"`
+	PerformedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+
+	Product   string    // product or shade tested
+	TestedAt  time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"index;not null"`
+	Result    string    `gorm:"type:varchar(20);not null;default:'pending'"` // 'pending', 'passed' or 'failed'
+	Notes     string
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// PatchTestWarning flags a service on an invoice whose customer has no valid
+// patch test for it
+type PatchTestWarning struct {
+	ServiceID   uuid.UUID  `json:"serviceId"`
+	ServiceName string     `json:"serviceName"`
+	Reason      string     `json:"reason"` // 'missing', 'pending', 'failed' or 'expired'
+	Message     string     `json:"message"`
+	LastTest    *PatchTest `json:"lastTest,omitempty"`
+}
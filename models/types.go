@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// StringList is a list of strings stored as a JSONB array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, l)
+}
+
+// Contains reports whether s is in the list
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// FloatMap is a string to number map stored as a JSONB object
+type FloatMap map[string]float64
+
+func (m FloatMap) Value() (driver.Value, error) {
+	if m == nil {
+		return json.Marshal(map[string]float64{})
+	}
+	return json.Marshal(map[string]float64(m))
+}
+
+func (m *FloatMap) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, m)
+}
+
+// StringMap is a string to string map stored as a JSONB object
+type StringMap map[string]string
+
+func (m StringMap) Value() (driver.Value, error) {
+	if m == nil {
+		return json.Marshal(map[string]string{})
+	}
+	return json.Marshal(map[string]string(m))
+}
+
+func (m *StringMap) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, m)
+}
+
+// IntMap is a string to integer map stored as a JSONB object
+type IntMap map[string]int
+
+func (m IntMap) Value() (driver.Value, error) {
+	if m == nil {
+		return json.Marshal(map[string]int{})
+	}
+	return json.Marshal(map[string]int(m))
+}
+
+func (m *IntMap) Scan(value interface{}) error {
+	b, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(b, m)
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WalletTransaction is one movement on a customer's prepaid wallet
+type WalletTransaction struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	SalonID         uuid.UUID  `gorm:"type:uuid;index;not null"`
+	CustomerID      uuid.UUID  `gorm:"type:uuid;index;not null"`
+	InvoiceID       *uuid.UUID `gorm:"type:uuid;index"` // invoice paid or refunded
+	CreatedByUserID uuid.UUID  `gorm:"type:uuid;not null"`
+
+	Type          string  `gorm:"type:varchar(20);not null"`   // 'topup', 'debit' or 'refund'
+	Amount        float64 `gorm:"type:decimal(10,2);not null"` // positive for money in, negative for money out
+	BalanceAfter  float64 `gorm:"type:decimal(10,2);not null"`
+	ReceiptNumber string  `gorm:"index"` // issued for top-ups
+	PaymentMethod string  // how a top-up was paid: 'cash', 'card', 'upi', ...
+	Reference     string
+	Note          string
+
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"salonpro-backend/config"
 	"salonpro-backend/controllers"
+	"salonpro-backend/services"
 	"salonpro-backend/utils"
 
 	"github.com/gin-contrib/cors"
@@ -26,6 +27,10 @@ func SetupRouter() *gin.Engine {
 
 	r.Use(config.PerformanceLogger())
 
+	// Online payments; the provider calls the webhook, so it sits outside auth
+	paymentController := controllers.PaymentGatewayController{Gateway: services.NewPaymentGateway()}
+	r.POST("/webhooks/payments", paymentController.HandlePaymentWebhook)
+
 	auth := r.Group("/auth")
 	{
 		auth.POST("/register", controllers.Register)
@@ -38,14 +43,99 @@ func SetupRouter() *gin.Engine {
 	api := r.Group("/api")
 	api.Use(utils.AuthMiddleware())
 	{
+		// Shared integrations (declared before the "services" group shadows the package)
+		deliveryController := controllers.InvoiceDeliveryController{Mailer: services.NewMailer()}
+		segmentController := controllers.CustomerSegmentController{Reminders: services.NewReminderService(config.DB)}
+		storage := services.NewStorage()
+		attachmentController := controllers.AttachmentController{Storage: storage}
+		dataRequestController := controllers.DataSubjectRequestController{Storage: storage}
+
 		// Customer routes
 		customers := api.Group("/customers")
 		{
 			customers.POST("", controllers.CreateCustomer)
 			customers.GET("", controllers.GetCustomers)
+			customers.GET("/export", controllers.ExportCustomers)
+			customers.GET("/exports", controllers.GetCustomerExports)
+			customers.GET("/duplicates", controllers.GetDuplicateCustomers)
+			customers.GET("/tags", controllers.GetCustomerTags)
 			customers.GET("/:id", controllers.GetCustomer)
 			customers.PUT("/:id", controllers.UpdateCustomer)
 			customers.DELETE("/:id", controllers.DeleteCustomer)
+			customers.GET("/:id/memberships", controllers.GetCustomerMemberships)
+			customers.GET("/:id/packages", controllers.GetCustomerPackages)
+			customers.GET("/:id/loyalty", controllers.GetCustomerLoyalty)
+			customers.POST("/:id/loyalty/adjust", controllers.AdjustCustomerLoyalty)
+			customers.GET("/:id/wallet", controllers.GetWalletStatement)
+			customers.GET("/:id/statement", controllers.GetCustomerStatement)
+			customers.POST("/:id/wallet/topup", controllers.TopUpWallet)
+			customers.POST("/:id/merge", controllers.MergeCustomer)
+			customers.GET("/:id/merges", controllers.GetCustomerMerges)
+			customers.GET("/:id/household", controllers.GetCustomerHousehold)
+			customers.POST("/:id/household", controllers.AddHouseholdMember)
+			customers.POST("/:id/household/link", controllers.LinkHouseholdMember)
+			customers.POST("/:id/household/unlink", controllers.UnlinkHouseholdMember)
+			customers.POST("/:id/household/make-primary", controllers.MakeHouseholdPrimary)
+			customers.GET("/:id/timeline", controllers.GetCustomerTimeline)
+			customers.GET("/:id/consents", controllers.GetCustomerConsents)
+			customers.POST("/:id/consents", controllers.RecordCustomerConsent)
+			customers.GET("/:id/service-records", controllers.GetServiceRecords)
+			customers.POST("/:id/service-records", controllers.CreateServiceRecord)
+			customers.PUT("/:id/service-records/:recordId", controllers.UpdateServiceRecord)
+			customers.DELETE("/:id/service-records/:recordId", controllers.DeleteServiceRecord)
+			customers.GET("/:id/allergies", controllers.GetCustomerAllergies)
+			customers.POST("/:id/allergies", controllers.CreateCustomerAllergy)
+			customers.PUT("/:id/allergies/:allergyId", controllers.UpdateCustomerAllergy)
+			customers.DELETE("/:id/allergies/:allergyId", controllers.DeleteCustomerAllergy)
+			customers.GET("/:id/patch-tests", controllers.GetPatchTests)
+			customers.POST("/:id/patch-tests", controllers.CreatePatchTest)
+			customers.GET("/:id/patch-tests/check", controllers.CheckPatchTests)
+			customers.PUT("/:id/patch-tests/:testId", controllers.UpdatePatchTest)
+			customers.GET("/:id/attachments", attachmentController.GetAttachments)
+			customers.POST("/:id/attachments", attachmentController.UploadAttachment)
+			customers.GET("/:id/attachments/:attachmentId/file", attachmentController.DownloadAttachment)
+			customers.PUT("/:id/attachments/:attachmentId", attachmentController.UpdateAttachment)
+			customers.DELETE("/:id/attachments/:attachmentId", attachmentController.DeleteAttachment)
+			customers.POST("/:id/data-requests", controllers.CreateDataSubjectRequest)
+		}
+
+		// Customer segment routes
+		segments := api.Group("/segments")
+		{
+			segments.POST("", controllers.CreateCustomerSegment)
+			segments.GET("", controllers.GetCustomerSegments)
+			segments.POST("/preview", controllers.PreviewCustomerSegment)
+			segments.GET("/:id", controllers.GetCustomerSegment)
+			segments.PUT("/:id", controllers.UpdateCustomerSegment)
+			segments.DELETE("/:id", controllers.DeleteCustomerSegment)
+			segments.POST("/:id/messages", segmentController.SendSegmentMessage)
+			segments.GET("/:id/messages", controllers.GetSegmentMessages)
+		}
+
+		// Messaging suppression list
+		suppressions := api.Group("/suppressions")
+		{
+			suppressions.GET("", controllers.GetMessageSuppressions)
+			suppressions.POST("", controllers.CreateMessageSuppression)
+			suppressions.DELETE("/:id", controllers.DeleteMessageSuppression)
+		}
+
+		// Privacy (data subject) request routes
+		dataRequests := api.Group("/data-requests")
+		{
+			dataRequests.GET("", controllers.GetDataSubjectRequests)
+			dataRequests.GET("/:id", controllers.GetDataSubjectRequest)
+			dataRequests.GET("/:id/archive", dataRequestController.DownloadCustomerDataArchive)
+			dataRequests.POST("/:id/erase", dataRequestController.EraseCustomerData)
+			dataRequests.POST("/:id/reject", controllers.RejectDataSubjectRequest)
+		}
+
+		// Customer import routes
+		customerImports := api.Group("/customer-imports")
+		{
+			customerImports.POST("", controllers.ImportCustomers)
+			customerImports.GET("", controllers.GetCustomerImports)
+			customerImports.GET("/:id", controllers.GetCustomerImport)
 		}
 
 		// Service routes
@@ -58,6 +148,16 @@ func SetupRouter() *gin.Engine {
 			services.DELETE("/:id", controllers.DeleteService)
 		}
 
+		// Retail product routes
+		products := api.Group("/products")
+		{
+			products.POST("", controllers.CreateProduct)
+			products.GET("", controllers.GetProducts)
+			products.GET("/:id", controllers.GetProduct)
+			products.PUT("/:id", controllers.UpdateProduct)
+			products.DELETE("/:id", controllers.DeleteProduct)
+		}
+
 		// Invoice routes
 		invoices := api.Group("/invoices")
 		{
@@ -66,11 +166,100 @@ func SetupRouter() *gin.Engine {
 			invoices.GET("/:id", controllers.GetInvoice)
 			invoices.PUT("/:id", controllers.UpdateInvoice)
 			invoices.DELETE("/:id", controllers.DeleteInvoice)
+			invoices.GET("/:id/history", controllers.GetInvoiceHistory)
+
+			invoices.GET("/:id/pdf", deliveryController.DownloadInvoicePDF)
+			invoices.POST("/:id/send", deliveryController.SendInvoice)
+			invoices.GET("/:id/deliveries", deliveryController.GetInvoiceDeliveries)
+			invoices.POST("/:id/payments", controllers.RecordInvoicePayment)
+			invoices.POST("/:id/payment-links", paymentController.CreatePaymentLink)
+			invoices.GET("/:id/payment-links", paymentController.GetPaymentLinks)
+			invoices.GET("/:id/upi", controllers.GetInvoiceUPI)
+			invoices.GET("/:id/upi/qr", controllers.GetInvoiceUPIQR)
+		}
+
+		// Coupon routes
+		coupons := api.Group("/coupons")
+		{
+			coupons.POST("", controllers.CreateCoupon)
+			coupons.GET("", controllers.GetCoupons)
+			coupons.POST("/validate", controllers.ValidateCoupon)
+			coupons.GET("/:id", controllers.GetCoupon)
+			coupons.PUT("/:id", controllers.UpdateCoupon)
+			coupons.DELETE("/:id", controllers.DeleteCoupon)
+		}
+
+		// Gift card routes
+		giftCards := api.Group("/gift-cards")
+		{
+			giftCards.POST("", controllers.SellGiftCard)
+			giftCards.GET("", controllers.GetGiftCards)
+			giftCards.GET("/liability", controllers.GetGiftCardLiability)
+			giftCards.GET("/lookup/:code", controllers.LookupGiftCardBalance)
+			giftCards.GET("/:id", controllers.GetGiftCard)
+		}
+
+		// Membership routes
+		membershipPlans := api.Group("/membership-plans")
+		{
+			membershipPlans.POST("", controllers.CreateMembershipPlan)
+			membershipPlans.GET("", controllers.GetMembershipPlans)
+			membershipPlans.PUT("/:id", controllers.UpdateMembershipPlan)
+		}
+
+		memberships := api.Group("/memberships")
+		{
+			memberships.POST("", controllers.SellMembership)
+			memberships.POST("/:id/renew", controllers.RenewMembership)
+			memberships.PUT("/:id/cancel", controllers.CancelMembership)
+		}
+
+		// Loyalty programme routes
+		api.GET("/loyalty/program", controllers.GetLoyaltyProgram)
+		api.PUT("/loyalty/program", controllers.UpdateLoyaltyProgram)
+
+		// Online payment routes
+		api.POST("/payment-links/:id/refresh", paymentController.RefreshPaymentLink)
+		api.POST("/gateway-payments/:id/refund", paymentController.RefundGatewayPayment)
+
+		// Receivables routes
+		api.GET("/receivables", controllers.GetReceivables)
+
+		// Cash drawer routes
+		register := api.Group("/register/sessions")
+		{
+			register.POST("", controllers.OpenRegisterSession)
+			register.GET("", controllers.GetRegisterSessions)
+			register.GET("/current", controllers.GetCurrentRegisterSession)
+			register.GET("/:id", controllers.GetRegisterSession)
+			register.POST("/:id/movements", controllers.AddCashMovement)
+			register.POST("/:id/close", controllers.CloseRegisterSession)
+		}
+
+		// Customer wallet routes
+		api.GET("/wallets/liability", controllers.GetWalletLiability)
+
+		// Prepaid package routes
+		packages := api.Group("/packages")
+		{
+			packages.POST("", controllers.CreateServicePackage)
+			packages.GET("", controllers.GetServicePackages)
+			packages.GET("/:id", controllers.GetServicePackage)
+			packages.PUT("/:id", controllers.UpdateServicePackage)
+		}
+
+		customerPackages := api.Group("/customer-packages")
+		{
+			customerPackages.POST("", controllers.SellPackage)
+			customerPackages.PUT("/:id/expiry", controllers.ExtendCustomerPackage)
 		}
 
 		//Reports routes
 		reportController := controllers.ReportController{}
 		api.GET("/reports", reportController.GetReportAnalytics)
+		api.GET("/reports/export", reportController.ExportInvoicesCSV)
+		api.GET("/reports/memberships", reportController.GetMembershipReport)
+		api.GET("/reports/packages", reportController.GetPackageReport)
 
 		// Dashboard routes
 		api.GET("/dashboard", controllers.GetDashboardOverview)
@@ -83,6 +272,8 @@ func SetupRouter() *gin.Engine {
 			profile.PUT("/update-hours", controllers.UpdateWorkingHours)
 			profile.PUT("/update-templates", controllers.UpdateReminderTemplates)
 			profile.PUT("/update-notifications", controllers.UpdateNotifications)
+			profile.PUT("/update-regional", controllers.UpdateRegionalSettings)
+			profile.PUT("/update-upi", controllers.UpdateUPISettings)
 		}
 
 		employees := api.Group("/employees")
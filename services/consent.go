@@ -0,0 +1,174 @@
+// services/consent.go
+package services
+
+import (
+	"salonpro-backend/models"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ConsentMarketing     = "marketing"
+	ConsentTransactional = "transactional"
+)
+
+// ConsentChannels are the channels consent is recorded for
+var ConsentChannels = []string{"sms", "whatsapp", "email"}
+
+// reminderPurposes says whether each outbound message type is marketing or
+// transactional. Unknown types are treated as marketing, the stricter of the two.
+var reminderPurposes = map[string]string{
+	"birthday":          ConsentMarketing,
+	"anniversary":       ConsentMarketing,
+	"segment_message":   ConsentMarketing,
+	"membership_expiry": ConsentTransactional,
+	"dues":              ConsentTransactional,
+}
+
+// ReminderPurpose returns the consent purpose a message type needs
+func ReminderPurpose(reminderType string) string {
+	if purpose, ok := reminderPurposes[reminderType]; ok {
+		return purpose
+	}
+	return ConsentMarketing
+}
+
+// SuppressionAddress normalises a phone number or email address the way the
+// suppression list stores it, so "+91 98765 43210" and "+919876543210" match
+func SuppressionAddress(channel, address string) string {
+	if channel == "email" {
+		return strings.ToLower(strings.TrimSpace(address))
+	}
+	var b strings.Builder
+	for _, r := range address {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CustomerAddress is the customer's contact point on a channel
+func CustomerAddress(customer models.Customer, channel string) string {
+	if channel == "email" {
+		return SuppressionAddress(channel, customer.Email)
+	}
+	return SuppressionAddress(channel, customer.Phone)
+}
+
+// customerAddressColumn is the SQL for a customer's address on a channel,
+// normalised like SuppressionAddress
+func customerAddressColumn(channel string) string {
+	if channel == "email" {
+		return "lower(trim(customers.email))"
+	}
+	return "regexp_replace(customers.phone, '[^0-9]', '', 'g')"
+}
+
+// RecordConsent stores a consent change and keeps the suppression list in step:
+// withdrawing adds the customer's address, granting again removes it unless
+// another customer with the same address has opted out
+func RecordConsent(tx *gorm.DB, customer models.Customer, consent *models.CustomerConsent) error {
+	if err := tx.Create(consent).Error; err != nil {
+		return err
+	}
+
+	address := CustomerAddress(customer, consent.Channel)
+	if address == "" {
+		return nil
+	}
+	if consent.Granted {
+		// Household members can share a phone or email; one of them opting
+		// back in must not lift another's opt-out
+		var stillOptedOut int64
+		if err := tx.Raw(`
+			SELECT COUNT(*) FROM (
+				SELECT DISTINCT ON (cc.customer_id) cc.granted
+				FROM customer_consents cc
+				JOIN customers ON customers.id = cc.customer_id
+				WHERE cc.salon_id = ? AND cc.channel = ? AND cc.purpose = ? AND cc.customer_id <> ?
+				AND `+customerAddressColumn(consent.Channel)+` = ?
+				ORDER BY cc.customer_id, cc.created_at DESC
+			) latest
+			WHERE NOT latest.granted
+		`, consent.SalonID, consent.Channel, consent.Purpose, customer.ID, address).
+			Scan(&stillOptedOut).Error; err != nil {
+			return err
+		}
+		if stillOptedOut > 0 {
+			return nil
+		}
+		return tx.Where("salon_id = ? AND channel = ? AND purpose = ? AND address = ?",
+			consent.SalonID, consent.Channel, consent.Purpose, address).
+			Delete(&models.MessageSuppression{}).Error
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.MessageSuppression{
+		SalonID:         consent.SalonID,
+		Channel:         consent.Channel,
+		Purpose:         consent.Purpose,
+		Address:         address,
+		Reason:          "opt_out",
+		Source:          consent.Source,
+		CreatedByUserID: consent.CapturedByUserID,
+		Note:            consent.Note,
+	}).Error
+}
+
+// LatestConsents returns the customer's current consent per channel and purpose
+func LatestConsents(db *gorm.DB, customer models.Customer) ([]models.CustomerConsent, error) {
+	var consents []models.CustomerConsent
+	err := db.Raw(`
+		SELECT DISTINCT ON (channel, purpose) *
+		FROM customer_consents
+		WHERE salon_id = ? AND customer_id = ?
+		ORDER BY channel, purpose, created_at DESC
+	`, customer.SalonID, customer.ID).Scan(&consents).Error
+	return consents, err
+}
+
+// CanMessage reports whether the customer may be sent a message of the given
+// purpose on a channel, and if not, why. Messages are blocked once consent is
+// withdrawn or the address is suppressed, whoever it now belongs to. Salons
+// that require opt-in also need a recorded grant before sending marketing.
+func CanMessage(db *gorm.DB, salon models.Salon, customer models.Customer, channel, purpose string) (bool, string, error) {
+	address := CustomerAddress(customer, channel)
+	if address == "" {
+		return false, "no " + channel + " address", nil
+	}
+
+	// Opting out of transactional messages opts out of marketing too
+	purposes := []string{purpose}
+	if purpose == ConsentMarketing {
+		purposes = append(purposes, ConsentTransactional)
+	}
+
+	var suppressed int64
+	if err := db.Model(&models.MessageSuppression{}).
+		Where("salon_id = ? AND channel = ? AND purpose IN ? AND address = ?", customer.SalonID, channel, purposes, address).
+		Count(&suppressed).Error; err != nil {
+		return false, "", err
+	}
+	if suppressed > 0 {
+		return false, "address is on the suppression list", nil
+	}
+
+	var latest models.CustomerConsent
+	result := db.Where("salon_id = ? AND customer_id = ? AND channel = ? AND purpose = ?",
+		customer.SalonID, customer.ID, channel, purpose).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&latest)
+	if result.Error != nil {
+		return false, "", result.Error
+	}
+
+	switch {
+	case result.RowsAffected > 0 && !latest.Granted:
+		return false, purpose + " consent withdrawn", nil
+	case result.RowsAffected == 0 && purpose == ConsentMarketing && salon.MarketingRequiresOptIn:
+		return false, "no marketing consent", nil
+	}
+	return true, "", nil
+}
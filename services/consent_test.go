@@ -0,0 +1,63 @@
+// services/consent_test.go
+package services
+
+import (
+	"salonpro-backend/models"
+	"testing"
+)
+
+func TestSuppressionAddress(t *testing.T) {
+	tests := []struct {
+		channel, address, want string
+	}{
+		{"sms", "+91 98765 43210", "919876543210"},
+		{"sms", "+919876543210", "919876543210"},
+		{"whatsapp", "(080) 2345-6789", "08023456789"},
+		{"email", "  Asha.Rao@Example.COM ", "asha.rao@example.com"},
+		{"sms", "", ""},
+		{"email", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.channel+" "+tt.address, func(t *testing.T) {
+			if got := SuppressionAddress(tt.channel, tt.address); got != tt.want {
+				t.Errorf("SuppressionAddress(%q, %q) = %q, want %q", tt.channel, tt.address, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomerAddress(t *testing.T) {
+	customer := models.Customer{Phone: "+91 98765 43210", Email: "Asha@Example.com"}
+	tests := []struct {
+		channel, want string
+	}{
+		{"sms", "919876543210"},
+		{"whatsapp", "919876543210"},
+		{"email", "asha@example.com"},
+	}
+	for _, tt := range tests {
+		if got := CustomerAddress(customer, tt.channel); got != tt.want {
+			t.Errorf("CustomerAddress(%s) = %q, want %q", tt.channel, got, tt.want)
+		}
+	}
+}
+
+func TestReminderPurpose(t *testing.T) {
+	tests := []struct {
+		reminderType, want string
+	}{
+		{"birthday", ConsentMarketing},
+		{"anniversary", ConsentMarketing},
+		{"segment_message", ConsentMarketing},
+		{"membership_expiry", ConsentTransactional},
+		{"dues", ConsentTransactional},
+		{"something_new", ConsentMarketing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reminderType, func(t *testing.T) {
+			if got := ReminderPurpose(tt.reminderType); got != tt.want {
+				t.Errorf("ReminderPurpose(%q) = %q, want %q", tt.reminderType, got, tt.want)
+			}
+		})
+	}
+}
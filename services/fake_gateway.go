@@ -0,0 +1,129 @@
+// services/fake_gateway.go
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// FakeGateway keeps payment links in memory, for local development and tests.
+// Pay simulates a customer paying and returns the signed webhook the real
+// provider would send.
+type FakeGateway struct {
+	mu            sync.Mutex
+	webhookSecret string
+	links         map[string]*GatewayLink
+	refunds       []GatewayRefund
+}
+
+func NewFakeGateway(webhookSecret string) *FakeGateway {
+	return &FakeGateway{webhookSecret: webhookSecret, links: map[string]*GatewayLink{}}
+}
+
+func (g *FakeGateway) Name() string {
+	return "fake"
+}
+
+func (g *FakeGateway) CreatePaymentLink(req PaymentLinkRequest) (GatewayLink, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := "plink_fake_" + uuid.New().String()[:8]
+	link := &GatewayLink{
+		ID:        id,
+		URL:       "https://pay.fake.local/" + id,
+		Status:    "created",
+		Amount:    req.Amount,
+		ExpiresAt: req.ExpiresAt,
+	}
+	g.links[id] = link
+	return *link, nil
+}
+
+func (g *FakeGateway) GetPaymentLink(linkID string) (GatewayLink, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	link, ok := g.links[linkID]
+	if !ok {
+		return GatewayLink{}, fmt.Errorf("payment link %s not found", linkID)
+	}
+	result := *link
+	result.Payments = append([]ProviderPayment(nil), link.Payments...)
+	return result, nil
+}
+
+func (g *FakeGateway) Refund(paymentID string, amount float64, currency string) (GatewayRefund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	refund := GatewayRefund{
+		ID:        "rfnd_fake_" + uuid.New().String()[:8],
+		PaymentID: paymentID,
+		Amount:    amount,
+		Status:    "processed",
+	}
+	g.refunds = append(g.refunds, refund)
+	return refund, nil
+}
+
+// Refunds returns a copy of all refunds issued so far
+func (g *FakeGateway) Refunds() []GatewayRefund {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]GatewayRefund(nil), g.refunds...)
+}
+
+// Pay records a captured payment on the link and returns the webhook body and
+// its X-Fake-Signature header value
+func (g *FakeGateway) Pay(linkID string, amount float64, method string) ([]byte, string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	link, ok := g.links[linkID]
+	if !ok {
+		return nil, "", fmt.Errorf("payment link %s not found", linkID)
+	}
+
+	payment := ProviderPayment{
+		ID:     "pay_fake_" + uuid.New().String()[:8],
+		Amount: amount,
+		Method: method,
+		Status: "captured",
+	}
+	link.Payments = append(link.Payments, payment)
+	link.AmountPaid += amount
+	if link.AmountPaid+0.005 >= link.Amount {
+		link.Status = "paid"
+	} else {
+		link.Status = "partially_paid"
+	}
+
+	body, err := json.Marshal(GatewayEvent{
+		ID:      "evt_fake_" + uuid.New().String()[:8],
+		Type:    GatewayEventPaymentCaptured,
+		LinkID:  linkID,
+		Payment: &payment,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return body, signHMAC(body, g.webhookSecret), nil
+}
+
+// ParseWebhook checks X-Fake-Signature and decodes a GatewayEvent body
+func (g *FakeGateway) ParseWebhook(body []byte, header http.Header) (GatewayEvent, error) {
+	if !validHMACSignature(body, header.Get("X-Fake-Signature"), g.webhookSecret) {
+		return GatewayEvent{}, ErrInvalidWebhookSignature
+	}
+
+	var event GatewayEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return GatewayEvent{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+	return event, nil
+}
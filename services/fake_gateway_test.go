@@ -0,0 +1,73 @@
+// services/fake_gateway_test.go
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestFakeGatewayPayRoundTrip(t *testing.T) {
+	g := NewFakeGateway("whsec")
+	link, err := g.CreatePaymentLink(PaymentLinkRequest{Amount: 500})
+	if err != nil {
+		t.Fatalf("CreatePaymentLink() error = %v", err)
+	}
+
+	body, signature, err := g.Pay(link.ID, 200, "upi")
+	if err != nil {
+		t.Fatalf("Pay() error = %v", err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Fake-Signature", signature)
+	event, err := g.ParseWebhook(body, header)
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	if event.ID == "" || event.Type != GatewayEventPaymentCaptured || event.LinkID != link.ID {
+		t.Errorf("ParseWebhook() = %+v", event)
+	}
+	if event.Payment == nil || event.Payment.Amount != 200 || event.Payment.Method != "upi" {
+		t.Errorf("payment = %+v", event.Payment)
+	}
+
+	got, err := g.GetPaymentLink(link.ID)
+	if err != nil {
+		t.Fatalf("GetPaymentLink() error = %v", err)
+	}
+	if got.Status != "partially_paid" || got.AmountPaid != 200 || len(got.Payments) != 1 {
+		t.Errorf("GetPaymentLink() = %+v", got)
+	}
+
+	header.Set("X-Fake-Signature", signHMAC(body, "other"))
+	if _, err := g.ParseWebhook(body, header); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("wrong secret: error = %v, want ErrInvalidWebhookSignature", err)
+	}
+}
+
+func TestNewPaymentGatewayFake(t *testing.T) {
+	t.Setenv("PAYMENT_GATEWAY", "")
+	t.Setenv("FAKE_GATEWAY_WEBHOOK_SECRET", "")
+
+	t.Setenv("GIN_MODE", "release")
+	disabled := NewPaymentGateway()
+	if _, ok := disabled.(DisabledGateway); !ok {
+		t.Fatalf("NewPaymentGateway() in release mode = %T, want DisabledGateway", disabled)
+	}
+	if _, err := disabled.CreatePaymentLink(PaymentLinkRequest{Amount: 500}); !errors.Is(err, ErrPaymentGatewayDisabled) {
+		t.Errorf("CreatePaymentLink() error = %v, want ErrPaymentGatewayDisabled", err)
+	}
+	if _, err := disabled.ParseWebhook([]byte("{}"), http.Header{}); !errors.Is(err, ErrPaymentGatewayDisabled) {
+		t.Errorf("ParseWebhook() error = %v, want ErrPaymentGatewayDisabled", err)
+	}
+
+	t.Setenv("GIN_MODE", "debug")
+	g, ok := NewPaymentGateway().(*FakeGateway)
+	if !ok {
+		t.Fatal("NewPaymentGateway() did not return the fake gateway")
+	}
+	if len(g.webhookSecret) != 64 || g.webhookSecret == NewPaymentGateway().(*FakeGateway).webhookSecret {
+		t.Errorf("webhook secret %q is not random", g.webhookSecret)
+	}
+}
@@ -0,0 +1,229 @@
+// services/invoice_document.go
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"salonpro-backend/models"
+	"salonpro-backend/utils"
+)
+
+// InvoiceDocument bundles everything needed to render an invoice or receipt
+type InvoiceDocument struct {
+	Salon    models.Salon
+	Customer models.Customer
+	Invoice  models.Invoice
+}
+
+// Title is "Receipt" for fully paid invoices and "Invoice" otherwise
+func (d InvoiceDocument) Title() string {
+	if d.Invoice.PaymentStatus == "paid" {
+		return "Receipt"
+	}
+	return "Invoice"
+}
+
+func (d InvoiceDocument) TaxAmount() float64 {
+	return d.Invoice.TaxAmount()
+}
+
+func (d InvoiceDocument) BalanceDue() float64 {
+	balance := d.Invoice.Total - d.Invoice.PaidAmount
+	if balance < 0 {
+		return 0
+	}
+	return balance
+}
+
+// Money formats an amount with the salon's currency symbol, for HTML output
+func (d InvoiceDocument) Money(amount float64) string {
+	return d.Salon.MoneyFormat().Format(amount)
+}
+
+// PlainMoney formats an amount with the ISO currency code, since the
+// standard PDF fonts cannot render symbols such as ₹
+func (d InvoiceDocument) PlainMoney(amount float64) string {
+	return d.Salon.MoneyFormat().FormatCode(amount)
+}
+
+// Date formats the invoice date in the salon's locale
+func (d InvoiceDocument) Date() string {
+	return d.Salon.MoneyFormat().FormatDate(d.Invoice.InvoiceDate)
+}
+
+// UPI returns the UPI intent for the balance due, or nil when the salon has no
+// UPI ID, does not bill in INR or the invoice is settled
+func (d InvoiceDocument) UPI() *UPIPayment {
+	payment, err := BuildUPIPayment(d.Salon, d.Invoice)
+	if err != nil {
+		return nil
+	}
+	return &payment
+}
+
+// UPILink is the UPI intent as a link html/template will not sanitise away
+func (d InvoiceDocument) UPILink() template.URL {
+	if upi := d.UPI(); upi != nil {
+		return template.URL(upi.URI)
+	}
+	return ""
+}
+
+// UPIQRCode returns the PNG QR code for the UPI intent, or nil when there is none
+func (d InvoiceDocument) UPIQRCode() []byte {
+	upi := d.UPI()
+	if upi == nil {
+		return nil
+	}
+	qr, err := utils.EncodeQR(upi.URI)
+	if err != nil {
+		return nil
+	}
+	data, err := qr.PNG(6)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func (d InvoiceDocument) Filename() string {
+	return d.Invoice.InvoiceNumber + ".pdf"
+}
+
+var invoiceEmailTemplate = template.Must(template.New("invoice").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Helvetica, Arial, sans-serif; color: #222;">
+  <h2>{{.Salon.Name}}</h2>
+  {{if .Salon.Address}}<p style="color: #666;">{{.Salon.Address}}</p>{{end}}
+  <p>Hi {{.Customer.Name}},</p>
+  <p>Thank you for visiting us. Please find your {{.Title}} <strong>{{.Invoice.InvoiceNumber}}</strong>
+     dated {{.Date}} attached.</p>
+  <table cellpadding="6" cellspacing="0" style="border-collapse: collapse; width: 100%; max-width: 560px;">
+    <tr style="background: #f3f3f3;">
+      <th align="left">Item</th><th align="right">Qty</th><th align="right">Price</th><th align="right">Amount</th>
+    </tr>
+    {{range .Invoice.Items}}
+    <tr>
+      <td>{{.ServiceName}}</td>
+      <td align="right">{{.Quantity}}</td>
+      <td align="right">{{$.Money .UnitPrice}}</td>
+      <td align="right">{{$.Money .TotalPrice}}</td>
+    </tr>
+    {{end}}
+    <tr><td colspan="3" align="right">Subtotal</td><td align="right">{{.Money .Invoice.Subtotal}}</td></tr>
+    {{if .Invoice.Discount}}<tr><td colspan="3" align="right">Discount</td><td align="right">-{{.Money .Invoice.Discount}}</td></tr>{{end}}
+    {{if .TaxAmount}}<tr><td colspan="3" align="right">Tax</td><td align="right">{{.Money .TaxAmount}}</td></tr>{{end}}
+    <tr><td colspan="3" align="right"><strong>Total</strong></td><td align="right"><strong>{{.Money .Invoice.Total}}</strong></td></tr>
+    <tr><td colspan="3" align="right">Paid</td><td align="right">{{.Money .Invoice.PaidAmount}}</td></tr>
+    {{if .BalanceDue}}<tr><td colspan="3" align="right"><strong>Balance due</strong></td><td align="right"><strong>{{.Money .BalanceDue}}</strong></td></tr>{{end}}
+  </table>
+  {{with .UPI}}
+  <p>Scan to pay {{$.Money .Amount}} with any UPI app, or <a href="{{$.UPILink}}">tap here on your phone</a>.</p>
+  <p><img src="cid:upi-qr" alt="UPI QR code" width="200" height="200"><br>
+     <span style="color: #666;">{{.PayeeName}} &middot; {{.VPA}}</span></p>
+  {{end}}
+  <p>We look forward to seeing you again!</p>
+</body>
+</html>`))
+
+// RenderInvoiceHTML renders the email body for an invoice or receipt
+func RenderInvoiceHTML(doc InvoiceDocument) (string, error) {
+	var buf bytes.Buffer
+	if err := invoiceEmailTemplate.Execute(&buf, doc); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// RenderInvoicePDF renders the printable invoice document
+func RenderInvoicePDF(doc InvoiceDocument) []byte {
+	pdf := utils.NewPDFDocument()
+	left, right := 50.0, utils.PDFPageWidth-50
+	y := utils.PDFPageHeight - 60
+
+	pdf.Text(left, y, 18, true, doc.Salon.Name)
+	pdf.TextRight(right, y, 18, true, doc.Title())
+	y -= 18
+	if doc.Salon.Address != "" {
+		pdf.Text(left, y, 10, false, doc.Salon.Address)
+	}
+	pdf.TextRight(right, y, 10, false, doc.Invoice.InvoiceNumber)
+	y -= 14
+	pdf.TextRight(right, y, 10, false, doc.Date())
+
+	y -= 30
+	pdf.Text(left, y, 10, true, "Billed to")
+	y -= 14
+	pdf.Text(left, y, 10, false, doc.Customer.Name)
+	y -= 14
+	pdf.Text(left, y, 10, false, doc.Customer.Phone)
+
+	// Items table
+	y -= 30
+	pdf.Text(left, y, 10, true, "Item")
+	pdf.TextRight(right-180, y, 10, true, "Qty")
+	pdf.TextRight(right-90, y, 10, true, "Price")
+	pdf.TextRight(right, y, 10, true, "Amount")
+	y -= 6
+	pdf.Line(left, y, right, y)
+
+	for _, item := range doc.Invoice.Items {
+		y -= 16
+		if y < 120 {
+			pdf.AddPage()
+			y = utils.PDFPageHeight - 60
+		}
+		pdf.Text(left, y, 10, false, item.ServiceName)
+		pdf.TextRight(right-180, y, 10, false, fmt.Sprintf("%d", item.Quantity))
+		pdf.TextRight(right-90, y, 10, false, doc.PlainMoney(item.UnitPrice))
+		pdf.TextRight(right, y, 10, false, doc.PlainMoney(item.TotalPrice))
+	}
+
+	y -= 8
+	pdf.Line(left, y, right, y)
+
+	totalLine := func(label, value string, bold bool) {
+		y -= 16
+		pdf.TextRight(right-90, y, 10, bold, label)
+		pdf.TextRight(right, y, 10, bold, value)
+	}
+	totalLine("Subtotal", doc.PlainMoney(doc.Invoice.Subtotal), false)
+	if doc.Invoice.Discount > 0 {
+		totalLine("Discount", "-"+doc.PlainMoney(doc.Invoice.Discount), false)
+	}
+	if doc.TaxAmount() > 0 {
+		totalLine("Tax", doc.PlainMoney(doc.TaxAmount()), false)
+	}
+	totalLine("Total", doc.PlainMoney(doc.Invoice.Total), true)
+	totalLine("Paid", doc.PlainMoney(doc.Invoice.PaidAmount), false)
+	if doc.BalanceDue() > 0 {
+		totalLine("Balance due", doc.PlainMoney(doc.BalanceDue()), true)
+	}
+
+	if upi := doc.UPI(); upi != nil {
+		if qr, err := utils.EncodeQR(upi.URI); err == nil {
+			const module = 3.0
+			side := float64(qr.Size) * module
+			if y-40-side < 50 {
+				pdf.AddPage()
+				y = utils.PDFPageHeight - 60
+			}
+
+			y -= 30
+			pdf.Text(left, y, 10, true, "Scan to pay "+doc.PlainMoney(upi.Amount)+" with any UPI app")
+			y -= 14
+			pdf.Text(left, y, 9, false, upi.PayeeName+" - "+upi.VPA)
+			y -= 10 + side
+			for row := 0; row < qr.Size; row++ {
+				for col := 0; col < qr.Size; col++ {
+					if qr.Dark(col, row) {
+						pdf.FillRect(left+float64(col)*module, y+side-float64(row+1)*module, module, module)
+					}
+				}
+			}
+		}
+	}
+
+	return pdf.Bytes()
+}
@@ -0,0 +1,168 @@
+// services/loyalty.go
+package services
+
+import (
+	"errors"
+	"log"
+	"salonpro-backend/models"
+	"time"
+
+	"github.com/google/uuid"
+	cron "github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientPoints is returned when a debit exceeds the customer's points balance
+var ErrInsufficientPoints = errors.New("insufficient loyalty points")
+
+// LoyaltyExpiry returns when points credited now should expire under the program, if ever
+func LoyaltyExpiry(program models.LoyaltyProgram, now time.Time) *time.Time {
+	if program.ExpiryMonths <= 0 {
+		return nil
+	}
+	expiresAt := now.AddDate(0, program.ExpiryMonths, 0)
+	return &expiresAt
+}
+
+// CreditLoyaltyPoints adds entry.Points (> 0) to the customer's balance and records
+// the ledger entry, which can later be redeemed or expire
+func CreditLoyaltyPoints(tx *gorm.DB, entry models.LoyaltyTransaction) (models.LoyaltyTransaction, error) {
+	// RETURNING gives the balance this update produced, whatever else runs meanwhile
+	var customer models.Customer
+	result := tx.Model(&customer).Clauses(clause.Returning{Columns: []clause.Column{{Name: "loyalty_points"}}}).
+		Where("id = ?", entry.CustomerID).
+		Update("loyalty_points", gorm.Expr("loyalty_points + ?", entry.Points))
+	if result.Error != nil {
+		return entry, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entry, gorm.ErrRecordNotFound
+	}
+
+	entry.ID = uuid.New()
+	entry.BalanceAfter = customer.LoyaltyPoints
+	entry.Remaining = entry.Points
+	err := tx.Create(&entry).Error
+	return entry, err
+}
+
+// DebitLoyaltyPoints takes entry.Points (> 0) off the customer's balance, using up
+// the oldest-expiring credits first, and records a negative ledger entry
+func DebitLoyaltyPoints(tx *gorm.DB, entry models.LoyaltyTransaction) (models.LoyaltyTransaction, error) {
+	points := entry.Points
+
+	// Guarded decrement so concurrent redemptions cannot overdraw the balance
+	var customer models.Customer
+	result := tx.Model(&customer).Clauses(clause.Returning{Columns: []clause.Column{{Name: "loyalty_points"}}}).
+		Where("id = ? AND loyalty_points >= ?", entry.CustomerID, points).
+		Update("loyalty_points", gorm.Expr("loyalty_points - ?", points))
+	if result.Error != nil {
+		return entry, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entry, ErrInsufficientPoints
+	}
+
+	if err := consumeLoyaltyCredits(tx, entry.CustomerID, points); err != nil {
+		return entry, err
+	}
+
+	entry.ID = uuid.New()
+	entry.Points = -points
+	entry.BalanceAfter = customer.LoyaltyPoints
+	entry.Remaining = 0
+	err := tx.Create(&entry).Error
+	return entry, err
+}
+
+// consumeLoyaltyCredits reduces the remaining points on credit entries, soonest to expire first
+func consumeLoyaltyCredits(tx *gorm.DB, customerID uuid.UUID, points int) error {
+	var credits []models.LoyaltyTransaction
+	if err := tx.Where("customer_id = ? AND remaining > 0", customerID).
+		Order("expires_at ASC NULLS LAST, created_at ASC").
+		Find(&credits).Error; err != nil {
+		return err
+	}
+
+	for _, credit := range credits {
+		if points == 0 {
+			break
+		}
+		used := credit.Remaining
+		if used > points {
+			used = points
+		}
+		if err := tx.Model(&credit).Update("remaining", credit.Remaining-used).Error; err != nil {
+			return err
+		}
+		points -= used
+	}
+	return nil
+}
+
+// ExpireLoyaltyPoints writes off the unused part of every credit that has passed its expiry
+func ExpireLoyaltyPoints(db *gorm.DB, now time.Time) error {
+	var credits []models.LoyaltyTransaction
+	if err := db.Where("remaining > 0 AND expires_at <= ?", now).
+		Order("customer_id, expires_at").
+		Find(&credits).Error; err != nil {
+		return err
+	}
+
+	for _, credit := range credits {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Zero the credit first so the debit below does not consume other credits
+			if err := tx.Model(&credit).Update("remaining", 0).Error; err != nil {
+				return err
+			}
+
+			// Locked so the balance cannot change between this read and the debit
+			var customer models.Customer
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("loyalty_points").
+				First(&customer, "id = ?", credit.CustomerID).Error; err != nil {
+				return err
+			}
+			expired := credit.Remaining
+			if expired > customer.LoyaltyPoints {
+				expired = customer.LoyaltyPoints
+			}
+			if expired <= 0 {
+				return nil
+			}
+
+			if err := tx.Model(&models.Customer{}).Where("id = ?", credit.CustomerID).
+				Update("loyalty_points", gorm.Expr("loyalty_points - ?", expired)).Error; err != nil {
+				return err
+			}
+
+			return tx.Create(&models.LoyaltyTransaction{
+				ID:           uuid.New(),
+				SalonID:      credit.SalonID,
+				CustomerID:   credit.CustomerID,
+				Type:         "expire",
+				Points:       -expired,
+				BalanceAfter: customer.LoyaltyPoints - expired,
+				Note:         "Points earned on " + credit.CreatedAt.Format("2006-01-02") + " expired",
+			}).Error
+		})
+		if err != nil {
+			log.Printf("Failed to expire loyalty credit %s: %v", credit.ID, err)
+		}
+	}
+	return nil
+}
+
+// StartLoyaltyExpiryScheduler expires points shortly after midnight every day
+func StartLoyaltyExpiryScheduler(db *gorm.DB) {
+	c := cron.New()
+
+	c.AddFunc("15 0 * * *", func() {
+		if err := ExpireLoyaltyPoints(db, time.Now()); err != nil {
+			log.Printf("Failed to expire loyalty points: %v", err)
+		}
+	})
+
+	c.Start()
+	log.Println("Loyalty expiry scheduler started")
+}
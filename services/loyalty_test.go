@@ -0,0 +1,36 @@
+// services/loyalty_test.go
+package services
+
+import (
+	"salonpro-backend/models"
+	"testing"
+	"time"
+)
+
+func TestLoyaltyExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 31, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		months int
+		want   *time.Time
+	}{
+		{0, nil},
+		{-1, nil},
+		{12, ptrTime(time.Date(2027, 1, 31, 10, 0, 0, 0, time.UTC))},
+		// AddDate normalises 31 February to 3 March
+		{1, ptrTime(time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC))},
+	}
+	for _, tt := range tests {
+		got := LoyaltyExpiry(models.LoyaltyProgram{ExpiryMonths: tt.months}, now)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("LoyaltyExpiry(%d months) = %v, want never", tt.months, *got)
+		case tt.want != nil && (got == nil || !got.Equal(*tt.want)):
+			t.Errorf("LoyaltyExpiry(%d months) = %v, want %v", tt.months, got, *tt.want)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
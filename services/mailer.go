@@ -0,0 +1,203 @@
+// services/mailer.go
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mailer delivers a fully composed email message
+type Mailer interface {
+	Send(msg MailMessage) error
+}
+
+type MailMessage struct {
+	From        string
+	To          string
+	Subject     string
+	HTMLBody    string
+	Attachments []MailAttachment
+}
+
+type MailAttachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	ContentID   string // set to show the attachment inline, referenced as cid:<ContentID>
+}
+
+// NewMailer picks the mail transport from MAIL_DRIVER ("smtp", "file" or "memory").
+// Defaults to "file" so local development never sends real email.
+func NewMailer() Mailer {
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		return NewSMTPMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+		)
+	case "memory":
+		return &MemoryMailer{}
+	default:
+		dir := os.Getenv("MAIL_FILE_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return &FileMailer{Dir: dir}
+	}
+}
+
+// DefaultFromAddress returns the sender address configured for outgoing mail
+func DefaultFromAddress() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	return "no-reply@salonpro.local"
+}
+
+// SMTPMailer sends mail through an SMTP server using PLAIN auth
+type SMTPMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+}
+
+func NewSMTPMailer(host, port, username, password string) *SMTPMailer {
+	if port == "" {
+		port = "587"
+	}
+	return &SMTPMailer{host: host, port: port, username: username, password: password}
+}
+
+func (m *SMTPMailer) Send(msg MailMessage) error {
+	if m.host == "" {
+		return fmt.Errorf("SMTP_HOST not set")
+	}
+
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	return smtp.SendMail(m.host+":"+m.port, auth, msg.From, []string{msg.To}, raw)
+}
+
+// FileMailer writes each message as an .eml file, for local development
+type FileMailer struct {
+	Dir string
+}
+
+func (m *FileMailer) Send(msg MailMessage) error {
+	raw, err := buildMIMEMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405"), uuid.New().String()[:8])
+	return os.WriteFile(filepath.Join(m.Dir, name), raw, 0o644)
+}
+
+// MemoryMailer keeps sent messages in memory, for tests
+type MemoryMailer struct {
+	mu       sync.Mutex
+	Messages []MailMessage
+}
+
+func (m *MemoryMailer) Send(msg MailMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Messages = append(m.Messages, msg)
+	return nil
+}
+
+// Sent returns a copy of all messages delivered so far
+func (m *MemoryMailer) Sent() []MailMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MailMessage(nil), m.Messages...)
+}
+
+// buildMIMEMessage renders a multipart/mixed message with an HTML body and attachments
+func buildMIMEMessage(msg MailMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	body, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/html; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := body.Write([]byte(wrapBase64([]byte(msg.HTMLBody)))); err != nil {
+		return nil, err
+	}
+
+	for _, att := range msg.Attachments {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {att.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", att.Filename)},
+		}
+		if att.ContentID != "" {
+			header.Set("Content-ID", "<"+att.ContentID+">")
+			header.Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", att.Filename))
+		}
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write([]byte(wrapBase64(att.Data))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// wrapBase64 encodes data as base64 split into 76 character lines (RFC 2045)
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+	return b.String()
+}
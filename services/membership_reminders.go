@@ -0,0 +1,55 @@
+// services/membership_reminders.go
+package services
+
+import (
+	"log"
+	"salonpro-backend/models"
+	"time"
+)
+
+// membershipExpiryMessage is used until the salon writes its own membership_expiry template
+const membershipExpiryMessage = "Hi [CustomerName], your [PlanName] membership at [SalonName] expires on [ExpiryDate]. Renew on your next visit to keep your member benefits!"
+
+// ProcessMembershipReminders reminds members whose plan expires within 7 days
+func (s *ReminderService) ProcessMembershipReminders(salon models.Salon) {
+	now := time.Now()
+
+	var memberships []models.CustomerMembership
+	if err := s.db.Preload("Plan").
+		Where("salon_id = ? AND status = 'active' AND end_date BETWEEN ? AND ?", salon.ID, now, now.AddDate(0, 0, 7)).
+		Find(&memberships).Error; err != nil {
+		log.Printf("Salon %s: Failed to get expiring memberships: %v", salon.ID, err)
+		return
+	}
+
+	template, ok := s.getTemplate(salon.ID, "membership_expiry")
+	if !ok {
+		return
+	}
+
+	for _, membership := range memberships {
+		// Skip members who have already renewed
+		var renewals int64
+		s.db.Model(&models.CustomerMembership{}).
+			Where("renewed_from_id = ? AND status = 'active'", membership.ID).
+			Count(&renewals)
+		if renewals > 0 {
+			continue
+		}
+
+		if s.alreadySent(membership.CustomerID, "membership_expiry", &membership.ID, membership.StartDate) {
+			continue
+		}
+
+		var customer models.Customer
+		if err := s.db.Where("id = ? AND is_active = true", membership.CustomerID).First(&customer).Error; err != nil {
+			continue
+		}
+
+		vars := ReminderMessageVariables(salon, customer)
+		vars["[PlanName]"] = membership.Plan.Name
+		vars["[ExpiryDate]"] = salon.MoneyFormat().FormatDate(membership.EndDate)
+
+		s.deliver(salon, customer, "membership_expiry", template.ID, &membership.ID, RenderReminderMessage(template.Message, vars))
+	}
+}
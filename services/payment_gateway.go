@@ -0,0 +1,160 @@
+// services/payment_gateway.go
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"salonpro-backend/utils"
+	"time"
+)
+
+var (
+	// ErrInvalidWebhookSignature is returned when a webhook body does not match its signature
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	// ErrPaymentGatewayDisabled is returned by every call when no provider is configured
+	ErrPaymentGatewayDisabled = errors.New("online payments are not configured")
+)
+
+// Normalised webhook event types, whatever the provider calls them
+const (
+	GatewayEventPaymentCaptured = "payment.captured"
+	GatewayEventLinkExpired     = "link.expired"
+	GatewayEventLinkCancelled   = "link.cancelled"
+)
+
+// PaymentGateway collects online payments through a hosted payment page
+type PaymentGateway interface {
+	// Name identifies the provider, and is stored on links and payments it creates
+	Name() string
+	CreatePaymentLink(req PaymentLinkRequest) (GatewayLink, error)
+	GetPaymentLink(linkID string) (GatewayLink, error)
+	// Refund returns amount, in the payment's currency, to the customer
+	Refund(paymentID string, amount float64, currency string) (GatewayRefund, error)
+	// ParseWebhook verifies the request signature and decodes the event
+	ParseWebhook(body []byte, header http.Header) (GatewayEvent, error)
+}
+
+// PaymentLinkRequest asks the provider for a payment page for a fixed amount
+type PaymentLinkRequest struct {
+	Amount        float64
+	Currency      string
+	Description   string
+	ReferenceID   string // our invoice number, echoed back by the provider
+	CustomerName  string
+	CustomerPhone string
+	CustomerEmail string
+	ExpiresAt     *time.Time
+	CallbackURL   string
+}
+
+// GatewayLink is the provider's view of a payment link
+type GatewayLink struct {
+	ID         string
+	URL        string
+	Status     string // 'created', 'partially_paid', 'paid', 'expired' or 'cancelled'
+	Amount     float64
+	AmountPaid float64
+	ExpiresAt  *time.Time
+	Payments   []ProviderPayment
+}
+
+// ProviderPayment is a single payment taken by the provider
+type ProviderPayment struct {
+	ID     string
+	Amount float64
+	Method string // provider's payment method, e.g. 'upi' or 'card'
+	Status string // only 'captured' payments are posted to invoices
+}
+
+// GatewayRefund is a refund issued through the provider
+type GatewayRefund struct {
+	ID        string
+	PaymentID string
+	Amount    float64
+	Status    string
+}
+
+// GatewayEvent is a verified webhook delivery
+type GatewayEvent struct {
+	ID      string // provider's event ID, used to ignore redeliveries
+	Type    string // one of the GatewayEvent* constants, or the raw provider type
+	LinkID  string
+	Payment *ProviderPayment
+}
+
+// NewPaymentGateway picks the provider from PAYMENT_GATEWAY ("razorpay" or "fake").
+// Defaults to "fake" so local development never talks to a real provider, but
+// the fake gateway is refused when GIN_MODE=release since anyone could sign
+// webhooks for it and mark invoices paid. Online payments are disabled instead,
+// so the rest of the app still starts.
+func NewPaymentGateway() PaymentGateway {
+	switch os.Getenv("PAYMENT_GATEWAY") {
+	case "razorpay":
+		return NewRazorpayGateway(
+			os.Getenv("RAZORPAY_KEY_ID"),
+			os.Getenv("RAZORPAY_KEY_SECRET"),
+			os.Getenv("RAZORPAY_WEBHOOK_SECRET"),
+		)
+	default:
+		if os.Getenv("GIN_MODE") == "release" {
+			log.Println("Warning: online payments are disabled, set PAYMENT_GATEWAY=razorpay to enable them")
+			return DisabledGateway{}
+		}
+		secret := os.Getenv("FAKE_GATEWAY_WEBHOOK_SECRET")
+		if secret == "" {
+			// A per-process secret, so unsigned webhooks are still rejected
+			buf := make([]byte, 32)
+			if _, err := rand.Read(buf); err != nil {
+				panic("Failed to generate fake gateway webhook secret: " + err.Error())
+			}
+			secret = hex.EncodeToString(buf)
+		}
+		return NewFakeGateway(secret)
+	}
+}
+
+// DisabledGateway rejects every call, for servers with no provider configured
+type DisabledGateway struct{}
+
+func (DisabledGateway) Name() string {
+	return "disabled"
+}
+
+func (DisabledGateway) CreatePaymentLink(PaymentLinkRequest) (GatewayLink, error) {
+	return GatewayLink{}, ErrPaymentGatewayDisabled
+}
+
+func (DisabledGateway) GetPaymentLink(string) (GatewayLink, error) {
+	return GatewayLink{}, ErrPaymentGatewayDisabled
+}
+
+func (DisabledGateway) Refund(string, float64, string) (GatewayRefund, error) {
+	return GatewayRefund{}, ErrPaymentGatewayDisabled
+}
+
+func (DisabledGateway) ParseWebhook([]byte, http.Header) (GatewayEvent, error) {
+	return GatewayEvent{}, ErrPaymentGatewayDisabled
+}
+
+// minorUnits converts an amount to the currency's smallest unit, as providers
+// expect, e.g. paise for INR, yen for JPY and fils for KWD
+func minorUnits(amount float64, currency string) int64 {
+	if amount < 0 {
+		return -minorUnits(-amount, currency)
+	}
+	return int64(amount*minorUnitScale(currency) + 0.5)
+}
+
+// majorUnits converts the currency's smallest unit back to an amount
+func majorUnits(amount int64, currency string) float64 {
+	return float64(amount) / minorUnitScale(currency)
+}
+
+func minorUnitScale(currency string) float64 {
+	return math.Pow10(utils.CurrencyDecimalPlaces(currency))
+}
@@ -0,0 +1,264 @@
+// services/razorpay_gateway.go
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const razorpayBaseURL = "https://api.razorpay.com/v1"
+
+// RazorpayGateway takes payments through Razorpay payment links
+type RazorpayGateway struct {
+	keyID         string
+	keySecret     string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+}
+
+func NewRazorpayGateway(keyID, keySecret, webhookSecret string) *RazorpayGateway {
+	return &RazorpayGateway{
+		keyID:         keyID,
+		keySecret:     keySecret,
+		webhookSecret: webhookSecret,
+		baseURL:       razorpayBaseURL,
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (g *RazorpayGateway) Name() string {
+	return "razorpay"
+}
+
+type razorpayLink struct {
+	ID         string `json:"id"`
+	ShortURL   string `json:"short_url"`
+	Status     string `json:"status"`
+	Amount     int64  `json:"amount"`
+	AmountPaid int64  `json:"amount_paid"`
+	Currency   string `json:"currency"`
+	ExpireBy   int64  `json:"expire_by"`
+	Payments   []struct {
+		PaymentID string `json:"payment_id"`
+		Amount    int64  `json:"amount"`
+		Method    string `json:"method"`
+		Status    string `json:"status"`
+	} `json:"payments"`
+}
+
+type razorpayPayment struct {
+	ID       string `json:"id"`
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+	Method   string `json:"method"`
+	Status   string `json:"status"`
+}
+
+// toGatewayLink converts amounts with the link's currency; its payments are
+// always in the same currency
+func (l razorpayLink) toGatewayLink() GatewayLink {
+	link := GatewayLink{
+		ID:         l.ID,
+		URL:        l.ShortURL,
+		Status:     l.Status,
+		Amount:     majorUnits(l.Amount, l.Currency),
+		AmountPaid: majorUnits(l.AmountPaid, l.Currency),
+	}
+	if l.ExpireBy > 0 {
+		expiresAt := time.Unix(l.ExpireBy, 0)
+		link.ExpiresAt = &expiresAt
+	}
+	for _, p := range l.Payments {
+		link.Payments = append(link.Payments, ProviderPayment{
+			ID:     p.PaymentID,
+			Amount: majorUnits(p.Amount, l.Currency),
+			Method: p.Method,
+			Status: p.Status,
+		})
+	}
+	return link
+}
+
+func (g *RazorpayGateway) CreatePaymentLink(req PaymentLinkRequest) (GatewayLink, error) {
+	body := map[string]interface{}{
+		"amount":       minorUnits(req.Amount, req.Currency),
+		"currency":     req.Currency,
+		"description":  req.Description,
+		"reference_id": req.ReferenceID,
+		"customer": map[string]string{
+			"name":    req.CustomerName,
+			"contact": req.CustomerPhone,
+			"email":   req.CustomerEmail,
+		},
+		"notify":          map[string]bool{"sms": false, "email": false},
+		"reminder_enable": false,
+	}
+	if req.ExpiresAt != nil {
+		body["expire_by"] = req.ExpiresAt.Unix()
+	}
+	if req.CallbackURL != "" {
+		body["callback_url"] = req.CallbackURL
+		body["callback_method"] = "get"
+	}
+
+	var link razorpayLink
+	if err := g.do(http.MethodPost, "/payment_links", body, &link); err != nil {
+		return GatewayLink{}, err
+	}
+	return link.toGatewayLink(), nil
+}
+
+func (g *RazorpayGateway) GetPaymentLink(linkID string) (GatewayLink, error) {
+	var link razorpayLink
+	if err := g.do(http.MethodGet, "/payment_links/"+url.PathEscape(linkID), nil, &link); err != nil {
+		return GatewayLink{}, err
+	}
+	return link.toGatewayLink(), nil
+}
+
+func (g *RazorpayGateway) Refund(paymentID string, amount float64, currency string) (GatewayRefund, error) {
+	var refund struct {
+		ID        string `json:"id"`
+		PaymentID string `json:"payment_id"`
+		Amount    int64  `json:"amount"`
+		Status    string `json:"status"`
+	}
+	body := map[string]interface{}{"amount": minorUnits(amount, currency)}
+	if err := g.do(http.MethodPost, "/payments/"+url.PathEscape(paymentID)+"/refund", body, &refund); err != nil {
+		return GatewayRefund{}, err
+	}
+	return GatewayRefund{
+		ID:        refund.ID,
+		PaymentID: refund.PaymentID,
+		Amount:    majorUnits(refund.Amount, currency),
+		Status:    refund.Status,
+	}, nil
+}
+
+// ParseWebhook checks X-Razorpay-Signature, the hex HMAC-SHA256 of the raw body
+// keyed with the webhook secret, and maps payment link events
+func (g *RazorpayGateway) ParseWebhook(body []byte, header http.Header) (GatewayEvent, error) {
+	if g.webhookSecret == "" {
+		return GatewayEvent{}, fmt.Errorf("RAZORPAY_WEBHOOK_SECRET not set")
+	}
+	if !validHMACSignature(body, header.Get("X-Razorpay-Signature"), g.webhookSecret) {
+		return GatewayEvent{}, ErrInvalidWebhookSignature
+	}
+
+	var payload struct {
+		Event   string `json:"event"`
+		Payload struct {
+			PaymentLink struct {
+				Entity razorpayLink `json:"entity"`
+			} `json:"payment_link"`
+			Payment struct {
+				Entity razorpayPayment `json:"entity"`
+			} `json:"payment"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return GatewayEvent{}, fmt.Errorf("invalid webhook body: %w", err)
+	}
+
+	event := GatewayEvent{
+		ID:     header.Get("X-Razorpay-Event-Id"),
+		Type:   payload.Event,
+		LinkID: payload.Payload.PaymentLink.Entity.ID,
+	}
+	switch payload.Event {
+	case "payment_link.paid", "payment_link.partially_paid":
+		p := payload.Payload.Payment.Entity
+		currency := p.Currency
+		if currency == "" {
+			currency = payload.Payload.PaymentLink.Entity.Currency
+		}
+		event.Type = GatewayEventPaymentCaptured
+		event.Payment = &ProviderPayment{
+			ID:     p.ID,
+			Amount: majorUnits(p.Amount, currency),
+			Method: p.Method,
+			Status: p.Status,
+		}
+	case "payment_link.expired":
+		event.Type = GatewayEventLinkExpired
+	case "payment_link.cancelled":
+		event.Type = GatewayEventLinkCancelled
+	}
+	return event, nil
+}
+
+// do sends a JSON request with basic auth and decodes the JSON response into out
+func (g *RazorpayGateway) do(method, path string, body interface{}, out interface{}) error {
+	if g.keyID == "" || g.keySecret == "" {
+		return fmt.Errorf("RAZORPAY_KEY_ID and RAZORPAY_KEY_SECRET must be set")
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(g.keyID, g.keySecret)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("razorpay request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error struct {
+				Code        string `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Description != "" {
+			return fmt.Errorf("razorpay: %s", apiErr.Error.Description)
+		}
+		return fmt.Errorf("razorpay: unexpected status %d", resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// validHMACSignature compares a hex HMAC-SHA256 signature in constant time
+func validHMACSignature(body []byte, signature, secret string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil || len(expected) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// signHMAC returns the hex HMAC-SHA256 of body
+func signHMAC(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,164 @@
+// services/razorpay_gateway_test.go
+package services
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestValidHMACSignature(t *testing.T) {
+	// RFC 4231 test case 2
+	body := []byte("what do ya want for nothing?")
+	signature := "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		secret    string
+		want      bool
+	}{
+		{"valid", body, signature, "Jefe", true},
+		{"uppercase hex", body, "5BDCC146BF60754E6A042426089575C75A003F089D2739839DEC58B964EC3843", "Jefe", true},
+		{"wrong secret", body, signature, "jefe", false},
+		{"tampered body", []byte("what do ya want for nothing!"), signature, "Jefe", false},
+		{"truncated signature", body, signature[:32], "Jefe", false},
+		{"invalid hex", body, "not-a-signature", "Jefe", false},
+		{"empty signature", body, "", "Jefe", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validHMACSignature(tt.body, tt.signature, tt.secret); got != tt.want {
+				t.Errorf("validHMACSignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := signHMAC(body, "Jefe"); got != signature {
+		t.Errorf("signHMAC() = %s, want %s", got, signature)
+	}
+}
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{1500.50, "INR", 150050},
+		{0.29, "USD", 29},
+		{-12.34, "INR", -1234},
+		{1500, "JPY", 1500},
+		{12.345, "KWD", 12345},
+		{1.5, "BHD", 1500},
+		{10.25, "", 1025},
+	}
+	for _, tt := range tests {
+		got := minorUnits(tt.amount, tt.currency)
+		if got != tt.want {
+			t.Errorf("minorUnits(%v, %q) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+		if back := majorUnits(got, tt.currency); back != tt.amount {
+			t.Errorf("majorUnits(%d, %q) = %v, want %v", got, tt.currency, back, tt.amount)
+		}
+	}
+}
+
+const razorpayPaidWebhook = `{
+  "entity": "event",
+  "event": "payment_link.paid",
+  "payload": {
+    "payment_link": {
+      "entity": {"id": "plink_ExjpAUN3gVHrPJ", "status": "paid", "amount": 150050, "amount_paid": 150050}
+    },
+    "payment": {
+      "entity": {"id": "pay_ExjpU2yEuSrCzs", "amount": 150050, "currency": "INR", "method": "upi", "status": "captured"}
+    }
+  }
+}`
+
+func razorpayHeader(body []byte, secret, eventID string) http.Header {
+	header := http.Header{}
+	header.Set("X-Razorpay-Signature", signHMAC(body, secret))
+	header.Set("X-Razorpay-Event-Id", eventID)
+	return header
+}
+
+func TestRazorpayParseWebhookPaid(t *testing.T) {
+	g := NewRazorpayGateway("key", "secret", "whsec")
+	body := []byte(razorpayPaidWebhook)
+
+	event, err := g.ParseWebhook(body, razorpayHeader(body, "whsec", "evt_1"))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	if event.ID != "evt_1" || event.Type != GatewayEventPaymentCaptured || event.LinkID != "plink_ExjpAUN3gVHrPJ" {
+		t.Errorf("ParseWebhook() = %+v", event)
+	}
+	if event.Payment == nil {
+		t.Fatal("ParseWebhook() returned no payment")
+	}
+	want := ProviderPayment{ID: "pay_ExjpU2yEuSrCzs", Amount: 1500.50, Method: "upi", Status: "captured"}
+	if *event.Payment != want {
+		t.Errorf("payment = %+v, want %+v", *event.Payment, want)
+	}
+}
+
+func TestRazorpayParseWebhookZeroDecimalCurrency(t *testing.T) {
+	g := NewRazorpayGateway("key", "secret", "whsec")
+	body := []byte(`{"event":"payment_link.paid","payload":{
+		"payment_link":{"entity":{"id":"plink_1","currency":"JPY"}},
+		"payment":{"entity":{"id":"pay_1","amount":1500,"method":"card","status":"captured"}}}}`)
+
+	event, err := g.ParseWebhook(body, razorpayHeader(body, "whsec", "evt_1"))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+	if event.Payment == nil || event.Payment.Amount != 1500 {
+		t.Errorf("payment = %+v, want 1500 yen from the link's currency", event.Payment)
+	}
+}
+
+func TestRazorpayParseWebhookLinkEvents(t *testing.T) {
+	g := NewRazorpayGateway("key", "secret", "whsec")
+
+	tests := map[string]string{
+		"payment_link.partially_paid": GatewayEventPaymentCaptured,
+		"payment_link.expired":        GatewayEventLinkExpired,
+		"payment_link.cancelled":      GatewayEventLinkCancelled,
+		"payment.authorized":          "payment.authorized",
+	}
+	for raw, want := range tests {
+		body := []byte(`{"event":"` + raw + `","payload":{"payment_link":{"entity":{"id":"plink_1"}}}}`)
+		event, err := g.ParseWebhook(body, razorpayHeader(body, "whsec", ""))
+		if err != nil {
+			t.Fatalf("%s: ParseWebhook() error = %v", raw, err)
+		}
+		if event.Type != want || event.LinkID != "plink_1" {
+			t.Errorf("%s: ParseWebhook() = %+v, want type %s", raw, event, want)
+		}
+	}
+}
+
+func TestRazorpayParseWebhookRejects(t *testing.T) {
+	body := []byte(razorpayPaidWebhook)
+
+	g := NewRazorpayGateway("key", "secret", "whsec")
+	if _, err := g.ParseWebhook(body, razorpayHeader(body, "other", "evt_1")); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("wrong secret: error = %v, want ErrInvalidWebhookSignature", err)
+	}
+	if _, err := g.ParseWebhook(body, http.Header{}); !errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("missing signature: error = %v, want ErrInvalidWebhookSignature", err)
+	}
+
+	bad := []byte("{not json")
+	if _, err := g.ParseWebhook(bad, razorpayHeader(bad, "whsec", "")); err == nil || errors.Is(err, ErrInvalidWebhookSignature) {
+		t.Errorf("invalid body: error = %v, want decode error", err)
+	}
+
+	unset := NewRazorpayGateway("key", "secret", "")
+	if _, err := unset.ParseWebhook(body, razorpayHeader(body, "", "evt_1")); err == nil {
+		t.Error("missing webhook secret: expected an error")
+	}
+}
@@ -3,12 +3,16 @@ package services
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"salonpro-backend/models"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	cron "github.com/robfig/cron/v3"
 	"github.com/twilio/twilio-go"
+	twilioApi "github.com/twilio/twilio-go/rest/api/v2010"
 	"gorm.io/gorm"
 )
 
@@ -17,6 +21,12 @@ type ReminderService struct {
 	client *twilio.RestClient
 }
 
+// Fallback messages for reminder types added after a salon's templates were created
+var defaultReminderMessages = map[string]string{
+	"membership_expiry": membershipExpiryMessage,
+	"dues":              "Hi [CustomerName], a balance of [BalanceDue] is still due on invoice [InvoiceNumber] dated [InvoiceDate] at [SalonName]. Please settle it at your convenience. Thank you!",
+}
+
 func NewReminderService(db *gorm.DB) *ReminderService {
 	// Initialize Twilio client
 	accountSid := os.Getenv("TWILIO_ACCOUNT_SID")
@@ -31,55 +41,64 @@ func NewReminderService(db *gorm.DB) *ReminderService {
 	}
 }
 
-// func (s *ReminderService) StartScheduler() {
-// 	c := cron.New()
+func (s *ReminderService) StartScheduler() {
+	c := cron.New()
 
-// 	// Run every day at 9 AM
-// 	s.SendDailyReminders()
+	// Run every day at 9 AM
+	c.AddFunc("0 9 * * *", s.SendDailyReminders)
 
-// 	c.Start()
-// 	log.Println("Reminder scheduler started")
-// }
+	c.Start()
+	log.Println("Reminder scheduler started")
+}
 
-// func (s *ReminderService) SendDailyReminders() {
-// 	log.Println("Starting daily reminder processing...")
+func (s *ReminderService) SendDailyReminders() {
+	log.Println("Starting daily reminder processing...")
 
-// 	// Get all active salons
-// 	var salons []models.User
-// 	if err := s.db.Find(&salons, "is_active = ?", true).Error; err != nil {
-// 		log.Printf("Failed to fetch salons: %v", err)
-// 		return
-// 	}
+	// Only salons with a messaging channel switched on can be reminded
+	var salons []models.Salon
+	if err := s.db.Where("whats_app_notifications = ? OR sms_notifications = ?", true, true).
+		Find(&salons).Error; err != nil {
+		log.Printf("Failed to fetch salons: %v", err)
+		return
+	}
 
-// 	for _, salon := range salons {
-// 		s.ProcessSalonReminders(salon.ID)
-// 	}
+	for _, salon := range salons {
+		s.ProcessSalonReminders(salon)
+	}
 
-// 	log.Println("Daily reminder processing completed")
-// }
+	log.Println("Daily reminder processing completed")
+}
 
-// func (s *ReminderService) ProcessSalonReminders(salonID uuid.UUID) {
-// 	// Get upcoming birthdays (7 days from now)
-// 	birthdayCustomers, err := s.getUpcomingCustomers(salonID, "birthday")
-// 	if err != nil {
-// 		log.Printf("Salon %s: Failed to get birthday customers: %v", salonID, err)
-// 	} else {
-// 		s.sendReminders(salonID, birthdayCustomers, "birthday")
-// 	}
+func (s *ReminderService) ProcessSalonReminders(salon models.Salon) {
+	// Get upcoming birthdays (7 days from now)
+	if salon.BirthdayReminders {
+		birthdayCustomers, err := s.getUpcomingCustomers(salon.ID, "birthday")
+		if err != nil {
+			log.Printf("Salon %s: Failed to get birthday customers: %v", salon.ID, err)
+		} else {
+			s.sendReminders(salon, birthdayCustomers, "birthday")
+		}
+	}
 
-// 	// Get upcoming anniversaries (7 days from now)
-// 	anniversaryCustomers, err := s.getUpcomingCustomers(salonID, "anniversary")
-// 	if err != nil {
-// 		log.Printf("Salon %s: Failed to get anniversary customers: %v", salonID, err)
-// 	} else {
-// 		s.sendReminders(salonID, anniversaryCustomers, "anniversary")
-// 	}
-// }
+	// Get upcoming anniversaries (7 days from now)
+	if salon.AnniversaryReminders {
+		anniversaryCustomers, err := s.getUpcomingCustomers(salon.ID, "anniversary")
+		if err != nil {
+			log.Printf("Salon %s: Failed to get anniversary customers: %v", salon.ID, err)
+		} else {
+			s.sendReminders(salon, anniversaryCustomers, "anniversary")
+		}
+	}
 
-func (s *ReminderService) getUpcomingCustomers(salonID uuid.UUID, eventType string) ([]models.Customer, error) {
-	now := time.Now()
-	startDate := now.AddDate(0, 0, 7) // 7 days from now
+	// Membership expiry reminders live with the membership code
+	s.ProcessMembershipReminders(salon)
 
+	if salon.DuesReminders {
+		s.ProcessDuesReminders(salon)
+	}
+}
+
+func (s *ReminderService) getUpcomingCustomers(salonID uuid.UUID, eventType string) ([]models.Customer, error) {
 	var customers []models.Customer
 	var field string
 	switch eventType {
@@ -97,80 +116,279 @@ func (s *ReminderService) getUpcomingCustomers(salonID uuid.UUID, eventType stri
 		WHERE salon_id = ?
 		AND is_active = true
 		AND %s IS NOT NULL
-		AND EXTRACT(MONTH FROM %s) = ?
-		AND EXTRACT(DAY FROM %s) BETWEEN ? AND ?
+		AND (EXTRACT(MONTH FROM %s), EXTRACT(DAY FROM %s)) IN ?
 	`, field, field, field)
 
-	err := s.db.Raw(query, salonID, startDate.Month(), now.Day(), startDate.Day()).Scan(&customers).Error
+	err := s.db.Raw(query, salonID, upcomingMonthDays(time.Now(), 7)).Scan(&customers).Error
 	return customers, err
 }
 
-// func (s *ReminderService) sendReminders(salonID uuid.UUID, customers []models.Customer, eventType string) {
-// 	// Get active template for this event type
-// 	var template models.ReminderTemplate
-// 	if err := s.db.Where("salon_id = ? AND type = ? AND is_active = true", salonID, eventType).
-// 		First(&template).Error; err != nil {
-// 		log.Printf("Salon %s: No active template for %s: %v", salonID, eventType, err)
-// 		return
-// 	}
-
-// 	for _, customer := range customers {
-// 		// Replace placeholders in the template
-// 		message := strings.ReplaceAll(template.Message, "[CustomerName]", customer.Name)
-
-// 		// Determine channel (WhatsApp if available, else SMS)
-// 		channel := "sms"
-// 		var to string
-
-// 		// Use WhatsApp if phone is in E.164 format and starts with '+'
-// 		if strings.HasPrefix(customer.Phone, "+") {
-// 			to = "whatsapp:" + customer.Phone
-// 			channel = "whatsapp"
-// 		} else {
-// 			to = customer.Phone
-// 		}
-
-// 		// Send message via Twilio
-// 		params := &twilioApi.CreateMessageParams{}
-// 		params.SetTo(to)
-// 		params.SetBody(message)
-
-// 		// Use WhatsApp sender if available
-// 		if channel == "whatsapp" {
-// 			params.SetFrom("whatsapp:" + os.Getenv("TWILIO_WHATSAPP_NUMBER"))
-// 		} else {
-// 			params.SetFrom(os.Getenv("TWILIO_PHONE_NUMBER"))
-// 		}
-
-// 		resp, err := s.client.Api.CreateMessage(params)
-// 		status := "sent"
-// 		errorMsg := ""
-
-// 		if err != nil {
-// 			log.Printf("Failed to send message to %s: %v", customer.Phone, err)
-// 			status = "failed"
-// 			errorMsg = err.Error()
-// 		} else if resp.Sid != nil {
-// 			log.Printf("Message sent to %s, SID: %s", customer.Phone, *resp.Sid)
-// 		} else {
-// 			log.Printf("Message sent to %s, but no SID returned", customer.Phone)
-// 		}
-
-// 		// Log the reminder
-// 		reminderLog := models.ReminderLog{
-// 			SalonID:      salonID,
-// 			CustomerID:   customer.ID,
-// 			TemplateID:   template.ID,
-// 			Type:         eventType,
-// 			Message:      message,
-// 			Status:       status,
-// 			ErrorMessage: errorMsg,
-// 			Channel:      channel,
-// 			SentAt:       time.Now(),
-// 		}
-
-// 		if err := s.db.Create(&reminderLog).Error; err != nil {
-// 			log.Printf("Failed to log reminder for customer %s: %v", customer.ID, err)
-// 		}
-// 	}
-// }
+// upcomingMonthDays lists the month and day of today and each of the next days,
+// so the window carries on into the next month or year. Outside leap years,
+// 29 February dates fall on the 28th.
+func upcomingMonthDays(now time.Time, days int) [][]interface{} {
+	var monthDays [][]interface{}
+	for i := 0; i <= days; i++ {
+		date := now.AddDate(0, 0, i)
+		monthDays = append(monthDays, []interface{}{int(date.Month()), date.Day()})
+		if date.Month() == time.February && date.Day() == 28 && date.AddDate(0, 0, 1).Month() == time.March {
+			monthDays = append(monthDays, []interface{}{2, 29})
+		}
+	}
+	return monthDays
+}
+
+func (s *ReminderService) sendReminders(salon models.Salon, customers []models.Customer, eventType string) {
+	template, ok := s.getTemplate(salon.ID, eventType)
+	if !ok {
+		log.Printf("Salon %s: No active template for %s", salon.ID, eventType)
+		return
+	}
+
+	for _, customer := range customers {
+		// The query window spans several days, so only remind once per occasion
+		if s.alreadySent(customer.ID, eventType, nil, time.Now().AddDate(0, 0, -30)) {
+			continue
+		}
+
+		message := RenderReminderMessage(template.Message, ReminderMessageVariables(salon, customer))
+		s.deliver(salon, customer, eventType, template.ID, nil, message)
+	}
+}
+
+// ProcessDuesReminders reminds customers of unpaid invoice balances, once the
+// invoice is DuesReminderIntervalDays old and then every interval until paid
+func (s *ReminderService) ProcessDuesReminders(salon models.Salon) {
+	interval := salon.DuesReminderIntervalDays
+	if interval <= 0 {
+		interval = 7
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -interval)
+
+	var invoices []models.Invoice
+	if err := s.db.Raw(`
+		SELECT * FROM invoices
+		WHERE salon_id = ?
+		AND payment_status IN ('unpaid', 'partial')
+		AND total - paid_amount > 0.005
+		AND invoice_date <= ?
+		AND deleted_at IS NULL
+		ORDER BY invoice_date
+	`, salon.ID, since).Scan(&invoices).Error; err != nil {
+		log.Printf("Salon %s: Failed to get unpaid invoices: %v", salon.ID, err)
+		return
+	}
+
+	template, ok := s.getTemplate(salon.ID, "dues")
+	if !ok {
+		return
+	}
+
+	format := salon.MoneyFormat()
+	for _, invoice := range invoices {
+		if s.alreadySent(invoice.CustomerID, "dues", &invoice.ID, since) {
+			continue
+		}
+
+		var customer models.Customer
+		if err := s.db.Where("id = ? AND is_active = true", invoice.CustomerID).First(&customer).Error; err != nil {
+			continue
+		}
+
+		vars := ReminderMessageVariables(salon, customer)
+		vars["[InvoiceNumber]"] = invoice.InvoiceNumber
+		vars["[InvoiceDate]"] = format.FormatDate(invoice.InvoiceDate)
+		vars["[BalanceDue]"] = format.Format(invoice.Total - invoice.PaidAmount)
+
+		s.deliver(salon, customer, "dues", template.ID, &invoice.ID, RenderReminderMessage(template.Message, vars))
+	}
+}
+
+// segmentProgressEvery is how many recipients are messaged between progress saves
+const segmentProgressEvery = 50
+
+// SendSegmentMessage delivers a segment message to each customer, keeping the
+// message's status and counts up to date as it goes
+func (s *ReminderService) SendSegmentMessage(salon models.Salon, customers []models.Customer, job *models.SegmentMessage) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Segment message %s panicked: %v", job.ID, r)
+			s.finishSegmentMessage(job, "failed", fmt.Sprint(r))
+		}
+	}()
+
+	now := time.Now()
+	job.Status = "running"
+	job.StartedAt = &now
+	s.saveSegmentMessage(job)
+
+	for _, customer := range customers {
+		vars := ReminderMessageVariables(salon, customer)
+		switch s.deliver(salon, customer, "segment_message", uuid.Nil, &job.ID, RenderReminderMessage(job.Message, vars)) {
+		case "sent":
+			job.SentCount++
+		case "failed":
+			job.FailedCount++
+		default:
+			job.SkippedCount++
+		}
+
+		job.ProcessedCount++
+		if job.ProcessedCount%segmentProgressEvery == 0 {
+			s.saveSegmentMessage(job)
+		}
+	}
+
+	s.finishSegmentMessage(job, "completed", "")
+	log.Printf("Salon %s: Sent segment %s message to %d of %d customers", salon.ID, job.SegmentID, job.SentCount, len(customers))
+}
+
+func (s *ReminderService) saveSegmentMessage(job *models.SegmentMessage) {
+	if err := s.db.Model(&models.SegmentMessage{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":          job.Status,
+		"started_at":      job.StartedAt,
+		"processed_count": job.ProcessedCount,
+		"sent_count":      job.SentCount,
+		"failed_count":    job.FailedCount,
+		"skipped_count":   job.SkippedCount,
+	}).Error; err != nil {
+		log.Printf("Failed to save progress of segment message %s: %v", job.ID, err)
+	}
+}
+
+func (s *ReminderService) finishSegmentMessage(job *models.SegmentMessage, status, reason string) {
+	now := time.Now()
+	job.Status = status
+	job.FailureReason = reason
+	job.FinishedAt = &now
+	if err := s.db.Model(&models.SegmentMessage{}).Where("id = ?", job.ID).Updates(map[string]interface{}{
+		"status":          job.Status,
+		"failure_reason":  job.FailureReason,
+		"finished_at":     job.FinishedAt,
+		"processed_count": job.ProcessedCount,
+		"sent_count":      job.SentCount,
+		"failed_count":    job.FailedCount,
+		"skipped_count":   job.SkippedCount,
+	}).Error; err != nil {
+		log.Printf("Failed to finish segment message %s: %v", job.ID, err)
+	}
+}
+
+// getTemplate returns the salon's active template for a reminder type, falling
+// back to the built-in message for types the salon has no template for
+func (s *ReminderService) getTemplate(salonID uuid.UUID, reminderType string) (models.ReminderTemplate, bool) {
+	var template models.ReminderTemplate
+	err := s.db.Where("salon_id = ? AND type = ?", salonID, reminderType).First(&template).Error
+	if err == nil {
+		return template, template.IsActive
+	}
+
+	if message, ok := defaultReminderMessages[reminderType]; ok {
+		return models.ReminderTemplate{SalonID: salonID, Type: reminderType, Message: message, IsActive: true}, true
+	}
+	return template, false
+}
+
+// alreadySent reports whether a reminder of this type (about this record, if given)
+// was sent to the customer since the given time
+func (s *ReminderService) alreadySent(customerID uuid.UUID, reminderType string, referenceID *uuid.UUID, since time.Time) bool {
+	query := s.db.Model(&models.ReminderLog{}).
+		Where("customer_id = ? AND type = ? AND status = 'sent' AND sent_at >= ?", customerID, reminderType, since)
+	if referenceID != nil {
+		query = query.Where("reference_id = ?", *referenceID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		// Err on the side of not spamming the customer
+		return true
+	}
+	return count > 0
+}
+
+// deliver sends a message over the salon's preferred channel the customer has
+// not opted out of, and logs the attempt. It returns "sent", "failed", or
+// "skipped" when there was no channel to send on.
+func (s *ReminderService) deliver(salon models.Salon, customer models.Customer, reminderType string, templateID uuid.UUID, referenceID *uuid.UUID, message string) string {
+	// Prefer WhatsApp if enabled and the number is in E.164 format, else SMS
+	var channels []string
+	if salon.WhatsAppNotifications && strings.HasPrefix(customer.Phone, "+") {
+		channels = append(channels, "whatsapp")
+	}
+	if salon.SMSNotifications {
+		channels = append(channels, "sms")
+	}
+
+	channel := ""
+	purpose := ReminderPurpose(reminderType)
+	for _, candidate := range channels {
+		allowed, reason, err := CanMessage(s.db, salon, customer, candidate, purpose)
+		if err != nil {
+			// Without a consent answer, don't send
+			log.Printf("Failed to check %s consent for customer %s: %v", candidate, customer.ID, err)
+			return "skipped"
+		}
+		if allowed {
+			channel = candidate
+			break
+		}
+		log.Printf("Not sending %s to customer %s by %s: %s", reminderType, customer.ID, candidate, reason)
+	}
+	if channel == "" {
+		return "skipped"
+	}
+
+	to := customer.Phone
+	if channel == "whatsapp" {
+		to = "whatsapp:" + customer.Phone
+	}
+
+	// Send message via Twilio
+	params := &twilioApi.CreateMessageParams{}
+	params.SetTo(to)
+	params.SetBody(message)
+
+	// Use WhatsApp sender if available
+	if channel == "whatsapp" {
+		params.SetFrom("whatsapp:" + os.Getenv("TWILIO_WHATSAPP_NUMBER"))
+	} else {
+		params.SetFrom(os.Getenv("TWILIO_PHONE_NUMBER"))
+	}
+
+	resp, err := s.client.Api.CreateMessage(params)
+	status := "sent"
+	errorMsg := ""
+
+	if err != nil {
+		log.Printf("Failed to send message to %s: %v", customer.Phone, err)
+		status = "failed"
+		errorMsg = err.Error()
+	} else if resp.Sid != nil {
+		log.Printf("Message sent to %s, SID: %s", customer.Phone, *resp.Sid)
+	} else {
+		log.Printf("Message sent to %s, but no SID returned", customer.Phone)
+	}
+
+	// Log the reminder
+	reminderLog := models.ReminderLog{
+		ID:           uuid.New(),
+		SalonID:      salon.ID,
+		CustomerID:   customer.ID,
+		ReferenceID:  referenceID,
+		Type:         reminderType,
+		Message:      message,
+		Status:       status,
+		ErrorMessage: errorMsg,
+		Channel:      channel,
+		SentAt:       time.Now(),
+	}
+	if templateID != uuid.Nil {
+		reminderLog.TemplateID = &templateID
+	}
+
+	if err := s.db.Create(&reminderLog).Error; err != nil {
+		log.Printf("Failed to log reminder for customer %s: %v", customer.ID, err)
+	}
+	return status
+}
@@ -0,0 +1,45 @@
+// services/reminder_variables.go
+package services
+
+import (
+	"salonpro-backend/models"
+	"strconv"
+	"strings"
+)
+
+// ReminderMessageVariables builds the placeholder values available to
+// reminder templates, with amounts and dates formatted for the salon
+func ReminderMessageVariables(salon models.Salon, customer models.Customer) map[string]string {
+	format := salon.MoneyFormat()
+
+	vars := map[string]string{
+		"[CustomerName]": customer.Name,
+		"[SalonName]":    salon.Name,
+		"[TotalSpent]":   format.Format(customer.TotalSpent),
+		"[Currency]":     format.Symbol(),
+		"[LastVisit]":    "",
+		"[Birthday]":     "",
+		"[Anniversary]":  "",
+
+		"[LoyaltyPoints]": strconv.Itoa(customer.LoyaltyPoints),
+	}
+	if customer.LastVisit != nil {
+		vars["[LastVisit]"] = format.FormatDate(*customer.LastVisit)
+	}
+	if customer.Birthday != nil {
+		vars["[Birthday]"] = format.FormatDate(*customer.Birthday)
+	}
+	if customer.Anniversary != nil {
+		vars["[Anniversary]"] = format.FormatDate(*customer.Anniversary)
+	}
+
+	return vars
+}
+
+// RenderReminderMessage replaces every known placeholder in a template message
+func RenderReminderMessage(message string, vars map[string]string) string {
+	for placeholder, value := range vars {
+		message = strings.ReplaceAll(message, placeholder, value)
+	}
+	return message
+}
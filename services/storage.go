@@ -0,0 +1,106 @@
+// services/storage.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrStoredFileNotFound is returned when a key has nothing stored under it
+var ErrStoredFileNotFound = errors.New("stored file not found")
+
+// Storage keeps uploaded files under slash-separated keys
+type Storage interface {
+	// Name identifies the backend, e.g. "local" or "s3"
+	Name() string
+	Put(key string, data []byte, contentType string) error
+	// Get returns ErrStoredFileNotFound if nothing is stored under key
+	Get(key string) (io.ReadCloser, error)
+	// Delete does nothing if nothing is stored under key
+	Delete(key string) error
+}
+
+// NewStorage picks the backend from STORAGE_DRIVER ("local" or "s3").
+// Defaults to "local" so development needs no bucket.
+func NewStorage() Storage {
+	switch os.Getenv("STORAGE_DRIVER") {
+	case "s3":
+		return NewS3Storage(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_REGION"),
+			os.Getenv("S3_BUCKET"),
+			os.Getenv("S3_ACCESS_KEY_ID"),
+			os.Getenv("S3_SECRET_ACCESS_KEY"),
+		)
+	default:
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "tmp/uploads"
+		}
+		return &LocalStorage{Dir: dir}
+	}
+}
+
+// LocalStorage keeps files in a directory on the server's disk
+type LocalStorage struct {
+	Dir string
+}
+
+func (s *LocalStorage) Name() string {
+	return "local"
+}
+
+func (s *LocalStorage) Put(key string, data []byte, contentType string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	// Write then rename, so a failed upload never leaves half a file behind
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	file, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrStoredFileNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	file, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path maps a key to a file inside Dir, refusing keys that would escape it
+func (s *LocalStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean != "/"+key {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(strings.TrimPrefix(clean, "/"))), nil
+}
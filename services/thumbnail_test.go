@@ -0,0 +1,163 @@
+// services/thumbnail_test.go
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage is a w x h image, red on the left half and blue on the right
+func testImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if x < w/2 {
+				img.Set(x, y, color.RGBA{R: 255, A: 255})
+			} else {
+				img.Set(x, y, color.RGBA{B: 255, A: 255})
+			}
+		}
+	}
+	return img
+}
+
+// testJPEG encodes img, adding an EXIF block with the given orientation unless it is 0
+func testJPEG(t *testing.T, img image.Image, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode: %v", err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// TIFF header, one IFD with a single SHORT orientation entry
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	// The APP1 segment goes straight after the start-of-image marker
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+// pngHeader is the start of a PNG claiming the given size, enough for DecodeConfig
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr, w)
+	binary.BigEndian.PutUint32(ihdr[4:], h)
+	ihdr[8], ihdr[9] = 8, 6 // 8-bit RGBA
+
+	chunk := append([]byte("IHDR"), ihdr...)
+	out := []byte("\x89PNG\r\n\x1a\n")
+	out = binary.BigEndian.AppendUint32(out, uint32(len(ihdr)))
+	out = append(out, chunk...)
+	return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(chunk))
+}
+
+func TestExifOrientation(t *testing.T) {
+	img := testImage(8, 8)
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", testJPEG(t, img, nil, 0), 1},
+		{"little-endian rotated", testJPEG(t, img, binary.LittleEndian, 6), 6},
+		{"big-endian upside down", testJPEG(t, img, binary.BigEndian, 3), 3},
+		{"out of range", testJPEG(t, img, binary.BigEndian, 9), 1},
+		{"PNG", pngData.Bytes(), 1},
+		{"truncated", testJPEG(t, img, binary.LittleEndian, 6)[:12], 1},
+		{"empty", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.data); got != tt.want {
+				t.Errorf("exifOrientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeThumbnail(t *testing.T) {
+	var wide bytes.Buffer
+	if err := png.Encode(&wide, testImage(1000, 500)); err != nil {
+		t.Fatalf("png.Encode: %v", err)
+	}
+
+	tests := []struct {
+		name                     string
+		data                     []byte
+		wantW, wantH             int // original, upright
+		thumbW, thumbH           int
+		cornerRed                bool // the thumbnail's bottom-left corner is red
+		wantErr, wantErrTooLarge bool
+	}{
+		{name: "wide PNG", data: wide.Bytes(), wantW: 1000, wantH: 500, thumbW: 320, thumbH: 160, cornerRed: true},
+		{name: "small JPEG kept at size", data: testJPEG(t, testImage(200, 100), nil, 0), wantW: 200, wantH: 100, thumbW: 200, thumbH: 100, cornerRed: true},
+		{name: "rotated phone photo", data: testJPEG(t, testImage(640, 480), binary.LittleEndian, 6), wantW: 480, wantH: 640, thumbW: 240, thumbH: 320},
+		{name: "flipped", data: testJPEG(t, testImage(640, 480), binary.BigEndian, 2), wantW: 640, wantH: 480, thumbW: 320, thumbH: 240},
+		{name: "too many pixels", data: pngHeader(10000, 10000), wantErr: true, wantErrTooLarge: true},
+		{name: "not an image", data: []byte("%PDF-1.4"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, width, height, err := MakeThumbnail(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MakeThumbnail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErrTooLarge && !errors.Is(err, ErrImageTooLarge) {
+				t.Errorf("MakeThumbnail() error = %v, want ErrImageTooLarge", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if width != tt.wantW || height != tt.wantH {
+				t.Errorf("size = %dx%d, want %dx%d", width, height, tt.wantW, tt.wantH)
+			}
+
+			decoded, err := jpeg.Decode(bytes.NewReader(thumb))
+			if err != nil {
+				t.Fatalf("thumbnail is not a JPEG: %v", err)
+			}
+			bounds := decoded.Bounds()
+			if bounds.Dx() != tt.thumbW || bounds.Dy() != tt.thumbH {
+				t.Errorf("thumbnail = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.thumbW, tt.thumbH)
+			}
+			r, _, b, _ := decoded.At(2, bounds.Dy()-3).RGBA()
+			if (r > b) != tt.cornerRed {
+				t.Errorf("bottom-left red = %v, want %v", r > b, tt.cornerRed)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// services/upi.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"salonpro-backend/models"
+	"strings"
+)
+
+var (
+	ErrUPINotConfigured = errors.New("salon has no UPI ID configured")
+	ErrUPICurrency      = errors.New("UPI payments are only available in INR")
+	ErrUPINoBalance     = errors.New("invoice has no amount due")
+)
+
+// UPIPayment is a UPI intent for collecting an invoice balance
+type UPIPayment struct {
+	URI       string  `json:"uri"`
+	VPA       string  `json:"vpa"`
+	PayeeName string  `json:"payeeName"`
+	Amount    float64 `json:"amount"`
+	Currency  string  `json:"currency"`
+	Note      string  `json:"note"`
+}
+
+// BuildUPIPayment builds the upi://pay intent for the invoice's outstanding
+// balance, with the invoice number as the transaction note
+func BuildUPIPayment(salon models.Salon, invoice models.Invoice) (UPIPayment, error) {
+	if salon.UPIID == "" {
+		return UPIPayment{}, ErrUPINotConfigured
+	}
+	if salon.MoneyFormat().CurrencyCode != "INR" {
+		return UPIPayment{}, ErrUPICurrency
+	}
+	balance := invoice.Total - invoice.PaidAmount
+	if balance < 0.005 {
+		return UPIPayment{}, ErrUPINoBalance
+	}
+
+	payeeName := salon.UPIPayeeName
+	if payeeName == "" {
+		payeeName = salon.Name
+	}
+
+	payment := UPIPayment{
+		VPA:       salon.UPIID,
+		PayeeName: payeeName,
+		Amount:    balance,
+		Currency:  "INR",
+		Note:      invoice.InvoiceNumber,
+	}
+	payment.URI = "upi://pay?pa=" + upiEscape(payment.VPA) +
+		"&pn=" + upiEscape(payment.PayeeName) +
+		"&am=" + fmt.Sprintf("%.2f", payment.Amount) +
+		"&cu=INR" +
+		"&tn=" + upiEscape(payment.Note)
+	return payment, nil
+}
+
+// upiEscape percent-encodes a parameter. Some UPI apps show "+" literally and
+// do not decode "%40" in addresses, so spaces become %20 and "@" is kept.
+func upiEscape(s string) string {
+	return strings.NewReplacer("+", "%20", "%40", "@").Replace(url.QueryEscape(s))
+}
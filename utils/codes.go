@@ -0,0 +1,24 @@
+// utils/codes.go
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// Unambiguous characters for codes customers read out or type (no 0/O, 1/I/L)
+const codeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// GenerateCode returns a cryptographically random code, e.g. for gift cards
+func GenerateCode(n int) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic("failed to generate random code")
+		}
+		b[i] = codeAlphabet[idx.Int64()]
+	}
+	return string(b)
+}
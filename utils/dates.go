@@ -1,7 +1,12 @@
 // utils/dates.go
 package utils
 
-import "time"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func BeginningOfDay(t time.Time) time.Time {
 	year, month, day := t.Date()
@@ -12,4 +17,43 @@ func DaysBetween(start, end time.Time) int {
 	start = BeginningOfDay(start)
 	end = BeginningOfDay(end)
 	return int(end.Sub(start).Hours() / 24)
-}
\ No newline at end of file
+}
+
+// Layouts tried by ParseFlexibleDate. Single-digit layout fields also accept
+// zero-padded values, so "2/1/2006" matches "02/01/2006".
+var (
+	isoDateLayouts    = []string{"2006-01-02", "2006/1/2", "2006.1.2", time.RFC3339, "2006-01-02 15:04:05"}
+	dayFirstLayouts   = []string{"2/1/2006", "2-1-2006", "2.1.2006", "2/1/06", "2-1-06"}
+	monthFirstLayouts = []string{"1/2/2006", "1-2-2006", "1/2/06", "1-2-06"}
+	namedMonthLayouts = []string{"2 Jan 2006", "2 January 2006", "Jan 2 2006", "Jan 2, 2006", "January 2 2006", "January 2, 2006", "2-Jan-2006", "2-Jan-06"}
+	excelEpoch        = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// ParseFlexibleDate parses the date formats commonly found in spreadsheets,
+// including Excel serial numbers. dayFirst decides whether "03/04/2020" is
+// 3 April (as in India and the UK) or March 4.
+func ParseFlexibleDate(s string, dayFirst bool) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if serial, err := strconv.ParseFloat(s, 64); err == nil {
+		if serial < 1 || serial > 2958465 {
+			return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+		}
+		return excelEpoch.AddDate(0, 0, int(serial)), nil
+	}
+
+	layouts := append([]string{}, isoDateLayouts...)
+	if dayFirst {
+		layouts = append(layouts, dayFirstLayouts...)
+	} else {
+		layouts = append(layouts, monthFirstLayouts...)
+	}
+	layouts = append(layouts, namedMonthLayouts...)
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
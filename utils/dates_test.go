@@ -0,0 +1,42 @@
+// utils/dates_test.go
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFlexibleDate(t *testing.T) {
+	tests := []struct {
+		in       string
+		dayFirst bool
+		want     time.Time
+		wantErr  bool
+	}{
+		{in: "1990-04-03", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "1990/4/3", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "03/04/1990", dayFirst: true, want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "03/04/1990", want: time.Date(1990, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{in: "3-4-90", dayFirst: true, want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "3 Apr 1990", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "April 3, 1990", dayFirst: true, want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "03-Apr-90", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "32966", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)}, // Excel serial
+		{in: " 1990-04-03 ", want: time.Date(1990, 4, 3, 0, 0, 0, 0, time.UTC)},
+		{in: "13/13/1990", dayFirst: true, wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "next tuesday", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFlexibleDate(tt.in, tt.dayFirst)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFlexibleDate(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("ParseFlexibleDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
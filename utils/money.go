@@ -0,0 +1,109 @@
+// utils/money.go
+package utils
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+const (
+	DefaultCurrencyCode  = "INR"
+	DefaultDecimalPlaces = 2
+	DefaultLocale        = "en-IN"
+)
+
+// MoneyFormat formats amounts and dates according to a salon's currency and locale
+type MoneyFormat struct {
+	CurrencyCode  string
+	DecimalPlaces int
+	Locale        string
+}
+
+// ValidateCurrencyCode checks the code is a recognised ISO 4217 currency
+func ValidateCurrencyCode(code string) bool {
+	_, err := currency.ParseISO(code)
+	return err == nil
+}
+
+// ValidateLocale checks the locale is a well-formed BCP 47 tag such as "en-IN"
+func ValidateLocale(locale string) bool {
+	_, err := language.Parse(locale)
+	return err == nil
+}
+
+// CurrencyDecimalPlaces returns the minor units a currency is normally shown
+// with, e.g. 2 for INR, 0 for JPY and 3 for KWD
+func CurrencyDecimalPlaces(code string) int {
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return DefaultDecimalPlaces
+	}
+	scale, _ := currency.Standard.Rounding(unit)
+	return scale
+}
+
+func (f MoneyFormat) tag() language.Tag {
+	tag, err := language.Parse(f.Locale)
+	if err != nil {
+		return language.MustParse(DefaultLocale)
+	}
+	return tag
+}
+
+func (f MoneyFormat) code() string {
+	if f.CurrencyCode == "" {
+		return DefaultCurrencyCode
+	}
+	return strings.ToUpper(f.CurrencyCode)
+}
+
+// Round rounds an amount to the currency's decimal places
+func (f MoneyFormat) Round(amount float64) float64 {
+	scale := math.Pow(10, float64(f.DecimalPlaces))
+	return math.Round(amount*scale) / scale
+}
+
+// Number formats an amount with locale grouping and no currency marker, e.g. "1,23,456.00"
+func (f MoneyFormat) Number(amount float64) string {
+	p := message.NewPrinter(f.tag())
+	return p.Sprint(number.Decimal(f.Round(amount), number.Scale(f.DecimalPlaces)))
+}
+
+// Symbol returns the locale's symbol for the currency, e.g. "₹" or "AED"
+func (f MoneyFormat) Symbol() string {
+	unit, err := currency.ParseISO(f.code())
+	if err != nil {
+		return f.code()
+	}
+	return message.NewPrinter(f.tag()).Sprint(currency.Symbol(unit))
+}
+
+// Format formats an amount with the currency symbol, e.g. "₹ 1,23,456.00"
+func (f MoneyFormat) Format(amount float64) string {
+	return f.Symbol() + " " + f.Number(amount)
+}
+
+// FormatCode formats an amount with the ISO code, e.g. "INR 1,23,456.00".
+// Used where the symbol may not be renderable (PDF fonts, CSV exports).
+func (f MoneyFormat) FormatCode(amount float64) string {
+	return f.code() + " " + f.Number(amount)
+}
+
+// FormatDate formats a date in the locale's conventional day/month order
+func (f MoneyFormat) FormatDate(t time.Time) string {
+	region, _ := f.tag().Region()
+	switch region.String() {
+	case "US", "PH":
+		return t.Format("01/02/2006")
+	case "CN", "JP", "KR", "TW":
+		return t.Format("2006/01/02")
+	default:
+		return t.Format("02/01/2006")
+	}
+}
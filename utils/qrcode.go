@@ -0,0 +1,506 @@
+// utils/qrcode.go
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// ErrQRDataTooLong is returned when the text does not fit the largest supported version
+var ErrQRDataTooLong = errors.New("data too long for QR code")
+
+// QRCode is a QR code symbol encoded in byte mode at error correction level M,
+// which is what payment apps expect and tolerates some print damage
+type QRCode struct {
+	Version int
+	Size    int
+	modules [][]bool
+}
+
+// qrQuietZone is the blank border, in modules, scanners need around the symbol
+const qrQuietZone = 4
+
+// qrBlockSpec is the level M block structure for one version
+type qrBlockSpec struct {
+	ecPerBlock              int
+	group1Blocks, group1Len int
+	group2Blocks, group2Len int
+}
+
+// Level M block structure for versions 1-20, indexed by version
+var qrBlockSpecs = [...]qrBlockSpec{
+	{},
+	{10, 1, 16, 0, 0}, {16, 1, 28, 0, 0}, {26, 1, 44, 0, 0}, {18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0}, {16, 4, 27, 0, 0}, {18, 4, 31, 0, 0}, {22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37}, {26, 4, 43, 1, 44}, {30, 1, 50, 4, 51}, {22, 6, 36, 2, 37},
+	{22, 8, 37, 1, 38}, {24, 4, 40, 5, 41}, {24, 5, 41, 5, 42}, {28, 7, 45, 3, 46},
+	{28, 10, 46, 1, 47}, {26, 9, 43, 4, 44}, {26, 3, 44, 11, 45}, {26, 3, 41, 13, 42},
+}
+
+// Alignment pattern centre coordinates for versions 1-20
+var qrAlignmentPositions = [...][]int{
+	{}, {},
+	{6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50}, {6, 30, 54}, {6, 32, 58}, {6, 34, 62},
+	{6, 26, 46, 66}, {6, 26, 48, 70}, {6, 26, 50, 74}, {6, 30, 54, 78}, {6, 30, 56, 82},
+	{6, 30, 58, 86}, {6, 34, 62, 90},
+}
+
+func (s qrBlockSpec) dataCodewords() int {
+	return s.group1Blocks*s.group1Len + s.group2Blocks*s.group2Len
+}
+
+// EncodeQR encodes text as a QR code, picking the smallest version that fits
+func EncodeQR(text string) (*QRCode, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v < len(qrBlockSpecs); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrBlockSpecs[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRDataTooLong
+	}
+
+	q := &QRCode{Version: version, Size: version*4 + 17}
+	q.modules = make([][]bool, q.Size)
+	function := make([][]bool, q.Size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, q.Size)
+		function[i] = make([]bool, q.Size)
+	}
+
+	q.drawFunctionPatterns(function)
+	q.drawCodewords(function, q.addErrorCorrection(q.dataCodewords(data)))
+
+	// Try every mask and keep the one with the lowest penalty
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(function, mask)
+		q.drawFormatBits(function, mask)
+		if penalty := q.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			best, bestPenalty = mask, penalty
+		}
+		q.applyMask(function, mask) // XOR again to undo
+	}
+	q.applyMask(function, best)
+	q.drawFormatBits(function, best)
+
+	return q, nil
+}
+
+// Dark reports whether the module at column x, row y is dark
+func (q *QRCode) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+// PNG renders the code with scale pixels per module and a quiet zone
+func (q *QRCode) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+	width := (q.Size + 2*qrQuietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if !q.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+qrQuietZone)*scale+dx, (y+qrQuietZone)*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the code as a scalable image, one module per user unit
+func (q *QRCode) SVG() string {
+	width := q.Size + 2*qrQuietZone
+	var path strings.Builder
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.modules[y][x] {
+				fmt.Fprintf(&path, "M%d,%dh1v1h-1z", x+qrQuietZone, y+qrQuietZone)
+			}
+		}
+	}
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+
+		`<rect width="100%%" height="100%%" fill="#fff"/><path d="%s" fill="#000"/></svg>`, width, width, path.String())
+}
+
+// dataCodewords builds the byte mode bit stream, padded to the version's capacity
+func (q *QRCode) dataCodewords(data []byte) []byte {
+	capacity := qrBlockSpecs[q.Version].dataCodewords()
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+
+	countBits := 8
+	if q.Version >= 10 {
+		countBits = 16
+	}
+	appendBits(0x4, 4) // byte mode
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	// Terminator, then zero bits up to a byte boundary
+	for i := 0; i < 4 && len(bits) < capacity*8; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+	return codewords
+}
+
+// addErrorCorrection splits the data into blocks, appends Reed-Solomon codewords
+// and interleaves the result
+func (q *QRCode) addErrorCorrection(data []byte) []byte {
+	spec := qrBlockSpecs[q.Version]
+	divisor := reedSolomonDivisor(spec.ecPerBlock)
+
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for i := 0; i < spec.group1Blocks+spec.group2Blocks; i++ {
+		length := spec.group1Len
+		if i >= spec.group1Blocks {
+			length = spec.group2Len
+		}
+		block := data[offset : offset+length]
+		offset += length
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i < spec.group2Len || i < spec.group1Len; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < spec.ecPerBlock; i++ {
+		for _, ec := range ecBlocks {
+			result = append(result, ec[i])
+		}
+	}
+	return result
+}
+
+func (q *QRCode) setFunction(function [][]bool, x, y int, dark bool) {
+	q.modules[y][x] = dark
+	function[y][x] = true
+}
+
+// drawFunctionPatterns draws the finder, timing and alignment patterns and
+// reserves the format and version areas
+func (q *QRCode) drawFunctionPatterns(function [][]bool) {
+	size := q.Size
+	for i := 0; i < size; i++ {
+		q.setFunction(function, 6, i, i%2 == 0)
+		q.setFunction(function, i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with their separators
+	for _, centre := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := centre[0]+dx, centre[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				dist := maxInt(absInt(dx), absInt(dy))
+				q.setFunction(function, x, y, dist != 2 && dist != 4)
+			}
+		}
+	}
+
+	positions := qrAlignmentPositions[q.Version]
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			// Skip the three corners occupied by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.setFunction(function, cx+dx, cy+dy, maxInt(absInt(dx), absInt(dy)) != 1)
+				}
+			}
+		}
+	}
+
+	// Reserve format areas; the real bits are drawn once a mask is chosen
+	q.drawFormatBits(function, 0)
+
+	if q.Version >= 7 {
+		rem := q.Version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := q.Version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>i)&1 == 1
+			a, b := size-11+i%3, i/3
+			q.setFunction(function, a, b, dark)
+			q.setFunction(function, b, a, dark)
+		}
+	}
+}
+
+// drawFormatBits writes both copies of the level M format information for mask
+func (q *QRCode) drawFormatBits(function [][]bool, mask int) {
+	data := 0<<3 | mask // level M is 00
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	size := q.Size
+	for i := 0; i <= 5; i++ {
+		q.setFunction(function, 8, i, bit(i))
+	}
+	q.setFunction(function, 8, 7, bit(6))
+	q.setFunction(function, 8, 8, bit(7))
+	q.setFunction(function, 7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(function, 14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.setFunction(function, size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(function, 8, size-15+i, bit(i))
+	}
+	q.setFunction(function, 8, size-8, true) // always-dark module
+}
+
+// drawCodewords places the codewords in the zigzag order from the bottom right
+func (q *QRCode) drawCodewords(function [][]bool, codewords []byte) {
+	i := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < q.Size; vert++ {
+			y := vert
+			if upward {
+				y = q.Size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[y][x] = (codewords[i/8]>>(7-i%8))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask XORs the data modules with mask pattern 0-7
+func (q *QRCode) applyMask(function [][]bool, mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol with the four rules from the QR specification;
+// lower is easier to scan
+func (q *QRCode) penalty() int {
+	size := q.Size
+	score := 0
+	finderLike := []bool{true, false, true, true, true, false, true}
+
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= size; i++ {
+			if i < size && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				score += 3 + run - 5
+			}
+			run = 1
+		}
+
+		// 1:1:3:1:1 finder-like patterns with four light modules on either side
+		for i := 0; i+7 <= size; i++ {
+			match := true
+			for k, dark := range finderLike {
+				if get(i+k) != dark {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+			lightRun := func(from, to int) bool {
+				for k := from; k < to; k++ {
+					if k >= 0 && k < size && get(k) {
+						return false
+					}
+				}
+				return true
+			}
+			if lightRun(i-4, i) || lightRun(i+7, i+11) {
+				score += 40
+			}
+		}
+	}
+
+	for y := 0; y < size; y++ {
+		line(func(i int) bool { return q.modules[y][i] })
+	}
+	for x := 0; x < size; x++ {
+		line(func(i int) bool { return q.modules[i][x] })
+	}
+
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					score += 3
+				}
+			}
+		}
+	}
+
+	total := size * size
+	score += absInt(dark*20-total*10) / total * 10
+	return score
+}
+
+// reedSolomonDivisor returns the generator polynomial of the given degree,
+// highest coefficient first and the leading 1 omitted
+func reedSolomonDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+// reedSolomonRemainder returns the error correction codewords for data
+func reedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func absInt(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
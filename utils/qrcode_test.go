@@ -0,0 +1,391 @@
+// utils/qrcode_test.go
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// Level M format strings from the QR specification, indexed by mask, bit 14 first
+var testFormatStrings = [8]string{
+	"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+	"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+}
+
+// Level M error correction codewords per block and block count, versions 1-20
+var testECPerBlock = [...]int{0, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26}
+var testNumBlocks = [...]int{0, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16}
+
+func TestReedSolomonReferenceVector(t *testing.T) {
+	// "HELLO WORLD" at version 1-M, from the worked example at thonky.com
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	got := reedSolomonRemainder(data, reedSolomonDivisor(10))
+	if !bytes.Equal(got, want) {
+		t.Errorf("reedSolomonRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestEncodeQRDecodes(t *testing.T) {
+	tests := []struct {
+		text    string
+		version int
+	}{
+		{"HELLO", 1},
+		{"upi://pay?pa=glowsalon@okicici&pn=Glow%20Salon&am=1500.00&cu=INR&tn=INV-000123", 5},
+		{"Café Crème ₹", 2},
+		{strings.Repeat("0123456789", 20), 10},
+		{strings.Repeat("upi://pay?pa=x@y&", 20), 14},
+		{strings.Repeat("a", 666), 20},
+	}
+	for _, tt := range tests {
+		q, err := EncodeQR(tt.text)
+		if err != nil {
+			t.Fatalf("EncodeQR(%.20q) error = %v", tt.text, err)
+		}
+		if q.Version != tt.version || q.Size != 17+4*tt.version {
+			t.Errorf("EncodeQR(%.20q) version %d size %d, want version %d", tt.text, q.Version, q.Size, tt.version)
+		}
+		if got := decodeTestQR(t, q); got != tt.text {
+			t.Errorf("decoded %.40q, want %.40q", got, tt.text)
+		}
+	}
+}
+
+func TestEncodeQRTooLong(t *testing.T) {
+	if _, err := EncodeQR(strings.Repeat("a", 667)); !errors.Is(err, ErrQRDataTooLong) {
+		t.Errorf("EncodeQR() error = %v, want ErrQRDataTooLong", err)
+	}
+}
+
+func TestQRCodePNG(t *testing.T) {
+	q, err := EncodeQR("upi://pay?pa=glowsalon@okicici")
+	if err != nil {
+		t.Fatalf("EncodeQR() error = %v", err)
+	}
+	data, err := q.PNG(3)
+	if err != nil {
+		t.Fatalf("PNG() error = %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+
+	width := (q.Size + 2*qrQuietZone) * 3
+	if b := img.Bounds(); b.Dx() != width || b.Dy() != width {
+		t.Fatalf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), width, width)
+	}
+	for y := 0; y < width; y++ {
+		for x := 0; x < width; x++ {
+			r, _, _, _ := img.At(x, y).RGBA()
+			mx, my := x/3-qrQuietZone, y/3-qrQuietZone
+			want := mx >= 0 && my >= 0 && mx < q.Size && my < q.Size && q.Dark(mx, my)
+			if (r == 0) != want {
+				t.Fatalf("pixel %d,%d dark = %v, want %v", x, y, r == 0, want)
+			}
+		}
+	}
+}
+
+// decodeTestQR reads a symbol back the way a scanner would, working from the
+// specification rather than the encoder's tables: format bits, unmasking, the
+// zigzag codeword order, de-interleaving, Reed-Solomon syndromes and byte mode
+func decodeTestQR(t *testing.T, q *QRCode) string {
+	t.Helper()
+	size, version := q.Size, q.Version
+
+	// Finder patterns and timing patterns
+	for _, corner := range [][2]int{{0, 0}, {size - 7, 0}, {0, size - 7}} {
+		for dy := 0; dy < 7; dy++ {
+			for dx := 0; dx < 7; dx++ {
+				ring := testMax(testAbs(dx-3), testAbs(dy-3))
+				if q.Dark(corner[0]+dx, corner[1]+dy) != (ring != 2) {
+					t.Fatalf("finder pattern at %v is wrong", corner)
+				}
+			}
+		}
+	}
+	for i := 8; i < size-8; i++ {
+		if q.Dark(i, 6) != (i%2 == 0) || q.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("timing pattern is wrong at %d", i)
+		}
+	}
+	if !q.Dark(8, size-8) {
+		t.Fatal("dark module is light")
+	}
+
+	// Both copies of the format information must agree and name level M
+	var first, second [15]bool
+	for i := 0; i <= 5; i++ {
+		first[i] = q.Dark(8, i)
+	}
+	first[6], first[7], first[8] = q.Dark(8, 7), q.Dark(8, 8), q.Dark(7, 8)
+	for i := 9; i < 15; i++ {
+		first[i] = q.Dark(14-i, 8)
+	}
+	for i := 0; i < 8; i++ {
+		second[i] = q.Dark(size-1-i, 8)
+	}
+	for i := 8; i < 15; i++ {
+		second[i] = q.Dark(8, size-15+i)
+	}
+	if first != second {
+		t.Fatal("format information copies differ")
+	}
+	var format strings.Builder
+	for i := 14; i >= 0; i-- {
+		if first[i] {
+			format.WriteByte('1')
+		} else {
+			format.WriteByte('0')
+		}
+	}
+	mask := -1
+	for m, s := range testFormatStrings {
+		if s == format.String() {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format information %s is not level M", format.String())
+	}
+
+	// Version information, versions 7 and up
+	if version >= 7 {
+		got := 0
+		for i := 17; i >= 0; i-- {
+			a, b := size-11+i%3, i/3
+			if q.Dark(a, b) != q.Dark(b, a) {
+				t.Fatal("version information copies differ")
+			}
+			got <<= 1
+			if q.Dark(a, b) {
+				got |= 1
+			}
+		}
+		if got>>12 != version || testPolyRemainder(got, 0x1F25, 12) != 0 {
+			t.Fatalf("version information %018b is wrong for version %d", got, version)
+		}
+	}
+
+	// Function modules, from the layout rules in the specification
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+	}
+	reserve := func(x0, y0, w, h int) {
+		for y := y0; y < y0+h; y++ {
+			for x := x0; x < x0+w; x++ {
+				function[y][x] = true
+			}
+		}
+	}
+	reserve(0, 0, 9, 9)
+	reserve(size-8, 0, 8, 9)
+	reserve(0, size-8, 9, 8)
+	reserve(6, 0, 1, size)
+	reserve(0, 6, size, 1)
+	if version >= 7 {
+		reserve(size-11, 0, 3, 6)
+		reserve(0, size-11, 6, 3)
+	}
+	if version > 1 {
+		count := version/7 + 2
+		step := (version*4 + count*2 + 1) / (count*2 - 2) * 2
+		centres := []int{6}
+		for pos := size - 7; len(centres) < count; pos -= step {
+			centres = append([]int{6}, append([]int{pos}, centres[1:]...)...)
+		}
+		last := centres[count-1]
+		for _, cy := range centres {
+			for _, cx := range centres {
+				if (cx == 6 && cy == 6) || (cx == 6 && cy == last) || (cx == last && cy == 6) {
+					continue // overlaps a finder pattern
+				}
+				reserve(cx-2, cy-2, 5, 5)
+				for dy := -2; dy <= 2; dy++ {
+					for dx := -2; dx <= 2; dx++ {
+						if q.Dark(cx+dx, cy+dy) != (testMax(testAbs(dx), testAbs(dy)) != 1) {
+							t.Fatalf("alignment pattern at %d,%d is wrong", cx, cy)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Read the data modules in zigzag order, removing the mask
+	var bits []bool
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := ((size-1-right)/2)%2 == 0
+		if right < 6 {
+			upward = ((size-2-right)/2)%2 == 0
+		}
+		for vert := 0; vert < size; vert++ {
+			row := vert
+			if upward {
+				row = size - 1 - vert
+			}
+			for col := right; col >= right-1; col-- {
+				if function[row][col] {
+					continue
+				}
+				bits = append(bits, q.Dark(col, row) != testMaskInverts(mask, row, col))
+			}
+		}
+	}
+
+	total := len(bits) / 8
+	codewords := make([]byte, total)
+	for i := range codewords {
+		for j := 0; j < 8; j++ {
+			if bits[i*8+j] {
+				codewords[i] |= 1 << (7 - j)
+			}
+		}
+	}
+
+	// De-interleave: short blocks first, long blocks carry one extra data codeword
+	ec, numBlocks := testECPerBlock[version], testNumBlocks[version]
+	shortData := total/numBlocks - ec
+	numLong := total % numBlocks
+	blocks := make([][]byte, numBlocks)
+	k := 0
+	for i := 0; i <= shortData; i++ {
+		for b := range blocks {
+			if i < shortData || b >= numBlocks-numLong {
+				blocks[b] = append(blocks[b], codewords[k])
+				k++
+			}
+		}
+	}
+	for i := 0; i < ec; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[k])
+			k++
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		for i := 0; i < ec; i++ {
+			if s := testSyndrome(block, i); s != 0 {
+				t.Fatalf("block %d syndrome %d = %d, want 0", b, i, s)
+			}
+		}
+		data = append(data, block[:len(block)-ec]...)
+	}
+
+	// Byte mode segment
+	pos := 0
+	read := func(n int) int {
+		v := 0
+		for i := 0; i < n; i++ {
+			v <<= 1
+			if data[(pos+i)/8]>>(7-(pos+i)%8)&1 == 1 {
+				v |= 1
+			}
+		}
+		pos += n
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("mode = %04b, want byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	length := read(countBits)
+	if pos+8*length > len(data)*8 {
+		t.Fatalf("length %d does not fit %d data codewords", length, len(data))
+	}
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+	return string(text)
+}
+
+// testMaskInverts is mask condition m for a module at row i, column j
+func testMaskInverts(m, i, j int) bool {
+	switch m {
+	case 0:
+		return (i+j)%2 == 0
+	case 1:
+		return i%2 == 0
+	case 2:
+		return j%3 == 0
+	case 3:
+		return (i+j)%3 == 0
+	case 4:
+		return (i/2+j/3)%2 == 0
+	case 5:
+		return (i*j)%2+(i*j)%3 == 0
+	case 6:
+		return ((i*j)%2+(i*j)%3)%2 == 0
+	default:
+		return ((i+j)%2+(i*j)%3)%2 == 0
+	}
+}
+
+// testSyndrome evaluates the block polynomial at alpha^i in GF(256)
+func testSyndrome(block []byte, i int) int {
+	x := 1
+	for n := 0; n < i; n++ {
+		x = testGFMul(x, 2)
+	}
+	s := 0
+	for _, b := range block {
+		s = testGFMul(s, x) ^ int(b)
+	}
+	return s
+}
+
+// testGFMul multiplies by shift and add, modulo 0x11D
+func testGFMul(a, b int) int {
+	p := 0
+	for b > 0 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		a <<= 1
+		if a&0x100 != 0 {
+			a ^= 0x11D
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// testPolyRemainder divides a GF(2) polynomial by generator of the given degree
+func testPolyRemainder(value, generator, degree int) int {
+	for bit := 31; bit >= degree; bit-- {
+		if value>>bit&1 == 1 {
+			value ^= generator << (bit - degree)
+		}
+	}
+	return value
+}
+
+func testAbs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func testMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,206 @@
+// utils/spreadsheet.go
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedSpreadsheet is returned for files that are neither CSV nor XLSX
+var ErrUnsupportedSpreadsheet = errors.New("unsupported file type, expected .csv or .xlsx")
+
+// ReadSpreadsheet returns the rows of a CSV file or of the first worksheet of an
+// XLSX workbook, chosen by the file extension
+func ReadSpreadsheet(filename string, data []byte) ([][]string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv", ".txt":
+		return readCSV(data)
+	case ".xlsx":
+		return readXLSX(data)
+	default:
+		return nil, ErrUnsupportedSpreadsheet
+	}
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	// Excel writes a byte order mark at the start of UTF-8 CSV files
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, errors.New("file is not UTF-8 encoded; save it as \"CSV UTF-8\"")
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+type xlsxCell struct {
+	Ref       string `xml:"r,attr"`
+	Type      string `xml:"t,attr"`
+	Value     string `xml:"v"`
+	InlineStr struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"is"`
+}
+
+type xlsxSharedString struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxSharedString) String() string {
+	if len(s.Runs) == 0 {
+		return s.Text
+	}
+	var b strings.Builder
+	for _, run := range s.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readXLSX reads cell values from the first worksheet. Numbers are returned as
+// stored, so dates arrive as Excel serial numbers.
+func readXLSX(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid xlsx file: %w", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var table struct {
+			Items []xlsxSharedString `xml:"si"`
+		}
+		if err := decodeZipXML(f, &table); err != nil {
+			return nil, err
+		}
+		for _, item := range table.Items {
+			shared = append(shared, item.String())
+		}
+	}
+
+	sheet, ok := files[firstWorksheetPath(files)]
+	if !ok {
+		return nil, errors.New("invalid xlsx file: no worksheet found")
+	}
+	var worksheet struct {
+		Rows []struct {
+			Index int        `xml:"r,attr"`
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodeZipXML(sheet, &worksheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range worksheet.Rows {
+		// Empty rows are omitted from the XML; keep row numbers aligned with Excel
+		for row.Index > len(rows)+1 {
+			rows = append(rows, nil)
+		}
+
+		var values []string
+		for i, cell := range row.Cells {
+			col := i
+			if cell.Ref != "" {
+				col = xlsxColumnIndex(cell.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				idx, err := strconv.Atoi(cell.Value)
+				if err != nil || idx < 0 || idx >= len(shared) {
+					return nil, fmt.Errorf("invalid shared string in cell %s", cell.Ref)
+				}
+				values[col] = shared[idx]
+			case "inlineStr":
+				values[col] = xlsxSharedString{Text: cell.InlineStr.Text, Runs: cell.InlineStr.Runs}.String()
+			default:
+				values[col] = cell.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// firstWorksheetPath finds the first sheet through the workbook relationships,
+// falling back to the conventional name
+func firstWorksheetPath(files map[string]*zip.File) string {
+	var workbook struct {
+		Sheets []struct {
+			RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Items []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+
+	wb, wbOK := files["xl/workbook.xml"]
+	rf, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if wbOK && relsOK && decodeZipXML(wb, &workbook) == nil && decodeZipXML(rf, &rels) == nil && len(workbook.Sheets) > 0 {
+		for _, rel := range rels.Items {
+			if rel.ID != workbook.Sheets[0].RelID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/")
+			}
+			return path.Join("xl", rel.Target)
+		}
+	}
+	return "xl/worksheets/sheet1.xml"
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, 200<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid xlsx file: %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumnIndex turns a cell reference such as "AB12" into a zero-based column
+func xlsxColumnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
@@ -19,4 +19,9 @@ func ValidatePhone(phone string) bool {
 	regex := `^\+?[1-9]\d{1,14}$`
 	match, _ := regexp.MatchString(regex, cleaned)
 	return match
-}
\ No newline at end of file
+}
+// ValidateUPIID checks a UPI virtual payment address such as "salon@okhdfcbank"
+func ValidateUPIID(vpa string) bool {
+	match, _ := regexp.MatchString(`^[a-zA-Z0-9.\-_]{2,256}@[a-zA-Z][a-zA-Z0-9]{1,63}$`, vpa)
+	return match
+}